@@ -45,6 +45,9 @@ export const config = {
   gatewayPeerEndpoint: process.env.GATEWAY_PEER_ENDPOINT || 'localhost:7051',
   gatewayPeerHostAlias: process.env.GATEWAY_PEER_HOST_ALIAS || 'peer0.org1.example.com',
 
+  // Oracle signing keys (<oracleID>.pem) for oracles this gateway submits for
+  oracleKeyDir: process.env.ORACLE_KEY_DIR || '',
+
   // CORS
   corsOrigin: process.env.CORS_ORIGIN || 'http://localhost:5173',
 
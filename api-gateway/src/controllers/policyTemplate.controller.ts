@@ -63,6 +63,24 @@ export const getTemplateThresholds = asyncHandler(async (req: Request, res: Resp
   });
 });
 
+/**
+ * Get the content hash an activation approval request must carry
+ */
+export const getTemplateContentHash = asyncHandler(async (req: Request, res: Response) => {
+  const { templateId } = req.params;
+
+  const result = await fabricGateway.evaluateTransaction(
+    'policy-template',
+    'GetTemplateContentHash',
+    templateId
+  );
+
+  res.status(200).json({
+    success: true,
+    data: result
+  });
+});
+
 /**
  * Get templates by crop type
  */
@@ -143,15 +161,23 @@ export const createTemplate = asyncHandler(async (req: Request, res: Response) =
 
 /**
  * Activate a policy template
+ * Requires an executed approval request for ValidateTemplateParameters(templateID, contentHash),
+ * signed by an insurer and a regulator or auditor
  */
 export const activateTemplate = asyncHandler(async (req: Request, res: Response) => {
   const { templateId } = req.params;
+  const { approvalRequestId } = req.body;
+
+  if (!approvalRequestId) {
+    throw new ApiError(400, 'approvalRequestId is required');
+  }
 
   // Submit transaction to activate template
   await fabricGateway.submitTransaction(
     'policy-template',
     'ActivateTemplate',
-    templateId
+    templateId,
+    approvalRequestId
   );
 
   res.status(200).json({
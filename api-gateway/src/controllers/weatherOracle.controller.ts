@@ -3,47 +3,72 @@ import fabricGateway from '../services/fabricGateway';
 import { asyncHandler, ApiError } from '../middleware/errorHandler';
 import config from '../config';
 import automaticPayoutService from '../services/automaticPayout.service';
+import oracleSigning, { WeatherBatch, WeatherReading } from '../services/oracleSigning.service';
 import logger from '../utils/logger';
 
 /**
  * Submit weather data
+ *
+ * Oracles sign readings themselves and send { payload, signature }. For oracles whose
+ * key this gateway holds, plain reading fields are signed here instead.
  */
 export const submitWeatherData = asyncHandler(async (req: Request, res: Response) => {
-  const { dataID, oracleID, location, latitude, longitude, rainfall, temperature, humidity, windSpeed } = req.body;
+  let { payload, signature } = req.body;
 
-  if (!dataID || !oracleID || !location) {
-    throw new ApiError(400, 'dataID, oracleID, and location are required');
+  if (!payload || !signature) {
+    const { dataID, oracleID, location, observationTime } = req.body;
+    if (!dataID || !oracleID || !location || !observationTime) {
+      throw new ApiError(400, 'payload and signature, or dataID, oracleID, location, and observationTime, are required');
+    }
+    if (!oracleSigning.hasSigningKey(oracleID)) {
+      throw new ApiError(400, `Oracle ${oracleID} must submit a signed payload`);
+    }
+    const missing = oracleSigning.missingReadingFields(req.body);
+    if (missing.length > 0) {
+      throw new ApiError(400, `Missing or invalid reading fields: ${missing.join(', ')}`);
+    }
+    payload = oracleSigning.canonicalPayload(req.body as WeatherReading);
+    signature = oracleSigning.signPayload(oracleID, payload);
   }
 
-  // Generate data hash
-  const dataHash = `0x${Math.random().toString(16).substr(2, 64)}`;
+  let reading: WeatherReading;
+  try {
+    reading = JSON.parse(payload);
+  } catch {
+    throw new ApiError(400, 'payload must be JSON');
+  }
 
-  const result = await fabricGateway.submitTransaction(
+  await fabricGateway.submitTransaction(
     config.chaincodes.weatherOracle,
     'SubmitWeatherData',
-    dataID,
-    oracleID,
-    location,
-    (latitude || 0).toString(),
-    (longitude || 0).toString(),
-    (rainfall || 0).toString(),
-    (temperature || 0).toString(),
-    (humidity || 0).toString(),
-    (windSpeed || 0).toString(),
-    dataHash
+    payload,
+    signature
+  );
+
+  // The chaincode closes the reading's round (grid cell and bucket) once it completes
+  // a quorum; payouts are checked here for rounds it closed
+  const stored = await fabricGateway.evaluateTransaction(
+    config.chaincodes.weatherOracle,
+    'GetWeatherData',
+    reading.dataID
   );
+  let consensusReached = false;
+  let payoutResult = null;
+  try {
+    ({ consensusReached, payoutResult } = await processClosedRound(stored.gridCell, stored.roundBucket));
+  } catch (error: any) {
+    logger.error(`Error reading consensus round ${stored.gridCell} ${stored.roundBucket}: ${error.message}`);
+  }
 
   res.status(201).json({
     success: true,
     message: 'Weather data submitted successfully',
     data: {
-      dataID,
-      oracleID,
-      location,
-      rainfall,
-      temperature,
-      humidity,
-      windSpeed
+      ...reading,
+      gridCell: stored.gridCell,
+      roundBucket: stored.roundBucket,
+      consensusReached,
+      automaticPayouts: consensusReached ? payoutSummary(payoutResult) : { enabled: false },
     },
   });
 });
@@ -107,71 +132,107 @@ export const getWeatherDataByLocation = asyncHandler(async (req: Request, res: R
 });
 
 /**
- * Validate oracle consensus
+ * Try to close a consensus round past its deadline and, if it reached consensus, check
+ * payout triggers
  */
-export const validateConsensus = asyncHandler(async (req: Request, res: Response) => {
-  const { location, timestamp, dataIDs } = req.body;
-
-  if (!location || !timestamp || !Array.isArray(dataIDs) || dataIDs.length < 2) {
-    throw new ApiError(400, 'location, timestamp, and dataIDs array (min 2) are required');
-  }
-
+async function closeConsensusRound(gridCell: string, bucket: string) {
   const result = await fabricGateway.submitTransaction(
     config.chaincodes.weatherOracle,
     'ValidateDataConsensus',
-    location,
-    timestamp,
-    JSON.stringify(dataIDs)
+    gridCell,
+    bucket
   );
 
-  const consensusReached = result === 'true' || result === true;
+  if (result !== 'true' && result !== true) {
+    return { consensusReached: false, payoutResult: null };
+  }
+  return processClosedRound(gridCell, bucket);
+}
+
+/**
+ * If a round has closed with consensus, check payout triggers against its record
+ */
+async function processClosedRound(gridCell: string, bucket: string) {
+  let record = null;
+  try {
+    record = await fabricGateway.evaluateTransaction(
+      config.chaincodes.weatherOracle,
+      'GetConsensusData',
+      gridCell,
+      bucket
+    );
+  } catch (error: any) {
+    // Open rounds have no consensus record yet
+    if (!String(error.message).includes('consensus record not found')) {
+      throw error;
+    }
+  }
+
+  const consensusReached = !!record?.consensusReached;
 
   // If consensus reached, trigger automatic payout checking
   let payoutResult = null;
   if (consensusReached) {
-    logger.info(`🎯 Consensus reached for ${location} - checking for automatic payout triggers...`);
-    
-    try {
-      // Get the first validated weather data to extract consensus values
-      const weatherData = await fabricGateway.evaluateTransaction(
-        config.chaincodes.weatherOracle,
-        'GetWeatherData',
-        dataIDs[0]
-      );
-      const weather = JSON.parse(weatherData.toString());
+    logger.info(`🎯 Consensus reached for ${gridCell} ${bucket} - checking for automatic payout triggers...`);
 
+    try {
       // Trigger automatic payout checking
       payoutResult = await automaticPayoutService.processConsensusAndTriggerPayouts({
-        location,
-        timestamp,
-        rainfall: weather.rainfall,
-        temperature: weather.temperature,
-        humidity: weather.humidity,
+        location: record.location,
+        gridCell: record.gridCell,
+        timestamp: record.timestamp,
+        rainfall: record.consensus.rainfall,
+        temperature: record.consensus.temperature,
+        humidity: record.consensus.humidity,
       });
 
       logger.info(`✅ Automatic payout processing complete: ${payoutResult.claimsTriggered.length} claims triggered`);
     } catch (error: any) {
       // Log but don't fail the consensus validation
       logger.error(`Error during automatic payout processing: ${error.message}`);
-      // Include error in response but don't throw
     }
   }
 
+  return { consensusReached, payoutResult };
+}
+
+/**
+ * Summarise automatic payout processing for a round that reached consensus
+ */
+function payoutSummary(payoutResult: any) {
+  return {
+    enabled: true,
+    policiesChecked: payoutResult?.policiesChecked || 0,
+    thresholdsBreached: payoutResult?.thresholdsBreached || 0,
+    claimsTriggered: payoutResult?.claimsTriggered || [],
+    errors: payoutResult?.errors || [],
+  };
+}
+
+/**
+ * Validate oracle consensus
+ *
+ * Evaluates every submission in the round for a grid cell and bucket (YYYY-MM-DD or
+ * YYYY-MM-DDTHH). Submissions close their round on quorum; this closes rounds whose
+ * deadline has passed.
+ */
+export const validateConsensus = asyncHandler(async (req: Request, res: Response) => {
+  const { gridCell, bucket } = req.body;
+
+  if (!gridCell || !bucket) {
+    throw new ApiError(400, 'gridCell and bucket are required');
+  }
+
+  const { consensusReached, payoutResult } = await closeConsensusRound(gridCell, bucket);
+
   res.json({
     success: true,
     message: consensusReached ? 'Consensus validation successful' : 'Consensus not reached',
     data: {
       consensusReached,
-      location,
-      timestamp,
-      validatedDataPoints: dataIDs.length,
-      automaticPayouts: consensusReached ? {
-        enabled: true,
-        policiesChecked: payoutResult?.policiesChecked || 0,
-        thresholdsBreached: payoutResult?.thresholdsBreached || 0,
-        claimsTriggered: payoutResult?.claimsTriggered || [],
-        errors: payoutResult?.errors || [],
-      } : {
+      gridCell,
+      bucket,
+      automaticPayouts: consensusReached ? payoutSummary(payoutResult) : {
         enabled: false,
         reason: 'Consensus not reached',
       }
@@ -179,6 +240,40 @@ export const validateConsensus = asyncHandler(async (req: Request, res: Response
   });
 });
 
+/**
+ * Get consensus round record for a grid cell and bucket
+ */
+export const getConsensusRound = asyncHandler(async (req: Request, res: Response) => {
+  const { gridCell, bucket } = req.params;
+
+  // Open rounds have no consensus record yet
+  let record = null;
+  try {
+    record = await fabricGateway.evaluateTransaction(
+      config.chaincodes.weatherOracle,
+      'GetConsensusData',
+      gridCell,
+      bucket
+    );
+  } catch (error: any) {
+    if (!String(error.message).includes('consensus record not found')) {
+      throw error;
+    }
+  }
+
+  const submissions = await fabricGateway.evaluateTransaction(
+    config.chaincodes.weatherOracle,
+    'GetRoundSubmissions',
+    gridCell,
+    bucket
+  );
+
+  res.json({
+    success: true,
+    data: { status: record ? 'Closed' : 'Open', record, submissions: submissions || [] },
+  });
+});
+
 /**
  * Get oracle provider by ID
  */
@@ -208,7 +303,7 @@ export const getOracleProvider = asyncHandler(async (req: Request, res: Response
  * Register an oracle provider
  */
 export const registerProvider = asyncHandler(async (req: Request, res: Response) => {
-  const { oracleID, providerName, providerType, dataSources } = req.body;
+  const { oracleID, providerName, providerType, dataSources, submitterAttribute, approvalRequestID } = req.body;
 
   if (!oracleID || !providerName || !providerType) {
     throw new ApiError(400, 'oracleID, providerName, and providerType are required');
@@ -229,7 +324,9 @@ export const registerProvider = asyncHandler(async (req: Request, res: Response)
     oracleID,
     providerName,
     providerType,
-    JSON.stringify(sources)
+    JSON.stringify(sources),
+    submitterAttribute || '',
+    approvalRequestID || ''
   );
 
   res.status(201).json({
@@ -243,3 +340,286 @@ export const registerProvider = asyncHandler(async (req: Request, res: Response)
     },
   });
 });
+
+/**
+ * Register the public key an oracle signs its submissions with
+ */
+export const registerOracleKey = asyncHandler(async (req: Request, res: Response) => {
+  const { oracleID, publicKey } = req.body;
+
+  if (!oracleID || !publicKey) {
+    throw new ApiError(400, 'oracleID and publicKey are required');
+  }
+
+  await fabricGateway.submitTransaction(
+    config.chaincodes.weatherOracle,
+    'RegisterOracleKey',
+    oracleID,
+    publicKey
+  );
+
+  res.status(201).json({
+    success: true,
+    message: 'Oracle public key registered successfully',
+    data: { oracleID },
+  });
+});
+
+/**
+ * Register a weather station reported by an oracle
+ */
+export const registerStation = asyncHandler(async (req: Request, res: Response) => {
+  const { stationID, name, latitude, longitude, elevation, oracleID, activeFrom, activeTo } = req.body;
+
+  if (!stationID || !oracleID || latitude === undefined || longitude === undefined || !activeFrom) {
+    throw new ApiError(400, 'stationID, oracleID, latitude, longitude, and activeFrom are required');
+  }
+
+  await fabricGateway.submitTransaction(
+    config.chaincodes.weatherOracle,
+    'RegisterStation',
+    stationID,
+    name || '',
+    String(latitude),
+    String(longitude),
+    String(elevation || 0),
+    oracleID,
+    activeFrom,
+    activeTo || ''
+  );
+
+  res.status(201).json({
+    success: true,
+    message: 'Weather station registered successfully',
+    data: { stationID, name, latitude, longitude, elevation, oracleID, activeFrom, activeTo },
+  });
+});
+
+/**
+ * Close a weather station's active period
+ */
+export const closeStation = asyncHandler(async (req: Request, res: Response) => {
+  const { stationID } = req.params;
+  const { activeTo } = req.body;
+
+  if (!activeTo) {
+    throw new ApiError(400, 'activeTo is required');
+  }
+
+  await fabricGateway.submitTransaction(
+    config.chaincodes.weatherOracle,
+    'CloseStation',
+    stationID,
+    activeTo
+  );
+
+  res.json({
+    success: true,
+    message: 'Weather station closed successfully',
+    data: { stationID, activeTo },
+  });
+});
+
+/**
+ * Get weather station by ID
+ */
+export const getStation = asyncHandler(async (req: Request, res: Response) => {
+  const { stationID } = req.params;
+
+  try {
+    const station = await fabricGateway.evaluateTransaction(
+      config.chaincodes.weatherOracle,
+      'GetStation',
+      stationID
+    );
+
+    res.json({
+      success: true,
+      data: station,
+    });
+  } catch (error: any) {
+    if (error.message && error.message.includes('does not exist')) {
+      throw new ApiError(404, `Weather station ${stationID} not found`);
+    } else {
+      throw error;
+    }
+  }
+});
+
+/**
+ * Find the active stations nearest a coordinate
+ */
+export const findNearestStations = asyncHandler(async (req: Request, res: Response) => {
+  const { latitude, longitude } = req.query;
+
+  if (latitude === undefined || longitude === undefined) {
+    throw new ApiError(400, 'latitude and longitude are required');
+  }
+
+  const asOf = (req.query.asOf as string) || new Date().toISOString().slice(0, 10);
+  const maxDistanceKm = (req.query.maxDistanceKm as string) || '50';
+  const limit = (req.query.limit as string) || '5';
+
+  const result = await fabricGateway.evaluateTransaction(
+    config.chaincodes.weatherOracle,
+    'FindNearestStations',
+    String(latitude),
+    String(longitude),
+    asOf,
+    maxDistanceKm,
+    limit
+  );
+
+  res.json({
+    success: true,
+    data: result || [],
+  });
+});
+
+/**
+ * Interpolate daily weather at a coordinate from nearby stations
+ */
+export const interpolateWeather = asyncHandler(async (req: Request, res: Response) => {
+  const { latitude, longitude, startDate, endDate } = req.query;
+
+  if (latitude === undefined || longitude === undefined || !startDate || !endDate) {
+    throw new ApiError(400, 'latitude, longitude, startDate, and endDate are required');
+  }
+
+  const maxDistanceKm = (req.query.maxDistanceKm as string) || '25';
+
+  const result = await fabricGateway.evaluateTransaction(
+    config.chaincodes.weatherOracle,
+    'InterpolateWeather',
+    String(latitude),
+    String(longitude),
+    startDate as string,
+    endDate as string,
+    maxDistanceKm
+  );
+
+  res.json({
+    success: true,
+    data: result || [],
+  });
+});
+
+/**
+ * Report farms with no active station within a radius
+ */
+export const getCoverageGaps = asyncHandler(async (req: Request, res: Response) => {
+  const { farms, radiusKm, asOf } = req.body;
+
+  if (!Array.isArray(farms) || !radiusKm) {
+    throw new ApiError(400, 'farms (array of { farmID, latitude, longitude }) and radiusKm are required');
+  }
+
+  const result = await fabricGateway.evaluateTransaction(
+    config.chaincodes.weatherOracle,
+    'GetCoverageGaps',
+    JSON.stringify(farms),
+    String(radiusKm),
+    asOf || new Date().toISOString().slice(0, 10)
+  );
+
+  res.json({
+    success: true,
+    data: { gaps: result || [], farmsChecked: farms.length },
+  });
+});
+
+/**
+ * Submit a batch of timestamped readings for one station
+ *
+ * Oracles send { payload, signature } for a canonical batch; for oracles whose key this
+ * gateway holds, { oracleID, stationID, readings } may be posted and signed here.
+ */
+export const submitWeatherBatch = asyncHandler(async (req: Request, res: Response) => {
+  let { payload, signature } = req.body;
+
+  if (!payload || !signature) {
+    const { oracleID, stationID, readings } = req.body;
+    if (!oracleID || !stationID || !Array.isArray(readings) || readings.length === 0) {
+      throw new ApiError(400, 'payload and signature, or oracleID, stationID, and readings, are required');
+    }
+    if (!oracleSigning.hasSigningKey(oracleID)) {
+      throw new ApiError(400, `Oracle ${oracleID} must submit a signed payload`);
+    }
+    const missing = oracleSigning.missingBatchFields(req.body);
+    if (missing.length > 0) {
+      throw new ApiError(400, `Missing or invalid batch fields: ${missing.join(', ')}`);
+    }
+    payload = oracleSigning.canonicalBatchPayload(req.body as WeatherBatch);
+    signature = oracleSigning.signPayload(oracleID, payload);
+  }
+
+  let batch: WeatherBatch;
+  try {
+    batch = JSON.parse(payload);
+  } catch {
+    throw new ApiError(400, 'payload must be JSON');
+  }
+
+  await fabricGateway.submitTransaction(
+    config.chaincodes.weatherOracle,
+    'SubmitWeatherBatch',
+    payload,
+    signature
+  );
+
+  res.status(201).json({
+    success: true,
+    message: 'Weather batch submitted successfully',
+    data: { oracleID: batch.oracleID, stationID: batch.stationID, readings: batch.readings.length },
+  });
+});
+
+/**
+ * Get a station's batched readings over a time range, optionally downsampled
+ */
+export const getStationSeries = asyncHandler(async (req: Request, res: Response) => {
+  const { stationID } = req.params;
+  const { start, end } = req.query;
+
+  if (!start || !end) {
+    throw new ApiError(400, 'start and end are required');
+  }
+
+  const interval = (req.query.interval as string) || '0';
+
+  const result = await fabricGateway.evaluateTransaction(
+    config.chaincodes.weatherOracle,
+    'GetStationSeries',
+    stationID,
+    start as string,
+    end as string,
+    interval
+  );
+
+  res.json({
+    success: true,
+    data: result || [],
+  });
+});
+
+/**
+ * Reconcile a station's batched series for a day
+ *
+ * Batched readings only feed interpolation, and so payouts, once their day agrees with
+ * the daily consensus round of the station's grid cell.
+ */
+export const reconcileStationSeries = asyncHandler(async (req: Request, res: Response) => {
+  const { stationID, day } = req.params;
+
+  const result = await fabricGateway.submitTransaction(
+    config.chaincodes.weatherOracle,
+    'ReconcileStationSeries',
+    stationID,
+    day
+  );
+
+  res.json({
+    success: true,
+    data: result,
+  });
+});
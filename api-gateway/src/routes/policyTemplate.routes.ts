@@ -3,6 +3,7 @@ import {
   getAllTemplates,
   getTemplate,
   getTemplateThresholds,
+  getTemplateContentHash,
   getTemplatesByCrop,
   getTemplatesByRegion,
   createTemplate,
@@ -30,6 +31,9 @@ router.get('/:templateId', getTemplate);
 // Set index threshold for template
 router.post('/:templateId/thresholds', setIndexThreshold);
 
+// Get the content hash for an activation approval request
+router.get('/:templateId/content-hash', getTemplateContentHash);
+
 // Activate template
 router.post('/:templateId/activate', activateTemplate);
 
@@ -11,6 +11,13 @@ const router = express.Router();
 // Register provider
 router.post('/register-provider', weatherOracleController.registerProvider);
 
+/**
+ * @route   POST /api/v1/weather-oracle/register-key
+ * @desc    Register an oracle's signing public key (PEM)
+ * @access  Public
+ */
+router.post('/register-key', weatherOracleController.registerOracleKey);
+
 /**
  * @route   POST /api/v1/weather-oracle
  * @desc    Submit weather data
@@ -41,9 +48,79 @@ router.get('/provider/:oracleID', weatherOracleController.getOracleProvider);
 
 /**
  * @route   POST /api/v1/weather-oracle/validate-consensus
- * @desc    Validate consensus for multiple oracle submissions
+ * @desc    Close the consensus round for a grid cell and bucket once its deadline passes
  * @access  Public
  */
 router.post('/validate-consensus', weatherOracleController.validateConsensus);
 
+/**
+ * @route   GET /api/v1/weather-oracle/rounds/:gridCell/:bucket
+ * @desc    Get a consensus round record and its submissions
+ * @access  Public
+ */
+router.get('/rounds/:gridCell/:bucket', weatherOracleController.getConsensusRound);
+
+/**
+ * @route   POST /api/v1/weather-oracle/stations
+ * @desc    Register a weather station
+ * @access  Public
+ */
+router.post('/stations', weatherOracleController.registerStation);
+
+/**
+ * @route   GET /api/v1/weather-oracle/stations/nearest
+ * @desc    Find active stations nearest a coordinate (?latitude&longitude&asOf&maxDistanceKm&limit)
+ * @access  Public
+ */
+router.get('/stations/nearest', weatherOracleController.findNearestStations);
+
+/**
+ * @route   GET /api/v1/weather-oracle/stations/interpolate
+ * @desc    Interpolate daily weather at a coordinate (?latitude&longitude&startDate&endDate&maxDistanceKm)
+ * @access  Public
+ */
+router.get('/stations/interpolate', weatherOracleController.interpolateWeather);
+
+/**
+ * @route   POST /api/v1/weather-oracle/stations/coverage-gaps
+ * @desc    Report farms with no active station within a radius
+ * @access  Public
+ */
+router.post('/stations/coverage-gaps', weatherOracleController.getCoverageGaps);
+
+/**
+ * @route   POST /api/v1/weather-oracle/stations/batch
+ * @desc    Submit a batch of timestamped readings for one station
+ * @access  Public
+ */
+router.post('/stations/batch', weatherOracleController.submitWeatherBatch);
+
+/**
+ * @route   GET /api/v1/weather-oracle/stations/:stationID/series
+ * @desc    Get a station's readings for a range (?start&end&interval minutes, 0 for raw)
+ * @access  Public
+ */
+router.get('/stations/:stationID/series', weatherOracleController.getStationSeries);
+
+/**
+ * @route   POST /api/v1/weather-oracle/stations/:stationID/series/:day/reconcile
+ * @desc    Check a station's batched day against its grid cell's daily consensus
+ * @access  Public
+ */
+router.post('/stations/:stationID/series/:day/reconcile', weatherOracleController.reconcileStationSeries);
+
+/**
+ * @route   GET /api/v1/weather-oracle/stations/:stationID
+ * @desc    Get weather station by ID
+ * @access  Public
+ */
+router.get('/stations/:stationID', weatherOracleController.getStation);
+
+/**
+ * @route   POST /api/v1/weather-oracle/stations/:stationID/close
+ * @desc    Close a weather station's active period
+ * @access  Public
+ */
+router.post('/stations/:stationID/close', weatherOracleController.closeStation);
+
 export default router;
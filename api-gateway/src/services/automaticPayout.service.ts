@@ -18,6 +18,7 @@ import logger from '../utils/logger';
 
 interface ConsensusData {
   location: string;
+  gridCell?: string;
   timestamp: string;
   rainfall: number;
   temperature: number;
@@ -32,6 +33,16 @@ interface PolicyThreshold {
   measurementDays: number;
   payoutPercent: number;
   severity: string;
+  payoutCurve?: PayoutCurve;
+}
+
+interface PayoutCurve {
+  type: 'Linear' | 'Piecewise';
+  trigger: number;
+  exit: number;
+  tick: number;
+  maxPayoutPercent: number;
+  points: { value: number; payoutPercent: number }[];
 }
 
 interface Policy {
@@ -39,8 +50,10 @@ interface Policy {
   farmerID: string;
   templateID: string;
   farmLocation: string;
+  gridCells?: string[];
   coverageAmount: number;
   status: string;
+  beneficiary?: { beneficiaryID: string };
 }
 
 interface PolicyTemplate {
@@ -69,12 +82,21 @@ export async function processConsensusAndTriggerPayouts(
   try {
     logger.info(`🔍 Checking policies for automatic payout triggers in location: ${consensusData.location}`);
 
-    // Step 1: Get all active policies
+    // Step 1 & 2: Find policies governed by the consensus location.
+    // Policies with a coverage area are matched by grid cell on-chain;
+    // the rest fall back to region name matching.
     const activePolicies = await getActivePolicies();
     logger.info(`Found ${activePolicies.length} active policies to check`);
 
-    // Step 2: Filter policies by location
-    const locationPolicies = filterPoliciesByLocation(activePolicies, consensusData.location);
+    const locationPolicies = consensusData.gridCell
+      ? mergePolicies(
+          await getPoliciesByGridCell(consensusData.gridCell),
+          filterPoliciesByLocation(
+            activePolicies.filter(p => !p.gridCells || p.gridCells.length === 0),
+            consensusData.location
+          )
+        )
+      : filterPoliciesByLocation(activePolicies, consensusData.location);
     result.policiesChecked = locationPolicies.length;
     logger.info(`${locationPolicies.length} policies in affected location`);
 
@@ -96,16 +118,16 @@ export async function processConsensusAndTriggerPayouts(
 
         // Check each threshold
         for (const threshold of template.indexThresholds) {
-          const breached = checkThreshold(threshold, consensusData);
-          
-          if (breached) {
+          const payoutPercent = thresholdPayoutPercent(threshold, consensusData);
+
+          if (payoutPercent > 0) {
             result.thresholdsBreached++;
             logger.warn(`⚠️  THRESHOLD BREACHED: Policy ${policy.policyID}, ${threshold.indexType} ${threshold.operator} ${threshold.thresholdValue}`);
 
             // Trigger automatic claim
             const claimID = await triggerAutomaticClaim(
               policy,
-              threshold,
+              payoutPercent,
               consensusData
             );
 
@@ -182,6 +204,42 @@ function filterPoliciesByLocation(policies: Policy[], location: string): Policy[
   });
 }
 
+/**
+ * Get active policies whose coverage area maps to a grid cell
+ */
+async function getPoliciesByGridCell(gridCell: string): Promise<Policy[]> {
+  try {
+    const result = await fabricGateway.evaluateTransaction(
+      config.chaincodes.policy,
+      'GetPoliciesByGridCell',
+      gridCell
+    );
+
+    const policies = JSON.parse(result.toString()) || [];
+    return policies.filter((p: Policy) => p.status === 'Active');
+  } catch (error: any) {
+    logger.error(`Error getting policies for grid cell ${gridCell}: ${error.message}`);
+    throw error;
+  }
+}
+
+/**
+ * Merge policy lists, dropping duplicates by policy ID
+ */
+function mergePolicies(...lists: Policy[][]): Policy[] {
+  const seen = new Set<string>();
+  const merged: Policy[] = [];
+  for (const list of lists) {
+    for (const policy of list) {
+      if (!seen.has(policy.policyID)) {
+        seen.add(policy.policyID);
+        merged.push(policy);
+      }
+    }
+  }
+  return merged;
+}
+
 /**
  * Get policy template with thresholds
  */
@@ -201,25 +259,78 @@ async function getPolicyTemplate(templateID: string): Promise<PolicyTemplate> {
 }
 
 /**
- * Check if a threshold is breached by the consensus weather data
+ * Payout percentage the consensus weather data earns under a threshold.
+ * Thresholds with a payout curve pay from the measured value; the rest pay
+ * their step percentage once breached.
  */
-function checkThreshold(threshold: PolicyThreshold, weather: ConsensusData): boolean {
-  let actualValue: number;
+function thresholdPayoutPercent(threshold: PolicyThreshold, weather: ConsensusData): number {
+  if (!threshold.payoutCurve) {
+    return checkThreshold(threshold, weather) ? threshold.payoutPercent : 0;
+  }
 
-  // Get the relevant weather metric
+  // Deviation-based curves need a baseline, which index-calculator applies on-chain
+  if (threshold.metric === '%' || threshold.metric === 'percent') {
+    return 0;
+  }
+
+  const actualValue = weatherValue(threshold, weather);
+  return actualValue === null ? 0 : curvePayout(threshold.payoutCurve, actualValue);
+}
+
+/**
+ * Evaluate a payout curve at a measured value (mirrors the chaincode curves)
+ */
+function curvePayout(curve: PayoutCurve, value: number): number {
+  if (curve.type === 'Piecewise') {
+    const points = curve.points || [];
+    if (points.length === 0) return 0;
+    if (value <= points[0].value) return points[0].payoutPercent;
+    for (let i = 1; i < points.length; i++) {
+      if (value <= points[i].value) {
+        const low = points[i - 1];
+        const high = points[i];
+        return low.payoutPercent +
+          ((value - low.value) / (high.value - low.value)) * (high.payoutPercent - low.payoutPercent);
+      }
+    }
+    return points[points.length - 1].payoutPercent;
+  }
+
+  // An exit below the trigger pays on deficits
+  const distance = curve.exit < curve.trigger ? curve.trigger - value : value - curve.trigger;
+  const span = Math.abs(curve.exit - curve.trigger);
+  if (distance <= 0) return 0;
+  if (distance >= span) return curve.maxPayoutPercent;
+
+  const tick = curve.tick || curve.maxPayoutPercent / span;
+  return Math.min(distance * tick, curve.maxPayoutPercent);
+}
+
+/**
+ * Get the weather metric a threshold is measured on
+ */
+function weatherValue(threshold: PolicyThreshold, weather: ConsensusData): number | null {
   switch (threshold.indexType.toLowerCase()) {
     case 'rainfall':
-      actualValue = weather.rainfall;
-      break;
+      return weather.rainfall;
     case 'temperature':
-      actualValue = weather.temperature;
-      break;
+      return weather.temperature;
     case 'humidity':
-      actualValue = weather.humidity;
-      break;
+      return weather.humidity;
     default:
       logger.warn(`Unknown index type: ${threshold.indexType}`);
-      return false;
+      return null;
+  }
+}
+
+/**
+ * Check if a threshold is breached by the consensus weather data
+ */
+function checkThreshold(threshold: PolicyThreshold, weather: ConsensusData): boolean {
+  // Get the relevant weather metric
+  const actualValue = weatherValue(threshold, weather);
+  if (actualValue === null) {
+    return false;
   }
 
   // Compare based on operator
@@ -245,7 +356,7 @@ function checkThreshold(threshold: PolicyThreshold, weather: ConsensusData): boo
  */
 async function triggerAutomaticClaim(
   policy: Policy,
-  threshold: PolicyThreshold,
+  payoutPercent: number,
   weather: ConsensusData
 ): Promise<string | null> {
   try {
@@ -253,9 +364,12 @@ async function triggerAutomaticClaim(
     const claimID = `CLAIM_AUTO_${policy.policyID}_${timestamp}`;
 
     // Calculate payout amount
-    const payoutAmount = (policy.coverageAmount * threshold.payoutPercent) / 100;
+    const payoutAmount = (policy.coverageAmount * payoutPercent) / 100;
+
+    // A designated beneficiary receives payouts in place of the farmer
+    const payeeID = policy.beneficiary?.beneficiaryID || policy.farmerID;
 
-    logger.info(`Triggering claim ${claimID} for ${payoutAmount} (${threshold.payoutPercent}% of ${policy.coverageAmount})`);
+    logger.info(`Triggering claim ${claimID} for ${payoutAmount} (${payoutPercent}% of ${policy.coverageAmount})`);
 
     // Submit transaction to claim processor
     const result = await fabricGateway.submitTransaction(
@@ -266,7 +380,7 @@ async function triggerAutomaticClaim(
       policy.farmerID,
       `WEATHER_CONSENSUS_${weather.location}_${Date.now()}`, // Weather data reference
       policy.coverageAmount.toString(),
-      threshold.payoutPercent.toString()
+      payoutPercent.toString()
     );
 
     logger.info(`Claim created: ${claimID}`);
@@ -274,7 +388,7 @@ async function triggerAutomaticClaim(
     // Execute payout from premium pool
     await executeAutomaticPayout(
       claimID,
-      policy.farmerID,
+      payeeID,
       policy.policyID,
       payoutAmount
     );
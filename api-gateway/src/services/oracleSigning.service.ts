@@ -0,0 +1,154 @@
+/**
+ * Oracle Signing Service
+ *
+ * Builds the canonical weather payload the weather-oracle chaincode verifies and,
+ * for oracles operated by this gateway, signs it with the oracle's private key.
+ *
+ * Keys live in ORACLE_KEY_DIR as <oracleID>.pem (PKCS#8, ECDSA P-256 or Ed25519).
+ * External oracles sign on their own side and submit { payload, signature }.
+ */
+
+import crypto from 'crypto';
+import fs from 'fs';
+import path from 'path';
+import config from '../config';
+
+export interface WeatherReading {
+  dataID: string;
+  oracleID: string;
+  stationID?: string;
+  location: string;
+  latitude: number;
+  longitude: number;
+  observationTime: string;
+  period: string;
+  rainfall: number;
+  temperature: number;
+  humidity: number;
+  windSpeed: number;
+}
+
+/**
+ * Canonical payload: fixed field order, no whitespace. Must match the chaincode's
+ * WeatherPayload struct field for field; stationID is omitted when empty.
+ */
+export function canonicalPayload(reading: WeatherReading): string {
+  return JSON.stringify({
+    dataID: reading.dataID,
+    oracleID: reading.oracleID,
+    ...(reading.stationID ? { stationID: reading.stationID } : {}),
+    location: reading.location,
+    latitude: Number(reading.latitude),
+    longitude: Number(reading.longitude),
+    observationTime: reading.observationTime,
+    period: reading.period,
+    rainfall: Number(reading.rainfall),
+    temperature: Number(reading.temperature),
+    humidity: Number(reading.humidity),
+    windSpeed: Number(reading.windSpeed),
+  });
+}
+
+const READING_METRICS = ['latitude', 'longitude', 'rainfall', 'temperature', 'humidity', 'windSpeed'];
+const BATCH_METRICS = ['rainfall', 'temperature', 'humidity', 'windSpeed'];
+
+/**
+ * Names of required reading fields that are absent or not finite numbers. Nothing is
+ * defaulted: a missing metric must not be signed as zero.
+ */
+export function missingReadingFields(reading: Partial<WeatherReading>): string[] {
+  const missing = missingMetrics(reading, READING_METRICS);
+  if (!reading.period) {
+    missing.push('period');
+  }
+  return missing;
+}
+
+/**
+ * Names of required fields missing from any batch reading, as readings[i].field
+ */
+export function missingBatchFields(batch: Partial<WeatherBatch>): string[] {
+  const missing: string[] = [];
+  (batch.readings || []).forEach((reading, i) => {
+    const fields = missingMetrics(reading, BATCH_METRICS);
+    if (!reading.observationTime) {
+      fields.push('observationTime');
+    }
+    missing.push(...fields.map((field) => `readings[${i}].${field}`));
+  });
+  return missing;
+}
+
+function missingMetrics(values: object, fields: string[]): string[] {
+  return fields.filter((field) => {
+    const value = (values as Record<string, unknown>)[field];
+    return value === undefined || value === null || value === '' || !Number.isFinite(Number(value));
+  });
+}
+
+export interface WeatherBatch {
+  oracleID: string;
+  stationID: string;
+  readings: Array<{
+    observationTime: string;
+    rainfall: number;
+    temperature: number;
+    humidity: number;
+    windSpeed: number;
+  }>;
+}
+
+/**
+ * Canonical batch payload. Must match the chaincode's WeatherBatchPayload struct.
+ */
+export function canonicalBatchPayload(batch: WeatherBatch): string {
+  return JSON.stringify({
+    oracleID: batch.oracleID,
+    stationID: batch.stationID,
+    readings: (batch.readings || []).map((reading) => ({
+      observationTime: reading.observationTime,
+      rainfall: Number(reading.rainfall),
+      temperature: Number(reading.temperature),
+      humidity: Number(reading.humidity),
+      windSpeed: Number(reading.windSpeed),
+    })),
+  });
+}
+
+/**
+ * Whether this gateway holds a signing key for the oracle
+ */
+export function hasSigningKey(oracleID: string): boolean {
+  return !!keyPath(oracleID) && fs.existsSync(keyPath(oracleID) as string);
+}
+
+/**
+ * Sign a payload with the oracle's key: ECDSA as ASN.1 DER over SHA-256, Ed25519 over
+ * the raw payload. Returns the base64 signature.
+ */
+export function signPayload(oracleID: string, payload: string): string {
+  const file = keyPath(oracleID);
+  if (!file || !fs.existsSync(file)) {
+    throw new Error(`No signing key for oracle ${oracleID}`);
+  }
+
+  const key = crypto.createPrivateKey(fs.readFileSync(file));
+  const algorithm = key.asymmetricKeyType === 'ed25519' ? null : 'sha256';
+  return crypto.sign(algorithm, Buffer.from(payload), key).toString('base64');
+}
+
+function keyPath(oracleID: string): string | null {
+  if (!config.oracleKeyDir || !/^[A-Za-z0-9_.-]+$/.test(oracleID)) {
+    return null;
+  }
+  return path.join(config.oracleKeyDir, `${oracleID}.pem`);
+}
+
+export default {
+  canonicalPayload,
+  canonicalBatchPayload,
+  missingReadingFields,
+  missingBatchFields,
+  hasSigningKey,
+  signPayload,
+};
@@ -17,7 +17,7 @@ type AccessControlChaincode struct {
 type Organization struct {
 	OrgID          string    `json:"orgID"`          // Unique organization identifier
 	OrgName        string    `json:"orgName"`        // Organization name
-	OrgType        string    `json:"orgType"`        // Type: Insurer, Coop, Oracle, Validator, Auditor
+	OrgType        string    `json:"orgType"`        // Type: Insurer, Coop, Oracle, Validator, Auditor, Regulator
 	MSP            string    `json:"msp"`            // Membership Service Provider ID
 	ContactEmail   string    `json:"contactEmail"`   // Primary contact
 	Status         string    `json:"status"`         // Active, Suspended, Revoked
@@ -79,6 +79,7 @@ func (ac *AccessControlChaincode) RegisterOrganization(ctx contractapi.Transacti
 	validTypes := map[string]bool{
 		"Insurer": true, "Coop": true, "Oracle": true,
 		"Validator": true, "Auditor": true, "Platform": true,
+		"Regulator": true,
 	}
 	if !validTypes[orgType] {
 		return fmt.Errorf("invalid organization type: %s", orgType)
@@ -149,6 +150,43 @@ func (ac *AccessControlChaincode) GetOrganization(ctx contractapi.TransactionCon
 	return &org, nil
 }
 
+// GetOrganizationByMSP retrieves the organization registered for an MSP ID
+func (ac *AccessControlChaincode) GetOrganizationByMSP(ctx contractapi.TransactionContextInterface, msp string) (*Organization, error) {
+	queryString := fmt.Sprintf(`{"selector":{"msp":"%s","orgType":{"$exists":true}}}`, msp)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organizations: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	// Prefer an active registration, then the lowest org ID, so the answer is deterministic
+	var found *Organization
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var org Organization
+		err = json.Unmarshal(queryResponse.Value, &org)
+		if err != nil {
+			return nil, err
+		}
+
+		active := org.Status == "Active"
+		foundActive := found != nil && found.Status == "Active"
+		if found == nil || (active && !foundActive) || (active == foundActive && org.OrgID < found.OrgID) {
+			found = &org
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("no organization registered for MSP %s", msp)
+	}
+
+	return found, nil
+}
+
 // UpdateOrganizationStatus changes organization status (Active/Suspended/Revoked)
 func (ac *AccessControlChaincode) UpdateOrganizationStatus(ctx contractapi.TransactionContextInterface,
 	orgID string, newStatus string) error {
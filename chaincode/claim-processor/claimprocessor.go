@@ -3,11 +3,26 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"payoutcurve"
+
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// Chaincodes invoked from the claim processor
+const (
+	policyChaincode          = "policy"
+	policyTemplateChaincode  = "policy-template"
+	indexCalculatorChaincode = "index-calculator"
+	weatherOracleChaincode   = "weather-oracle"
+	farmerChaincode          = "farmer"
+)
+
 // ClaimProcessorChaincode automates claim evaluation and payout execution
 type ClaimProcessorChaincode struct {
 	contractapi.Contract
@@ -17,7 +32,9 @@ type ClaimProcessorChaincode struct {
 type Claim struct {
 	ClaimID       string    `json:"claimID"`       // Unique claim identifier
 	PolicyID      string    `json:"policyID"`      // Associated policy
-	FarmerID      string    `json:"farmerID"`      // Farmer receiving payout
+	TermID        string    `json:"termID"`        // Policy renewal term claimed against
+	FarmerID      string    `json:"farmerID"`      // Policyholder the claim is made under
+	PayeeID       string    `json:"payeeID"`       // Farmer or designated beneficiary receiving payout
 	IndexID       string    `json:"indexID"`       // Weather index that triggered
 	TriggerDate   time.Time `json:"triggerDate"`   // When conditions were met
 	PayoutAmount  float64   `json:"payoutAmount"`  // Calculated payout
@@ -51,10 +68,34 @@ func (cp *ClaimProcessorChaincode) EvaluatePolicy(ctx contractapi.TransactionCon
 		return false, fmt.Errorf("invalid payout percentage: %.2f", payoutPercent)
 	}
 
-	// Check if policy is active (would query PolicyChaincode in production)
-	// For now, we'll assume policy validation is done externally
+	// Check the policy is active
+	policyJSON, err := invokeChaincode(ctx, policyChaincode, "GetPolicy", policyID)
+	if err != nil {
+		return false, err
+	}
+	var policy struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(policyJSON, &policy); err != nil {
+		return false, fmt.Errorf("failed to unmarshal policy: %v", err)
+	}
+	if policy.Status != "Active" {
+		return false, nil
+	}
+
+	// Check the index triggered within the policy's coverage area
+	validationJSON, err := invokeChaincode(ctx, indexCalculatorChaincode, "ValidateIndexTrigger", indexID, policyID)
+	if err != nil {
+		return false, err
+	}
+	var validation struct {
+		IsTriggered bool `json:"isTriggered"`
+	}
+	if err := json.Unmarshal(validationJSON, &validation); err != nil {
+		return false, fmt.Errorf("failed to unmarshal trigger validation: %v", err)
+	}
 
-	return true, nil
+	return validation.IsTriggered, nil
 }
 
 // TriggerPayout automatically initiates payout transaction
@@ -81,11 +122,18 @@ func (cp *ClaimProcessorChaincode) TriggerPayout(ctx contractapi.TransactionCont
 	}
 	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
+	// Payouts go to a designated beneficiary in place of the farmer
+	payeeID, err := invokeChaincode(ctx, policyChaincode, "GetPolicyPayee", policyID)
+	if err != nil {
+		return err
+	}
+
 	// Create claim record
 	claim := Claim{
 		ClaimID:       claimID,
 		PolicyID:      policyID,
 		FarmerID:      farmerID,
+		PayeeID:       string(payeeID),
 		IndexID:       indexID,
 		TriggerDate:   timestamp,
 		PayoutAmount:  payoutAmount,
@@ -146,6 +194,870 @@ func (cp *ClaimProcessorChaincode) CalculatePayoutAmount(ctx contractapi.Transac
 	return payoutAmount, nil
 }
 
+// ========================================
+// MULTI-PERIL SEASON EVALUATION
+// ========================================
+
+// PerilTrigger is one triggered index considered for a season claim
+type PerilTrigger struct {
+	IndexID       string    `json:"indexID"`       // Triggered weather index
+	IndexType     string    `json:"indexType"`     // Rainfall, Temperature, Drought
+	StartDate     time.Time `json:"startDate"`     // Event start
+	PayoutPercent float64   `json:"payoutPercent"` // Payout on its own (% of coverage)
+	Counted       bool      `json:"counted"`       // Whether the combination rule counted it
+}
+
+// SeasonClaimEvaluation is the outcome of combining a policy's triggered indices
+type SeasonClaimEvaluation struct {
+	ClaimID         string          `json:"claimID"`         // Claim raised for the net payout, if any
+	PolicyID        string          `json:"policyID"`        // Evaluated policy
+	Rule            string          `json:"rule"`            // Combination rule applied
+	CapPercent      float64         `json:"capPercent"`      // Combined payout cap
+	Triggers        []*PerilTrigger `json:"triggers"`        // Triggered indices in event order
+	Phases          []*PhaseOutcome `json:"phases"`          // Growth phases evaluated so far
+	PhasePercent    float64         `json:"phasePercent"`    // Summed phase payouts after the phase cap
+	OnsetDate       *time.Time      `json:"onsetDate"`       // Onset of rains, once known
+	TriggerDate     time.Time       `json:"triggerDate"`     // Date the terms were resolved at
+	TermsVersion    int             `json:"termsVersion"`    // Policy terms version in force on that date
+	CoverageAmount  float64         `json:"coverageAmount"`  // Coverage under those terms
+	CombinedPercent float64         `json:"combinedPercent"` // Season entitlement after combination
+	PriorAmount     float64         `json:"priorAmount"`     // Amount already claimed this season
+	PriorPercent    float64         `json:"priorPercent"`    // Prior amount as a share of coverage
+	PayoutPercent   float64         `json:"payoutPercent"`   // Net percentage claimed now
+	PayoutAmount    float64         `json:"payoutAmount"`    // Net amount claimed now
+	ClaimCreated    bool            `json:"claimCreated"`    // Whether a claim was raised
+}
+
+// seasonPolicy holds the policy fields used for season evaluation
+type seasonPolicy struct {
+	PolicyID       string    `json:"policyID"`
+	FarmerID       string    `json:"farmerID"`
+	TemplateID     string    `json:"templateID"`
+	CoverageAmount float64   `json:"coverageAmount"`
+	StartDate      time.Time `json:"startDate"`
+	EndDate        time.Time `json:"endDate"`
+	TermID         string    `json:"termID"`
+	TermStartDate  time.Time `json:"termStartDate"`
+	Status         string    `json:"status"`
+	FarmLocation   string    `json:"farmLocation"`
+	GridCells      []string  `json:"gridCells"`
+	Beneficiary    *struct {
+		BeneficiaryID string `json:"beneficiaryID"`
+	} `json:"beneficiary"`
+}
+
+// perilRule mirrors the template's peril combination settings
+type perilRule struct {
+	Rule            string  `json:"rule"`
+	CapPercent      float64 `json:"capPercent"`
+	EventWindowDays int     `json:"eventWindowDays"`
+}
+
+// curveThreshold mirrors the template threshold fields that drive a payout curve
+type curveThreshold struct {
+	IndexType   string       `json:"indexType"`
+	Metric      string       `json:"metric"`
+	PayoutCurve *PayoutCurve `json:"payoutCurve"`
+}
+
+// Payout curves come from the shared payoutcurve module so every chaincode pays the same curve
+type (
+	PayoutCurve = payoutcurve.Curve
+	CurvePoint  = payoutcurve.Point
+)
+
+// EvaluateSeasonClaim applies the template's peril combination rule across every
+// index triggered for the policy during its current term and raises one claim for the
+// entitlement not already claimed. indexIDsJSON may list candidate indices;
+// when empty, indices are discovered from the policy's grid cells or region.
+func (cp *ClaimProcessorChaincode) EvaluateSeasonClaim(ctx contractapi.TransactionContextInterface,
+	claimID string, policyID string, indexIDsJSON string) (*SeasonClaimEvaluation, error) {
+
+	exists, err := cp.claimExists(ctx, claimID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("claim %s already exists", claimID)
+	}
+
+	policyJSON, err := invokeChaincode(ctx, policyChaincode, "GetPolicy", policyID)
+	if err != nil {
+		return nil, err
+	}
+	var policy seasonPolicy
+	if err := json.Unmarshal(policyJSON, &policy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy: %v", err)
+	}
+	if policy.Status != "Active" {
+		return nil, fmt.Errorf("cannot claim on non-active policy")
+	}
+
+	// The season is the policy's current renewal term
+	if policy.TermStartDate.IsZero() {
+		policy.TermStartDate = policy.StartDate
+	}
+	if policy.TermID == "" {
+		policy.TermID = policyID + "_T1"
+	}
+
+	templateJSON, err := invokeChaincode(ctx, policyTemplateChaincode, "GetTemplate", policy.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	var template struct {
+		PerilRule       perilRule        `json:"perilRule"`
+		IndexThresholds []curveThreshold `json:"indexThresholds"`
+		WaitingDays     int              `json:"waitingDays"`
+		Phases          []growthPhase    `json:"phases"`
+		PhaseCapPercent float64          `json:"phaseCapPercent"`
+		OnsetRule       *onsetRule       `json:"onsetRule"`
+	}
+	if err := json.Unmarshal(templateJSON, &template); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal template: %v", err)
+	}
+	rule := template.PerilRule
+	if rule.Rule == "" {
+		rule = perilRule{Rule: "MaxOf", CapPercent: 100}
+	}
+
+	triggers, err := cp.collectSeasonTriggers(ctx, &policy, template.IndexThresholds, indexIDsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get deterministic timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	// Growth phases are evaluated from the term's daily readings
+	var phases []*PhaseOutcome
+	var onsetDate *time.Time
+	phasePercent := 0.0
+	if len(template.Phases) > 0 {
+		end := policy.EndDate
+		if timestamp.Before(end) {
+			end = timestamp
+		}
+		days, err := cp.seasonReadings(ctx, &policy, end)
+		if err != nil {
+			return nil, err
+		}
+		phases, onsetDate, phasePercent = evaluatePhases(template.Phases, template.OnsetRule,
+			template.PhaseCapPercent, template.WaitingDays, policy.TermStartDate, days)
+	}
+
+	combined := combinePerils(rule, triggers) + phasePercent
+	if combined > 100 {
+		combined = 100
+	}
+
+	// The claim is dated by its latest counted event, or by the end of the
+	// evaluated period when growth phases contribute
+	triggerDate := time.Time{}
+	for _, trigger := range triggers {
+		if trigger.Counted && trigger.StartDate.After(triggerDate) {
+			triggerDate = trigger.StartDate
+		}
+	}
+	if phasePercent > 0 || triggerDate.IsZero() {
+		triggerDate = policy.EndDate
+		if timestamp.Before(triggerDate) {
+			triggerDate = timestamp
+		}
+	}
+
+	// Coverage comes from the terms in force on the trigger date, not the latest endorsement
+	termsJSON, err := invokeChaincode(ctx, policyChaincode, "GetPolicyTermsAt",
+		policyID, triggerDate.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	var terms struct {
+		Version int `json:"version"`
+		Terms   struct {
+			CoverageAmount float64 `json:"coverageAmount"`
+		} `json:"terms"`
+	}
+	if err := json.Unmarshal(termsJSON, &terms); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy terms: %v", err)
+	}
+	coverage := terms.Terms.CoverageAmount
+
+	// Net off the amounts earlier claims this season already paid or will pay
+	priorClaims, err := cp.GetClaimsByPolicy(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+	priorAmount := 0.0
+	for _, claim := range priorClaims {
+		if claim.Status == "Rejected" {
+			continue
+		}
+		if claim.TermID == policy.TermID ||
+			(claim.TermID == "" && !claim.TriggerDate.Before(policy.TermStartDate)) {
+			priorAmount += claim.PayoutAmount
+		}
+	}
+
+	entitlement := coverage * (combined / 100.0)
+	netAmount := entitlement - priorAmount
+	if netAmount < 0 {
+		netAmount = 0
+	}
+	prior := 0.0
+	net := 0.0
+	if coverage > 0 {
+		prior = priorAmount / coverage * 100
+		net = netAmount / coverage * 100
+	}
+
+	evaluation := &SeasonClaimEvaluation{
+		PolicyID:        policyID,
+		Rule:            rule.Rule,
+		CapPercent:      rule.CapPercent,
+		Triggers:        triggers,
+		Phases:          phases,
+		PhasePercent:    phasePercent,
+		OnsetDate:       onsetDate,
+		TriggerDate:     triggerDate,
+		TermsVersion:    terms.Version,
+		CoverageAmount:  coverage,
+		CombinedPercent: combined,
+		PriorAmount:     priorAmount,
+		PriorPercent:    prior,
+		PayoutPercent:   net,
+		PayoutAmount:    netAmount,
+	}
+
+	if netAmount == 0 {
+		return evaluation, nil
+	}
+
+	var counted []string
+	for _, trigger := range triggers {
+		if trigger.Counted {
+			counted = append(counted, trigger.IndexID)
+		}
+	}
+
+	// Payouts go to a designated beneficiary in place of the farmer
+	payeeID := policy.FarmerID
+	if policy.Beneficiary != nil {
+		payeeID = policy.Beneficiary.BeneficiaryID
+	}
+
+	claim := Claim{
+		ClaimID:       claimID,
+		PolicyID:      policyID,
+		TermID:        policy.TermID,
+		FarmerID:      policy.FarmerID,
+		PayeeID:       payeeID,
+		IndexID:       strings.Join(counted, ","),
+		TriggerDate:   triggerDate,
+		PayoutAmount:  netAmount,
+		PayoutPercent: net,
+		Status:        "Pending",
+		ApprovedBy:    "",
+		ProcessedDate: timestamp,
+		PaymentTxID:   "",
+		Notes: fmt.Sprintf("%s across %d triggered indices and %.2f%% from growth phases: %.2f%% of %.2f coverage (terms v%d) less %.2f already claimed",
+			rule.Rule, len(triggers), phasePercent, combined, coverage, terms.Version, priorAmount),
+	}
+
+	claimJSON, err := json.Marshal(claim)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal claim: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(claimID, claimJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store claim: %v", err)
+	}
+
+	evaluation.ClaimID = claimID
+	evaluation.ClaimCreated = true
+	return evaluation, nil
+}
+
+// collectSeasonTriggers validates candidate indices against the policy and keeps those that
+// triggered. Thresholds with a payout curve are paid from the index's measured value.
+func (cp *ClaimProcessorChaincode) collectSeasonTriggers(ctx contractapi.TransactionContextInterface,
+	policy *seasonPolicy, thresholds []curveThreshold, indexIDsJSON string) ([]*PerilTrigger, error) {
+
+	type weatherIndex struct {
+		IndexID         string    `json:"indexID"`
+		IndexType       string    `json:"indexType"`
+		StartDate       time.Time `json:"startDate"`
+		CalculatedValue float64   `json:"calculatedValue"`
+		Deviation       float64   `json:"deviation"`
+	}
+
+	var candidates []*weatherIndex
+	if indexIDsJSON != "" && indexIDsJSON != "[]" {
+		var indexIDs []string
+		if err := json.Unmarshal([]byte(indexIDsJSON), &indexIDs); err != nil {
+			return nil, fmt.Errorf("failed to parse index IDs: %v", err)
+		}
+		for _, indexID := range indexIDs {
+			indexJSON, err := invokeChaincode(ctx, indexCalculatorChaincode, "GetWeatherIndex", indexID)
+			if err != nil {
+				return nil, err
+			}
+			var index weatherIndex
+			if err := json.Unmarshal(indexJSON, &index); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal index: %v", err)
+			}
+			candidates = append(candidates, &index)
+		}
+	} else {
+		locations := policy.GridCells
+		if len(locations) == 0 {
+			locations = []string{policy.FarmLocation}
+		}
+		for _, location := range locations {
+			indicesJSON, err := invokeChaincode(ctx, indexCalculatorChaincode, "GetIndicesByLocation", location)
+			if err != nil {
+				return nil, err
+			}
+			var indices []*weatherIndex
+			if len(indicesJSON) > 0 {
+				if err := json.Unmarshal(indicesJSON, &indices); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal indices: %v", err)
+				}
+			}
+			candidates = append(candidates, indices...)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var triggers []*PerilTrigger
+	for _, index := range candidates {
+		if seen[index.IndexID] {
+			continue
+		}
+		seen[index.IndexID] = true
+
+		if index.StartDate.Before(policy.TermStartDate) || index.StartDate.After(policy.EndDate) {
+			continue
+		}
+
+		validationJSON, err := invokeChaincode(ctx, indexCalculatorChaincode, "ValidateIndexTrigger",
+			index.IndexID, policy.PolicyID)
+		if err != nil {
+			return nil, err
+		}
+		var validation struct {
+			IsTriggered   bool    `json:"isTriggered"`
+			PayoutPercent float64 `json:"payoutPercent"`
+		}
+		if err := json.Unmarshal(validationJSON, &validation); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal trigger validation: %v", err)
+		}
+		if !validation.IsTriggered {
+			continue
+		}
+
+		payoutPercent := validation.PayoutPercent
+		hasCurve := false
+		for _, threshold := range thresholds {
+			if threshold.IndexType != index.IndexType || threshold.PayoutCurve == nil {
+				continue
+			}
+			if !hasCurve {
+				hasCurve = true
+				payoutPercent = 0
+			}
+
+			// Percent thresholds are measured as the deviation from the baseline
+			measured := index.CalculatedValue
+			if threshold.Metric == "%" || threshold.Metric == "percent" {
+				measured = index.Deviation
+			}
+			payoutPercent = math.Max(payoutPercent, payoutcurve.Evaluate(threshold.PayoutCurve, measured))
+		}
+		if payoutPercent <= 0 {
+			continue
+		}
+
+		triggers = append(triggers, &PerilTrigger{
+			IndexID:       index.IndexID,
+			IndexType:     index.IndexType,
+			StartDate:     index.StartDate,
+			PayoutPercent: payoutPercent,
+		})
+	}
+
+	// Event order, with index ID as a deterministic tie-break
+	sort.Slice(triggers, func(i, j int) bool {
+		if !triggers[i].StartDate.Equal(triggers[j].StartDate) {
+			return triggers[i].StartDate.Before(triggers[j].StartDate)
+		}
+		return triggers[i].IndexID < triggers[j].IndexID
+	})
+
+	return triggers, nil
+}
+
+// combinePerils applies a combination rule to triggers sorted in event order,
+// marking the triggers it counts, and returns the capped season percentage
+func combinePerils(rule perilRule, triggers []*PerilTrigger) float64 {
+	combined := 0.0
+
+	switch rule.Rule {
+	case "AdditiveCapped":
+		for _, trigger := range triggers {
+			trigger.Counted = true
+			combined += trigger.PayoutPercent
+		}
+
+	case "FirstTrigger":
+		// Only the first trigger of each event window pays
+		var windowEnd time.Time
+		for _, trigger := range triggers {
+			if !windowEnd.IsZero() && trigger.StartDate.Before(windowEnd) {
+				continue
+			}
+			trigger.Counted = true
+			combined += trigger.PayoutPercent
+			windowEnd = trigger.StartDate.AddDate(0, 0, rule.EventWindowDays)
+		}
+
+	default: // MaxOf
+		var best *PerilTrigger
+		for _, trigger := range triggers {
+			if best == nil || trigger.PayoutPercent > best.PayoutPercent {
+				best = trigger
+			}
+		}
+		if best != nil {
+			best.Counted = true
+			combined = best.PayoutPercent
+		}
+	}
+
+	capPercent := rule.CapPercent
+	if capPercent <= 0 || capPercent > 100 {
+		capPercent = 100
+	}
+	if combined > capPercent {
+		combined = capPercent
+	}
+
+	return combined
+}
+
+// ========================================
+// GROWTH PHASE EVALUATION
+// ========================================
+
+// PhaseOutcome is the evaluated payout of one growth phase
+type PhaseOutcome struct {
+	Name          string    `json:"name"`          // Growth phase name
+	StartDate     time.Time `json:"startDate"`     // Phase start after any onset shift
+	EndDate       time.Time `json:"endDate"`       // Phase end (exclusive)
+	IndexType     string    `json:"indexType"`     // Threshold that paid, if any
+	ObservedValue float64   `json:"observedValue"` // Measured value of that threshold
+	PayoutPercent float64   `json:"payoutPercent"` // Weighted phase payout (% of coverage)
+}
+
+// growthPhase mirrors a template growth phase
+type growthPhase struct {
+	Name        string           `json:"name"`
+	StartOffset int              `json:"startOffset"`
+	Duration    int              `json:"duration"`
+	FromOnset   bool             `json:"fromOnset"`
+	Weight      float64          `json:"weight"`
+	Thresholds  []phaseThreshold `json:"thresholds"`
+}
+
+// phaseThreshold mirrors the template threshold fields used within a phase
+type phaseThreshold struct {
+	IndexType       string       `json:"indexType"`
+	ThresholdValue  float64      `json:"thresholdValue"`
+	Operator        string       `json:"operator"`
+	MeasurementDays int          `json:"measurementDays"`
+	PayoutPercent   float64      `json:"payoutPercent"`
+	PayoutCurve     *PayoutCurve `json:"payoutCurve"`
+}
+
+// onsetRule mirrors the template's onset-of-rains rule
+type onsetRule struct {
+	RainfallMM     float64 `json:"rainfallMM"`
+	WindowDays     int     `json:"windowDays"`
+	EarliestOffset int     `json:"earliestOffset"`
+	LatestOffset   int     `json:"latestOffset"`
+}
+
+// dailyReading averages a day's oracle readings
+type dailyReading struct {
+	Rainfall    float64
+	Temperature float64
+	Humidity    float64
+}
+
+// dryDayRainfall is the rainfall (mm) below which a day counts towards a drought spell
+const dryDayRainfall = 1.0
+
+// farmWeatherRadiusKm is how far from a farm station readings are interpolated
+const farmWeatherRadiusKm = 25.0
+
+// seasonReadings loads the term's daily weather at the policy's farm. When the farmer's
+// coordinates are known it is interpolated from nearby weather stations, counting only
+// days that stations of several independent oracles corroborate; without coordinates,
+// or when no day near the farm is corroborated, the readings for the policy location
+// are used.
+func (cp *ClaimProcessorChaincode) seasonReadings(ctx contractapi.TransactionContextInterface,
+	policy *seasonPolicy, end time.Time) ([]*dailyReading, error) {
+
+	farmerJSON, err := invokeChaincode(ctx, farmerChaincode, "GetFarmer", policy.FarmerID)
+	if err != nil {
+		return nil, err
+	}
+	var farmer struct {
+		FarmLocation struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"farmLocation"`
+	}
+	if err := json.Unmarshal(farmerJSON, &farmer); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal farmer: %v", err)
+	}
+	latitude, longitude := farmer.FarmLocation.Latitude, farmer.FarmLocation.Longitude
+	if (latitude == 0 && longitude == 0) || end.Before(policy.TermStartDate) {
+		return cp.termReadings(ctx, policy.FarmLocation, policy.TermStartDate, end)
+	}
+
+	startDay := policy.TermStartDate.UTC().Truncate(24 * time.Hour)
+	interpolatedJSON, err := invokeChaincode(ctx, weatherOracleChaincode, "InterpolateWeather",
+		strconv.FormatFloat(latitude, 'f', -1, 64), strconv.FormatFloat(longitude, 'f', -1, 64),
+		startDay.Format("2006-01-02"), end.UTC().Format("2006-01-02"),
+		strconv.FormatFloat(farmWeatherRadiusKm, 'f', -1, 64))
+	if err != nil {
+		return nil, err
+	}
+	var interpolated []*struct {
+		Date         time.Time `json:"date"`
+		HasData      bool      `json:"hasData"`
+		Corroborated bool      `json:"corroborated"`
+		Rainfall     float64   `json:"rainfall"`
+		Temperature  float64   `json:"temperature"`
+		Humidity     float64   `json:"humidity"`
+	}
+	if err := json.Unmarshal(interpolatedJSON, &interpolated); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal interpolated weather: %v", err)
+	}
+
+	dayCount := int(end.Sub(policy.TermStartDate).Hours()/24) + 1
+	days := make([]*dailyReading, dayCount)
+	covered := false
+	for _, estimate := range interpolated {
+		day := int(estimate.Date.Sub(startDay).Hours() / 24)
+		if !estimate.HasData || !estimate.Corroborated || day < 0 || day >= dayCount {
+			continue
+		}
+		days[day] = &dailyReading{
+			Rainfall:    estimate.Rainfall,
+			Temperature: estimate.Temperature,
+			Humidity:    estimate.Humidity,
+		}
+		covered = true
+	}
+	if !covered {
+		return cp.termReadings(ctx, policy.FarmLocation, policy.TermStartDate, end)
+	}
+
+	return days, nil
+}
+
+// termReadings loads the oracle readings for a location and averages them per day of
+// observation from the term start; days without readings are nil. Only readings a
+// consensus round has validated count, so pending submissions cannot move a payout
+// before they are judged. An oracle's hourly
+// rainfall readings are summed into its daily total, and its daily reading is used when
+// it reported one.
+func (cp *ClaimProcessorChaincode) termReadings(ctx contractapi.TransactionContextInterface,
+	location string, termStart time.Time, end time.Time) ([]*dailyReading, error) {
+
+	weatherJSON, err := invokeChaincode(ctx, weatherOracleChaincode, "GetWeatherByRegion",
+		location, termStart.Format(time.RFC3339), end.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	var readings []*struct {
+		DataID            string    `json:"dataID"`
+		OracleID          string    `json:"oracleID"`
+		Timestamp         time.Time `json:"timestamp"`
+		ObservationPeriod string    `json:"observationPeriod"`
+		Rainfall          float64   `json:"rainfall"`
+		Temperature       float64   `json:"temperature"`
+		Humidity          float64   `json:"humidity"`
+		Status            string    `json:"status"`
+	}
+	if len(weatherJSON) > 0 {
+		if err := json.Unmarshal(weatherJSON, &readings); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal weather data: %v", err)
+		}
+	}
+
+	// Sum in a fixed order so every endorser computes identical averages
+	sort.Slice(readings, func(i, j int) bool { return readings[i].DataID < readings[j].DataID })
+
+	dayCount := int(end.Sub(termStart).Hours()/24) + 1
+	if dayCount < 0 {
+		dayCount = 0
+	}
+
+	// Rainfall per oracle per day: daily readings are averaged, hourly ones summed
+	type oracleDay struct {
+		daily, hourly float64
+		dailyCount    int
+	}
+	rainfall := make([]map[string]*oracleDay, dayCount)
+
+	days := make([]*dailyReading, dayCount)
+	counts := make([]int, dayCount)
+	for _, reading := range readings {
+		if reading.Status != "Validated" || reading.Timestamp.Before(termStart) {
+			continue
+		}
+		day := int(reading.Timestamp.Sub(termStart).Hours() / 24)
+		if day >= dayCount {
+			continue
+		}
+		if days[day] == nil {
+			days[day] = &dailyReading{}
+			rainfall[day] = make(map[string]*oracleDay)
+		}
+		days[day].Temperature += reading.Temperature
+		days[day].Humidity += reading.Humidity
+		counts[day]++
+
+		total := rainfall[day][reading.OracleID]
+		if total == nil {
+			total = &oracleDay{}
+			rainfall[day][reading.OracleID] = total
+		}
+		if reading.ObservationPeriod == "Hourly" {
+			total.hourly += reading.Rainfall
+		} else {
+			total.daily += reading.Rainfall
+			total.dailyCount++
+		}
+	}
+	for i, day := range days {
+		if day == nil {
+			continue
+		}
+		n := float64(counts[i])
+		day.Temperature /= n
+		day.Humidity /= n
+
+		oracleIDs := make([]string, 0, len(rainfall[i]))
+		for oracleID := range rainfall[i] {
+			oracleIDs = append(oracleIDs, oracleID)
+		}
+		sort.Strings(oracleIDs)
+		for _, oracleID := range oracleIDs {
+			total := rainfall[i][oracleID]
+			if total.dailyCount > 0 {
+				day.Rainfall += total.daily / float64(total.dailyCount)
+			} else {
+				day.Rainfall += total.hourly
+			}
+		}
+		day.Rainfall /= float64(len(oracleIDs))
+	}
+
+	return days, nil
+}
+
+// evaluatePhases places each growth phase in the term, shifting onset phases by the
+// detected onset of rains, and sums the weighted phase payouts under the cap. Phases
+// are only evaluated once every day of a measurement window has readings.
+func evaluatePhases(phases []growthPhase, rule *onsetRule, capPercent float64, waitingDays int,
+	termStart time.Time, days []*dailyReading) ([]*PhaseOutcome, *time.Time, float64) {
+
+	onset, onsetKnown := 0, false
+	var onsetDate *time.Time
+	if rule != nil {
+		onset, onsetKnown = detectOnset(rule, days)
+		if onsetKnown {
+			date := termStart.AddDate(0, 0, onset)
+			onsetDate = &date
+		}
+	}
+
+	outcomes := []*PhaseOutcome{}
+	total := 0.0
+	for _, phase := range phases {
+		if phase.FromOnset && !onsetKnown {
+			continue
+		}
+		offset := phase.StartOffset
+		if phase.FromOnset {
+			offset += onset
+		}
+
+		// Index events during the waiting period do not count
+		from, to := offset, offset+phase.Duration
+		if from < waitingDays {
+			from = waitingDays
+		}
+		if to > len(days) {
+			to = len(days)
+		}
+		if from >= to {
+			continue
+		}
+		series := days[from:to]
+
+		outcome := &PhaseOutcome{
+			Name:      phase.Name,
+			StartDate: termStart.AddDate(0, 0, offset),
+			EndDate:   termStart.AddDate(0, 0, offset+phase.Duration),
+		}
+		evaluated := false
+		best := 0.0
+		for _, threshold := range phase.Thresholds {
+			value, payoutPercent, complete := phaseThresholdPayout(threshold, series, phase.Duration-(from-offset))
+			evaluated = evaluated || complete
+			if payoutPercent > best {
+				best = payoutPercent
+				outcome.IndexType = threshold.IndexType
+				outcome.ObservedValue = value
+			}
+		}
+		if !evaluated {
+			continue
+		}
+
+		outcome.PayoutPercent = best * phase.Weight / 100
+		outcomes = append(outcomes, outcome)
+		total += outcome.PayoutPercent
+	}
+
+	if total > capPercent {
+		total = capPercent
+	}
+	return outcomes, onsetDate, total
+}
+
+// phaseThresholdPayout measures a threshold within a phase. Drought counts the longest
+// dry spell; rainfall is totalled and other indices averaged over sliding windows, the
+// whole phase when no measurement days are set. It returns the breaching value, its
+// payout, and whether any window could be evaluated.
+func phaseThresholdPayout(threshold phaseThreshold, series []*dailyReading, phaseDays int) (float64, float64, bool) {
+	payout := func(value float64) float64 {
+		if threshold.PayoutCurve != nil {
+			return payoutcurve.Evaluate(threshold.PayoutCurve, value)
+		}
+		if compareThreshold(value, threshold.Operator, threshold.ThresholdValue) {
+			return threshold.PayoutPercent
+		}
+		return 0
+	}
+
+	if threshold.IndexType == "Drought" {
+		longest, run, complete := 0, 0, false
+		for _, day := range series {
+			if day == nil || day.Rainfall >= dryDayRainfall {
+				run = 0
+				complete = complete || day != nil
+				continue
+			}
+			complete = true
+			run++
+			if run > longest {
+				longest = run
+			}
+		}
+		return float64(longest), payout(float64(longest)), complete
+	}
+
+	length := threshold.MeasurementDays
+	if length <= 0 || length > phaseDays {
+		length = phaseDays
+	}
+
+	complete := false
+	bestValue, bestPayout := 0.0, 0.0
+	for offset := 0; offset+length <= len(series); offset++ {
+		total, gap := 0.0, false
+		for _, day := range series[offset : offset+length] {
+			if day == nil {
+				gap = true
+				break
+			}
+			switch threshold.IndexType {
+			case "Temperature":
+				total += day.Temperature
+			case "Humidity":
+				total += day.Humidity
+			default:
+				total += day.Rainfall
+			}
+		}
+		if gap {
+			continue
+		}
+		complete = true
+
+		value := total
+		if threshold.IndexType != "Rainfall" {
+			value = total / float64(length)
+		}
+		if p := payout(value); p > bestPayout {
+			bestValue, bestPayout = value, p
+		}
+	}
+	return bestValue, bestPayout, complete
+}
+
+// detectOnset finds the onset of rains as a day offset into the term, falling back to
+// the latest offset once readings reach it
+func detectOnset(rule *onsetRule, days []*dailyReading) (int, bool) {
+	for offset := rule.EarliestOffset; offset <= rule.LatestOffset && offset+rule.WindowDays <= len(days); offset++ {
+		total, gap := 0.0, false
+		for _, day := range days[offset : offset+rule.WindowDays] {
+			if day == nil {
+				gap = true
+				break
+			}
+			total += day.Rainfall
+		}
+		if !gap && total >= rule.RainfallMM {
+			return offset, true
+		}
+	}
+	for offset := rule.LatestOffset; offset < len(days); offset++ {
+		if days[offset] != nil {
+			return rule.LatestOffset, true
+		}
+	}
+	return 0, false
+}
+
+// compareThreshold applies a threshold operator; == allows for rounding in oracle readings
+func compareThreshold(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case "<":
+		return value < threshold
+	case ">":
+		return value > threshold
+	case "<=":
+		return value <= threshold
+	case ">=":
+		return value >= threshold
+	case "==":
+		return math.Abs(value-threshold) < 0.01
+	}
+	return false
+}
+
 // ========================================
 // CLAIM MANAGEMENT
 // ========================================
@@ -401,6 +1313,7 @@ Claim Report
 Claim ID: %s
 Policy ID: %s
 Farmer ID: %s
+Payee ID: %s
 Index ID: %s
 Trigger Date: %s
 Payout Amount: %.2f
@@ -414,6 +1327,7 @@ Notes: %s
 		claim.ClaimID,
 		claim.PolicyID,
 		claim.FarmerID,
+		claim.PayeeID,
 		claim.IndexID,
 		claim.TriggerDate.Format(time.RFC3339),
 		claim.PayoutAmount,
@@ -440,6 +1354,24 @@ func (cp *ClaimProcessorChaincode) claimExists(ctx contractapi.TransactionContex
 	return claimJSON != nil, nil
 }
 
+// invokeChaincode calls a function on another chaincode in the same channel
+func invokeChaincode(ctx contractapi.TransactionContextInterface,
+	chaincodeName string, functionName string, args ...string) ([]byte, error) {
+
+	invokeArgs := make([][]byte, len(args)+1)
+	invokeArgs[0] = []byte(functionName)
+	for i, arg := range args {
+		invokeArgs[i+1] = []byte(arg)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(chaincodeName, invokeArgs, ctx.GetStub().GetChannelID())
+	if response.Status != 200 {
+		return nil, fmt.Errorf("%s.%s failed: %s", chaincodeName, functionName, response.Message)
+	}
+
+	return response.Payload, nil
+}
+
 // ========================================
 // MAIN
 // ========================================
@@ -0,0 +1,41 @@
+// Package geogrid defines the lat/lon grid shared by the policy, weather oracle,
+// index calculator and policy template chaincodes. Policies, stations, weather
+// readings and indices are matched by cell ID, so every chaincode must derive
+// cells from this package rather than its own copy.
+package geogrid
+
+import (
+	"fmt"
+	"math"
+)
+
+// Coordinates are quantised to integer micro-degrees before any cell arithmetic
+// so every endorser derives the same cells regardless of floating point formatting.
+const (
+	MicroDegrees = 1000000
+	CellMicro    = 50000 // 0.05 degrees, roughly 5.5 km at the equator
+)
+
+// ToMicro quantises a coordinate to integer micro-degrees
+func ToMicro(degrees float64) int64 {
+	return int64(math.Round(degrees * MicroDegrees))
+}
+
+// FloorDiv divides rounding towards negative infinity
+func FloorDiv(a int64, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// CellID returns the grid cell containing a coordinate
+func CellID(latitude float64, longitude float64) string {
+	return CellKey(FloorDiv(ToMicro(latitude), CellMicro), FloorDiv(ToMicro(longitude), CellMicro))
+}
+
+// CellKey formats the ID of the cell at the given row and column indices
+func CellKey(latIndex int64, lonIndex int64) string {
+	return fmt.Sprintf("CELL_%d_%d", latIndex, lonIndex)
+}
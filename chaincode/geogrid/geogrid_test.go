@@ -0,0 +1,82 @@
+package geogrid
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// gridChaincodes derive cell IDs and must all take them from this package
+var gridChaincodes = []string{"policy", "weather-oracle", "index-calculator", "policy-template"}
+
+// Cell IDs are stored on the ledger by the policy, weather-oracle, index-calculator
+// and policy-template chaincodes. Changing any of these breaks matching between them.
+func TestCellIDPinned(t *testing.T) {
+	cases := []struct {
+		latitude  float64
+		longitude float64
+		want      string
+	}{
+		{0, 0, "CELL_0_0"},
+		{-1.286389, 36.817223, "CELL_-26_736"},
+		{0.049999, 0.05, "CELL_0_1"},
+		{-0.000001, -0.05, "CELL_-1_-1"},
+		{-0.0000004, 0.0000004, "CELL_0_0"},
+		{9.145, 40.489673, "CELL_182_809"},
+		{90, 180, "CELL_1800_3600"},
+		{-90, -180, "CELL_-1800_-3600"},
+	}
+
+	for _, c := range cases {
+		if got := CellID(c.latitude, c.longitude); got != c.want {
+			t.Errorf("CellID(%v, %v) = %s, want %s", c.latitude, c.longitude, got, c.want)
+		}
+	}
+}
+
+func TestFloorDiv(t *testing.T) {
+	cases := []struct{ a, b, want int64 }{
+		{7, 2, 3},
+		{-7, 2, -4},
+		{-6, 2, -3},
+		{0, 5, 0},
+	}
+
+	for _, c := range cases {
+		if got := FloorDiv(c.a, c.b); got != c.want {
+			t.Errorf("FloorDiv(%d, %d) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestChaincodesShareGrid(t *testing.T) {
+	localGrid := regexp.MustCompile(`(?m)^func (gridCellID|floorDiv|cellKey)\(`)
+	replace := regexp.MustCompile(`(?m)^replace geogrid => \.\./geogrid$`)
+
+	for _, name := range gridChaincodes {
+		dir := filepath.Join("..", name)
+
+		mod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			t.Fatalf("failed to read %s go.mod: %v", name, err)
+		}
+		if !replace.Match(mod) {
+			t.Errorf("%s does not use the shared geogrid module", name)
+		}
+
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatalf("failed to list %s sources: %v", name, err)
+		}
+		for _, file := range files {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", file, err)
+			}
+			if match := localGrid.Find(src); match != nil {
+				t.Errorf("%s defines its own %s", file, match)
+			}
+		}
+	}
+}
@@ -0,0 +1,3 @@
+module geogrid
+
+go 1.20
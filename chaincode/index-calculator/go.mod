@@ -2,7 +2,11 @@ module index-calculator
 
 go 1.20
 
-require github.com/hyperledger/fabric-contract-api-go v1.2.2
+require (
+	geogrid v0.0.0
+	payoutcurve v0.0.0
+	github.com/hyperledger/fabric-contract-api-go v1.2.2
+)
 
 require (
 	github.com/go-openapi/jsonpointer v0.20.0 // indirect
@@ -31,3 +35,7 @@ require (
 	google.golang.org/protobuf v1.31.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace geogrid => ../geogrid
+
+replace payoutcurve => ../payoutcurve
@@ -4,11 +4,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"strconv"
 	"time"
 
+	"geogrid"
+	"payoutcurve"
+
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// Chaincodes invoked from the index calculator
+const (
+	policyChaincode         = "policy"
+	policyTemplateChaincode = "policy-template"
+	weatherOracleChaincode  = "weather-oracle"
+)
+
 // IndexCalculatorChaincode performs mathematical computations for weather indices
 type IndexCalculatorChaincode struct {
 	contractapi.Contract
@@ -17,7 +28,8 @@ type IndexCalculatorChaincode struct {
 // WeatherIndex represents a calculated index for policy evaluation
 type WeatherIndex struct {
 	IndexID         string    `json:"indexID"`         // Unique index identifier
-	Location        string    `json:"location"`        // Geographic location
+	Location        string    `json:"location"`        // Geographic location or grid cell ID
+	GridCell        string    `json:"gridCell"`        // Grid cell when location is a cell ID
 	IndexType       string    `json:"indexType"`       // Rainfall, Temperature, Drought
 	StartDate       time.Time `json:"startDate"`       // Measurement period start
 	EndDate         time.Time `json:"endDate"`         // Measurement period end
@@ -40,6 +52,12 @@ type RegionalBaseline struct {
 	LastUpdated    time.Time          `json:"lastUpdated"`    // Last baseline update
 }
 
+// Payout curves come from the shared payoutcurve module so every chaincode pays the same curve
+type (
+	PayoutCurve = payoutcurve.Curve
+	CurvePoint  = payoutcurve.Point
+)
+
 // ========================================
 // RAINFALL INDEX CALCULATIONS
 // ========================================
@@ -78,6 +96,7 @@ func (ic *IndexCalculatorChaincode) CalculateRainfallIndex(ctx contractapi.Trans
 	index := WeatherIndex{
 		IndexID:         indexID,
 		Location:        location,
+		GridCell:        indexGridCell(location),
 		IndexType:       "Rainfall",
 		StartDate:       startDate,
 		EndDate:         endDate,
@@ -139,6 +158,7 @@ func (ic *IndexCalculatorChaincode) CalculateTemperatureIndex(ctx contractapi.Tr
 	index := WeatherIndex{
 		IndexID:         indexID,
 		Location:        location,
+		GridCell:        indexGridCell(location),
 		IndexType:       "Temperature",
 		StartDate:       startDate,
 		EndDate:         endDate,
@@ -209,6 +229,7 @@ func (ic *IndexCalculatorChaincode) CalculateDroughtIndex(ctx contractapi.Transa
 	index := WeatherIndex{
 		IndexID:         indexID,
 		Location:        location,
+		GridCell:        indexGridCell(location),
 		IndexType:       "Drought",
 		StartDate:       startDate,
 		EndDate:         endDate,
@@ -233,6 +254,68 @@ func (ic *IndexCalculatorChaincode) CalculateDroughtIndex(ctx contractapi.Transa
 	return nil
 }
 
+// ========================================
+// FARM INDEX CALCULATIONS
+// ========================================
+
+// dryDayRainfall is the rainfall (mm) below which a day counts towards a drought spell
+const dryDayRainfall = 1.0
+
+// interpolatedDay mirrors the weather oracle's daily estimate at a point
+type interpolatedDay struct {
+	Date         time.Time `json:"date"`
+	HasData      bool      `json:"hasData"`
+	Corroborated bool      `json:"corroborated"`
+	Rainfall     float64   `json:"rainfall"`
+}
+
+// CalculateFarmRainfallIndex computes the rainfall index at a farm from station readings
+// interpolated to its coordinates. The index is stored against the farm's grid cell.
+func (ic *IndexCalculatorChaincode) CalculateFarmRainfallIndex(ctx contractapi.TransactionContextInterface,
+	indexID string, latitude float64, longitude float64, startDateStr string, endDateStr string,
+	baselineRainfall float64, maxDistanceKm float64) error {
+
+	days, err := farmWeather(ctx, latitude, longitude, startDateStr, endDateStr, maxDistanceKm)
+	if err != nil {
+		return err
+	}
+
+	totalRainfall := 0.0
+	for _, day := range days {
+		totalRainfall += day.Rainfall
+	}
+
+	return ic.CalculateRainfallIndex(ctx, indexID, geogrid.CellID(latitude, longitude),
+		startDateStr, endDateStr, totalRainfall, baselineRainfall)
+}
+
+// CalculateFarmDroughtIndex computes the drought index at a farm from the longest run of
+// dry days in the station readings interpolated to its coordinates
+func (ic *IndexCalculatorChaincode) CalculateFarmDroughtIndex(ctx contractapi.TransactionContextInterface,
+	indexID string, latitude float64, longitude float64, startDateStr string, endDateStr string,
+	thresholdDays int, maxDistanceKm float64) error {
+
+	days, err := farmWeather(ctx, latitude, longitude, startDateStr, endDateStr, maxDistanceKm)
+	if err != nil {
+		return err
+	}
+
+	longest, run := 0, 0
+	for _, day := range days {
+		if day.Rainfall < dryDayRainfall {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+
+	return ic.CalculateDroughtIndex(ctx, indexID, geogrid.CellID(latitude, longitude),
+		startDateStr, endDateStr, longest, thresholdDays)
+}
+
 // ========================================
 // BASELINE & COMPARISON
 // ========================================
@@ -384,8 +467,10 @@ func (ic *IndexCalculatorChaincode) CalculatePayoutPercentage(ctx contractapi.Tr
 // ValidateIndexTrigger confirms payout conditions are genuinely met
 // TriggerValidation represents the result of index trigger validation
 type TriggerValidation struct {
-	IsTriggered   bool    `json:"isTriggered"`
-	PayoutPercent float64 `json:"payoutPercent"`
+	IsTriggered     bool    `json:"isTriggered"`
+	LocationMatched bool    `json:"locationMatched"`
+	PayoutPercent   float64 `json:"payoutPercent"`
+	Reason          string  `json:"reason"`
 }
 
 func (ic *IndexCalculatorChaincode) ValidateIndexTrigger(ctx contractapi.TransactionContextInterface,
@@ -406,26 +491,121 @@ func (ic *IndexCalculatorChaincode) ValidateIndexTrigger(ctx contractapi.Transac
 		return nil, fmt.Errorf("failed to unmarshal index: %v", err)
 	}
 
+	// Check the index governs the policy's coverage area, by grid cell when available,
+	// and that the event started after the policy's waiting and cooling-off windows
+	matchLocation := index.Location
+	if index.GridCell != "" {
+		matchLocation = index.GridCell
+	}
+	eligibilityJSON, err := invokeChaincode(ctx, policyChaincode, "CheckIndexEligibility",
+		policyID, matchLocation, index.StartDate.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	var eligibility struct {
+		Eligible        bool   `json:"eligible"`
+		LocationMatched bool   `json:"locationMatched"`
+		Reason          string `json:"reason"`
+	}
+	err = json.Unmarshal(eligibilityJSON, &eligibility)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal eligibility: %v", err)
+	}
+
+	if !eligibility.Eligible {
+		return &TriggerValidation{
+			IsTriggered:     false,
+			LocationMatched: eligibility.LocationMatched,
+			PayoutPercent:   0,
+			Reason:          eligibility.Reason,
+		}, nil
+	}
+
+	// Payout curves on the policy's template pay from the measured value and
+	// decide the trigger themselves
+	payoutPercent, hasCurve, err := ic.curvePayoutForPolicy(ctx, policyID, &index)
+	if err != nil {
+		return nil, err
+	}
+	if hasCurve {
+		return &TriggerValidation{
+			IsTriggered:     payoutPercent > 0,
+			LocationMatched: true,
+			PayoutPercent:   payoutPercent,
+		}, nil
+	}
+
 	// Check if payout is triggered
 	if !index.PayoutTriggered {
 		return &TriggerValidation{
-			IsTriggered:   false,
-			PayoutPercent: 0,
+			IsTriggered:     false,
+			LocationMatched: true,
+			PayoutPercent:   0,
 		}, nil
 	}
 
 	// Calculate payout percentage based on severity
-	payoutPercent, err := ic.CalculatePayoutPercentage(ctx, index.IndexType, index.Deviation, index.Severity)
+	payoutPercent, err = ic.CalculatePayoutPercentage(ctx, index.IndexType, index.Deviation, index.Severity)
 	if err != nil {
 		return nil, err
 	}
 
 	return &TriggerValidation{
-		IsTriggered:   true,
-		PayoutPercent: payoutPercent,
+		IsTriggered:     true,
+		LocationMatched: true,
+		PayoutPercent:   payoutPercent,
 	}, nil
 }
 
+// curvePayoutForPolicy evaluates the payout curves the policy's template defines for the
+// index type and returns the largest payout; hasCurve is false when none are defined
+func (ic *IndexCalculatorChaincode) curvePayoutForPolicy(ctx contractapi.TransactionContextInterface,
+	policyID string, index *WeatherIndex) (float64, bool, error) {
+
+	policyJSON, err := invokeChaincode(ctx, policyChaincode, "GetPolicy", policyID)
+	if err != nil {
+		return 0, false, err
+	}
+	var policy struct {
+		TemplateID string `json:"templateID"`
+	}
+	if err := json.Unmarshal(policyJSON, &policy); err != nil {
+		return 0, false, fmt.Errorf("failed to unmarshal policy: %v", err)
+	}
+
+	thresholdsJSON, err := invokeChaincode(ctx, policyTemplateChaincode, "GetIndexThresholds", policy.TemplateID)
+	if err != nil {
+		return 0, false, err
+	}
+	var thresholds []struct {
+		IndexType   string       `json:"indexType"`
+		Metric      string       `json:"metric"`
+		PayoutCurve *PayoutCurve `json:"payoutCurve"`
+	}
+	if len(thresholdsJSON) > 0 {
+		if err := json.Unmarshal(thresholdsJSON, &thresholds); err != nil {
+			return 0, false, fmt.Errorf("failed to unmarshal index thresholds: %v", err)
+		}
+	}
+
+	payoutPercent, hasCurve := 0.0, false
+	for _, threshold := range thresholds {
+		if threshold.IndexType != index.IndexType || threshold.PayoutCurve == nil {
+			continue
+		}
+		hasCurve = true
+
+		// Percent thresholds are measured as the deviation from the baseline
+		measured := index.CalculatedValue
+		if threshold.Metric == "%" || threshold.Metric == "percent" {
+			measured = index.Deviation
+		}
+		payoutPercent = math.Max(payoutPercent, payoutcurve.Evaluate(threshold.PayoutCurve, measured))
+	}
+
+	return payoutPercent, hasCurve, nil
+}
+
 // ========================================
 // QUERY & RETRIEVAL
 // ========================================
@@ -507,6 +687,80 @@ func (ic *IndexCalculatorChaincode) GetTriggeredIndices(ctx contractapi.Transact
 	return indices, nil
 }
 
+// ========================================
+// HELPER FUNCTIONS
+// ========================================
+
+// indexGridCell returns the location when it is a grid cell ID such as CELL_135_767
+func indexGridCell(location string) string {
+	var latIndex, lonIndex int64
+	if _, err := fmt.Sscanf(location, "CELL_%d_%d", &latIndex, &lonIndex); err != nil {
+		return ""
+	}
+	if fmt.Sprintf("CELL_%d_%d", latIndex, lonIndex) != location {
+		return ""
+	}
+	return location
+}
+
+// farmWeather loads the daily weather interpolated to a farm over a period. Every day
+// must be covered by stations within maxDistanceKm that independent oracles corroborate.
+func farmWeather(ctx contractapi.TransactionContextInterface, latitude float64, longitude float64,
+	startDateStr string, endDateStr string, maxDistanceKm float64) ([]*interpolatedDay, error) {
+
+	startDate, err := time.Parse(time.RFC3339, startDateStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date: %v", err)
+	}
+	endDate, err := time.Parse(time.RFC3339, endDateStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date: %v", err)
+	}
+
+	daysJSON, err := invokeChaincode(ctx, weatherOracleChaincode, "InterpolateWeather",
+		strconv.FormatFloat(latitude, 'f', -1, 64), strconv.FormatFloat(longitude, 'f', -1, 64),
+		startDate.UTC().Format("2006-01-02"), endDate.UTC().Format("2006-01-02"),
+		strconv.FormatFloat(maxDistanceKm, 'f', -1, 64))
+	if err != nil {
+		return nil, err
+	}
+	var days []*interpolatedDay
+	if err := json.Unmarshal(daysJSON, &days); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal interpolated weather: %v", err)
+	}
+
+	for _, day := range days {
+		if !day.HasData {
+			return nil, fmt.Errorf("no station within %.1f km reported on %s",
+				maxDistanceKm, day.Date.Format("2006-01-02"))
+		}
+		if !day.Corroborated {
+			return nil, fmt.Errorf("station readings on %s are not corroborated by independent oracles",
+				day.Date.Format("2006-01-02"))
+		}
+	}
+
+	return days, nil
+}
+
+// invokeChaincode calls a function on another chaincode in the same channel
+func invokeChaincode(ctx contractapi.TransactionContextInterface,
+	chaincodeName string, functionName string, args ...string) ([]byte, error) {
+
+	invokeArgs := make([][]byte, len(args)+1)
+	invokeArgs[0] = []byte(functionName)
+	for i, arg := range args {
+		invokeArgs[i+1] = []byte(arg)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(chaincodeName, invokeArgs, ctx.GetStub().GetChannelID())
+	if response.Status != 200 {
+		return nil, fmt.Errorf("%s.%s failed: %s", chaincodeName, functionName, response.Message)
+	}
+
+	return response.Payload, nil
+}
+
 // ========================================
 // MAIN
 // ========================================
@@ -0,0 +1,3 @@
+module payoutcurve
+
+go 1.20
@@ -0,0 +1,65 @@
+// Package payoutcurve defines the continuous payout curves templates attach to index
+// thresholds. The policy template prices and backtests with them, and the index
+// calculator and claim processor pay out with them, so every chaincode must evaluate
+// curves through this package rather than its own copy.
+package payoutcurve
+
+import "math"
+
+// Curve maps a measured index value to a payout percentage. A Linear curve
+// pays Tick percent per index unit past the trigger and the maximum from the exit
+// on; a zero Tick interpolates straight from the trigger to the maximum at the exit.
+// The direction follows the levels: an exit below the trigger pays on deficits.
+type Curve struct {
+	Type             string  `json:"type"`             // Linear, Piecewise
+	Trigger          float64 `json:"trigger"`          // Linear: index level where payouts start
+	Exit             float64 `json:"exit"`             // Linear: index level paying the maximum
+	Tick             float64 `json:"tick"`             // Linear: payout % per index unit past the trigger
+	MaxPayoutPercent float64 `json:"maxPayoutPercent"` // Linear: payout at and beyond the exit
+	Points           []Point `json:"points"`           // Piecewise: breakpoints in increasing index order
+}
+
+// Point is one breakpoint of a piecewise payout curve; payouts are interpolated between points
+type Point struct {
+	Value         float64 `json:"value"`         // Measured index value
+	PayoutPercent float64 `json:"payoutPercent"` // Payout at that value (% of coverage)
+}
+
+// Evaluate returns the payout percentage of a curve at a measured index value.
+// A piecewise curve without points pays nothing.
+func Evaluate(curve *Curve, value float64) float64 {
+	if curve.Type == "Piecewise" {
+		points := curve.Points
+		if len(points) == 0 {
+			return 0
+		}
+		if value <= points[0].Value {
+			return points[0].PayoutPercent
+		}
+		for i := 1; i < len(points); i++ {
+			if value <= points[i].Value {
+				low, high := points[i-1], points[i]
+				return low.PayoutPercent + (value-low.Value)/(high.Value-low.Value)*(high.PayoutPercent-low.PayoutPercent)
+			}
+		}
+		return points[len(points)-1].PayoutPercent
+	}
+
+	distance := value - curve.Trigger
+	if curve.Exit < curve.Trigger {
+		distance = -distance
+	}
+	span := math.Abs(curve.Exit - curve.Trigger)
+	if distance <= 0 {
+		return 0
+	}
+	if distance >= span {
+		return curve.MaxPayoutPercent
+	}
+
+	tick := curve.Tick
+	if tick == 0 {
+		tick = curve.MaxPayoutPercent / span
+	}
+	return math.Min(distance*tick, curve.MaxPayoutPercent)
+}
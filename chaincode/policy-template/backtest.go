@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"policy-template/pricing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Chaincodes the backtest query reads history from
+const (
+	indexCalculatorChaincode = "index-calculator"
+	weatherOracleChaincode   = "weather-oracle"
+)
+
+// Backtest inputs and results are defined in the pricing package, which the offline tools share
+type (
+	BacktestObservation = pricing.BacktestObservation
+	BacktestBaseline    = pricing.BacktestBaseline
+	BacktestOptions     = pricing.BacktestOptions
+	BacktestTrigger     = pricing.BacktestTrigger
+	BacktestSeason      = pricing.BacktestSeason
+	BacktestPhase       = pricing.BacktestPhase
+	BacktestReport      = pricing.BacktestReport
+)
+
+// ========================================
+// BACKTEST QUERY
+// ========================================
+
+// BacktestTemplate replays a template against the oracle history of a location between two dates.
+// It is read-only; baselineID may be empty when no threshold is measured against a baseline.
+func (pt *PolicyTemplateChaincode) BacktestTemplate(ctx contractapi.TransactionContextInterface,
+	templateID string, location string, startDate string, endDate string, seasonStart string,
+	baselineID string, coverageAmount float64, farmSize float64) (*BacktestReport, error) {
+
+	template, err := pt.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	weatherJSON, err := invokeChaincode(ctx, weatherOracleChaincode, "GetWeatherByRegion", location, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	var observations []*BacktestObservation
+	if len(weatherJSON) > 0 {
+		if err := json.Unmarshal(weatherJSON, &observations); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal weather data: %v", err)
+		}
+	}
+
+	var baseline *BacktestBaseline
+	if baselineID != "" {
+		baselineJSON, err := invokeChaincode(ctx, indexCalculatorChaincode, "GetRegionalBaseline", baselineID)
+		if err != nil {
+			return nil, err
+		}
+		baseline = &BacktestBaseline{}
+		if err := json.Unmarshal(baselineJSON, baseline); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal baseline: %v", err)
+		}
+	}
+
+	return pricing.RunBacktest(template, observations, baseline, BacktestOptions{
+		SeasonStart:    seasonStart,
+		CoverageAmount: coverageAmount,
+		FarmSize:       farmSize,
+	})
+}
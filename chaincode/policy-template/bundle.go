@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"policy-template/pricing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ========================================
+// TEMPLATE BUNDLES
+// ========================================
+
+// ImportTemplateBundle creates a Draft template from a JSON or YAML bundle. The whole
+// bundle is validated before anything is written, so a bad bundle leaves no partial template.
+func (pt *PolicyTemplateChaincode) ImportTemplateBundle(ctx contractapi.TransactionContextInterface,
+	bundleContent string) (*PolicyTemplate, error) {
+
+	bundle, err := pricing.ParseTemplateBundle([]byte(bundleContent))
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := pt.templateExists(ctx, bundle.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("template %s already exists", bundle.TemplateID)
+	}
+
+	template := pricing.BundleTemplate(bundle)
+	ratesVersion := 1
+
+	// A bundle may release a new version of an existing lineage, as VersionTemplate does
+	if bundle.PreviousVersionID != "" {
+		previous, err := pt.GetTemplate(ctx, bundle.PreviousVersionID)
+		if err != nil {
+			return nil, err
+		}
+		if previous.Status == "Draft" {
+			return nil, fmt.Errorf("template %s is still a draft; change it directly", previous.TemplateID)
+		}
+		template.Version = previous.Version + 1
+		template.LineageID = templateLineage(previous)
+		template.PreviousVersionID = previous.TemplateID
+		if previous.RegionalRates != nil {
+			ratesVersion = previous.RegionalRates.Version + 1
+		}
+	}
+
+	if err := pricing.ValidateTemplate(template); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %v", err)
+	}
+
+	// Get caller identity
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	template.CreatedBy = callerID
+	template.CreatedDate = timestamp
+	template.LastUpdated = timestamp
+	if template.RegionalRates != nil {
+		template.RegionalRates.Version = ratesVersion
+		template.RegionalRates.ImportedBy = callerID
+		template.RegionalRates.ImportedDate = timestamp
+	}
+
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal template: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(template.TemplateID, templateJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import template bundle: %v", err)
+	}
+
+	return template, nil
+}
+
+// ExportTemplate emits a template as a bundle in "json" or "yaml" format
+func (pt *PolicyTemplateChaincode) ExportTemplate(ctx contractapi.TransactionContextInterface,
+	templateID string, format string) (string, error) {
+
+	template, err := pt.GetTemplate(ctx, templateID)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := pricing.EncodeTemplateBundle(pricing.NewTemplateBundle(template), format)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,288 @@
+// Command template-tools checks policy templates without a peer or network, using
+// the same pricing and validation code as the policy-template chaincode:
+//
+//	template-tools backtest -template TMPL.json -weather history.csv -season-start 03-01 -coverage 5000
+//	template-tools validate [-format json|yaml] bundle.yaml...
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"policy-template/pricing"
+)
+
+// tools are the subcommands, each given the arguments after its name
+var tools = map[string]func(args []string) error{
+	"backtest": backtestTool,
+	"validate": validateTool,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: template-tools backtest|validate [flags]")
+		os.Exit(2)
+	}
+
+	tool, ok := tools[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %s; expected backtest or validate\n", os.Args[1])
+		os.Exit(2)
+	}
+	if err := tool(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// validateTool checks template bundles with the rules ImportTemplateBundle applies,
+// reporting every file, and optionally prints each valid bundle in a normalized format
+func validateTool(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	format := flags.String("format", "", "print valid bundles normalized as json or yaml")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("at least one bundle file is required")
+	}
+
+	failed := 0
+	for _, path := range flags.Args() {
+		if err := validateBundleFile(path, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed++
+			continue
+		}
+		if *format == "" {
+			fmt.Printf("%s: ok\n", path)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d bundles are invalid", failed, flags.NArg())
+	}
+
+	return nil
+}
+
+// validateBundleFile parses and validates one bundle, printing it when a format is given
+func validateBundleFile(path string, format string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %v", err)
+	}
+
+	bundle, err := pricing.ParseTemplateBundle(content)
+	if err != nil {
+		return err
+	}
+	template := pricing.BundleTemplate(bundle)
+	if err := pricing.ValidateTemplate(template); err != nil {
+		return fmt.Errorf("invalid bundle: %v", err)
+	}
+
+	if format != "" {
+		normalized, err := pricing.EncodeTemplateBundle(pricing.NewTemplateBundle(template), format)
+		if err != nil {
+			return err
+		}
+		fmt.Println(strings.TrimRight(string(normalized), "\n"))
+	}
+
+	return nil
+}
+
+// backtestTool replays a template exported with GetTemplate against weather
+// history from a CSV file or a WeatherData ledger export and prints the report
+func backtestTool(args []string) error {
+	flags := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	templatePath := flags.String("template", "", "template JSON as returned by GetTemplate")
+	weatherPath := flags.String("weather", "", "weather history: CSV, or JSON array of WeatherData")
+	baselinePath := flags.String("baseline", "", "optional baseline: CSV, or RegionalBaseline JSON")
+	location := flags.String("location", "", "only replay readings for this location")
+	seasonStart := flags.String("season-start", "", "MM-DD each season's coverage starts")
+	coverage := flags.Float64("coverage", 0, "coverage amount per season")
+	farmSize := flags.Float64("farm-size", 0, "farm size used for pricing")
+	district := flags.String("district", "", "district whose regional risk loading applies")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *templatePath == "" || *weatherPath == "" {
+		return fmt.Errorf("-template and -weather are required")
+	}
+
+	templateJSON, err := os.ReadFile(*templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to read template: %v", err)
+	}
+	var template pricing.PolicyTemplate
+	if err := json.Unmarshal(templateJSON, &template); err != nil {
+		return fmt.Errorf("failed to unmarshal template: %v", err)
+	}
+
+	observations, err := readObservations(*weatherPath)
+	if err != nil {
+		return err
+	}
+	if *location != "" {
+		filtered := observations[:0]
+		for _, observation := range observations {
+			if observation.Location == *location {
+				filtered = append(filtered, observation)
+			}
+		}
+		observations = filtered
+	}
+
+	var baseline *pricing.BacktestBaseline
+	if *baselinePath != "" {
+		if baseline, err = readBaseline(*baselinePath); err != nil {
+			return err
+		}
+	}
+
+	report, err := pricing.RunBacktest(&template, observations, baseline, pricing.BacktestOptions{
+		SeasonStart:    *seasonStart,
+		CoverageAmount: *coverage,
+		FarmSize:       *farmSize,
+		District:       *district,
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// readObservations loads weather history from a CSV file with a header row
+// (timestamp, rainfall, temperature, humidity and optionally location, status)
+// or from a JSON array of WeatherData records
+func readObservations(path string) ([]*pricing.BacktestObservation, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open weather history: %v", err)
+	}
+	defer file.Close()
+
+	if !strings.HasSuffix(strings.ToLower(path), ".csv") {
+		var observations []*pricing.BacktestObservation
+		if err := json.NewDecoder(file).Decode(&observations); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal weather history: %v", err)
+		}
+		return observations, nil
+	}
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read weather header: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"timestamp", "rainfall", "temperature", "humidity"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("weather history is missing the %s column", required)
+		}
+	}
+
+	var observations []*pricing.BacktestObservation
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read weather history: %v", err)
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		number := func(name string) (float64, error) {
+			value, err := strconv.ParseFloat(field(name), 64)
+			if err != nil {
+				return 0, fmt.Errorf("line %d: invalid %s: %v", line, name, err)
+			}
+			return value, nil
+		}
+
+		observation := &pricing.BacktestObservation{Location: field("location"), Status: field("status")}
+		if observation.Timestamp, err = parseHistoryTime(field("timestamp")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid timestamp: %v", line, err)
+		}
+		if observation.Rainfall, err = number("rainfall"); err != nil {
+			return nil, err
+		}
+		if observation.Temperature, err = number("temperature"); err != nil {
+			return nil, err
+		}
+		if observation.Humidity, err = number("humidity"); err != nil {
+			return nil, err
+		}
+		observations = append(observations, observation)
+	}
+
+	return observations, nil
+}
+
+// readBaseline loads a RegionalBaseline JSON export, or a CSV whose header names
+// the metrics (rainfall, temperature, humidity) and whose first row holds the averages
+func readBaseline(path string) (*pricing.BacktestBaseline, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open baseline: %v", err)
+	}
+	defer file.Close()
+
+	baseline := &pricing.BacktestBaseline{}
+	if !strings.HasSuffix(strings.ToLower(path), ".csv") {
+		if err := json.NewDecoder(file).Decode(baseline); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal baseline: %v", err)
+		}
+		return baseline, nil
+	}
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %v", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("baseline needs a header row and a row of values")
+	}
+	baseline.BaselineValues = make(map[string]float64)
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "rainfall" && name != "temperature" && name != "humidity" || i >= len(records[1]) {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(records[1][i]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid baseline %s: %v", name, err)
+		}
+		baseline.BaselineValues[name] = value
+	}
+
+	return baseline, nil
+}
+
+// parseHistoryTime accepts RFC 3339 timestamps or plain dates
+func parseHistoryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -2,7 +2,12 @@ module policy-template
 
 go 1.20
 
-require github.com/hyperledger/fabric-contract-api-go v1.2.2
+require (
+	geogrid v0.0.0
+	payoutcurve v0.0.0
+	github.com/hyperledger/fabric-contract-api-go v1.2.2
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/go-openapi/jsonpointer v0.20.0 // indirect
@@ -29,5 +34,8 @@ require (
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20231030173426-d783a09b4405 // indirect
 	google.golang.org/grpc v1.59.0 // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace geogrid => ../geogrid
+
+replace payoutcurve => ../payoutcurve
@@ -1,55 +1,92 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"policy-template/pricing"
+
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// Chaincodes invoked from the policy template chaincode
+const (
+	accessControlChaincode   = "access-control"
+	approvalManagerChaincode = "approval-manager"
+	farmerChaincode          = "farmer"
+	policyChaincode          = "policy"
+)
+
 // PolicyTemplateChaincode manages standardized policy templates for insurance products
 type PolicyTemplateChaincode struct {
 	contractapi.Contract
 }
 
-// PolicyTemplate defines a reusable insurance policy structure
-type PolicyTemplate struct {
-	TemplateID      string           `json:"templateID"`      // Unique template identifier
-	TemplateName    string           `json:"templateName"`    // Descriptive name
-	CropType        string           `json:"cropType"`        // Coffee type (Arabica, Robusta, etc.)
-	Region          string           `json:"region"`          // Geographic region
-	RiskLevel       string           `json:"riskLevel"`       // Low, Medium, High
-	CoveragePeriod  int              `json:"coveragePeriod"`  // Coverage duration in days
-	PricingModel    PricingModel     `json:"pricingModel"`    // Premium calculation formula
-	IndexThresholds []IndexThreshold `json:"indexThresholds"` // Payout trigger conditions
-	MaxCoverage     float64          `json:"maxCoverage"`     // Maximum coverage amount
-	MinPremium      float64          `json:"minPremium"`      // Minimum premium required
-	Version         int              `json:"version"`         // Template version number
-	Status          string           `json:"status"`          // Active, Deprecated, Draft
-	CreatedBy       string           `json:"createdBy"`       // Creator organization
-	CreatedDate     time.Time        `json:"createdDate"`     // Creation timestamp
-	LastUpdated     time.Time        `json:"lastUpdated"`     // Last modification timestamp
-}
-
-// PricingModel defines how premiums are calculated
-type PricingModel struct {
-	BaseRate        float64            `json:"baseRate"`        // Base premium rate (% of coverage)
-	RiskMultiplier  float64            `json:"riskMultiplier"`  // Risk adjustment factor
-	FarmSizeFactor  float64            `json:"farmSizeFactor"`  // Farm size adjustment
-	HistoryDiscount float64            `json:"historyDiscount"` // Discount for claim-free history
-	Parameters      map[string]float64 `json:"parameters"`      // Additional pricing parameters
-}
-
-// IndexThreshold defines conditions that trigger payouts
-type IndexThreshold struct {
-	IndexType       string  `json:"indexType"`       // Rainfall, Temperature, Drought, etc.
-	Metric          string  `json:"metric"`          // Measurement unit
-	ThresholdValue  float64 `json:"thresholdValue"`  // Trigger value
-	Operator        string  `json:"operator"`        // <, >, <=, >=, ==
-	MeasurementDays int     `json:"measurementDays"` // Days to measure over
-	PayoutPercent   float64 `json:"payoutPercent"`   // Percentage of coverage to pay
-	Severity        string  `json:"severity"`        // Mild, Moderate, Severe
+// Template terms are defined in the pricing package, which the offline tools share
+type (
+	PolicyTemplate    = pricing.PolicyTemplate
+	PricingModel      = pricing.PricingModel
+	RegionalRateTable = pricing.RegionalRateTable
+	RegionalRate      = pricing.RegionalRate
+	PricingLocation   = pricing.PricingLocation
+	PremiumLine       = pricing.PremiumLine
+	PremiumBreakdown  = pricing.PremiumBreakdown
+	PerilCombination  = pricing.PerilCombination
+	IndexThreshold    = pricing.IndexThreshold
+	PayoutCurve       = pricing.PayoutCurve
+	CurvePoint        = pricing.CurvePoint
+	GrowthPhase       = pricing.GrowthPhase
+	OnsetRule         = pricing.OnsetRule
+)
+
+// TemplateVersionSummary is one version in a template lineage
+type TemplateVersionSummary struct {
+	TemplateID        string    `json:"templateID"`        // Version template ID
+	Version           int       `json:"version"`           // Template version number
+	Status            string    `json:"status"`            // Active, Deprecated, Draft
+	PreviousVersionID string    `json:"previousVersionID"` // Parent version, empty for the root
+	ApprovalRequestID string    `json:"approvalRequestID"` // Approval that activated the version
+	CreatedDate       time.Time `json:"createdDate"`       // When the version was created
+	ActivePolicies    int       `json:"activePolicies"`    // Policies in force on this version
+	BoundPolicies     int       `json:"boundPolicies"`     // Policies written on this version, whatever their status
+}
+
+// TemplateLineage lists every version derived from the same original template
+type TemplateLineage struct {
+	LineageID string                    `json:"lineageID"` // Template ID of the first version
+	Versions  []*TemplateVersionSummary `json:"versions"`  // Versions, oldest first
+}
+
+// TemplateChange is one field that differs between two templates
+type TemplateChange struct {
+	Field string `json:"field"` // Field path, e.g. parameters.altitude
+	From  string `json:"from"`  // Value in the first template, empty when absent
+	To    string `json:"to"`    // Value in the second template, empty when absent
+}
+
+// TemplateDiff is a structured comparison of two templates
+type TemplateDiff struct {
+	FromTemplateID    string           `json:"fromTemplateID"`    // Template compared from
+	ToTemplateID      string           `json:"toTemplateID"`      // Template compared to
+	SameLineage       bool             `json:"sameLineage"`       // Whether both are versions of one template
+	PricingChanges    []TemplateChange `json:"pricingChanges"`    // Pricing model and parameter changes
+	CoverageChanges   []TemplateChange `json:"coverageChanges"`   // Coverage terms and peril rule changes
+	AddedThresholds   []IndexThreshold `json:"addedThresholds"`   // Thresholds only in the second template
+	RemovedThresholds []IndexThreshold `json:"removedThresholds"` // Thresholds only in the first template
+}
+
+// TemplateDigest identifies the exact template content an activation approval covers
+type TemplateDigest struct {
+	TemplateID  string `json:"templateID"`  // Template the hash was taken from
+	Version     int    `json:"version"`     // Template version number
+	Status      string `json:"status"`      // Template status when hashed
+	ContentHash string `json:"contentHash"` // SHA-256 of the template terms, second approval argument
 }
 
 // ========================================
@@ -105,6 +142,7 @@ func (pt *PolicyTemplateChaincode) CreateTemplate(ctx contractapi.TransactionCon
 			Parameters:      make(map[string]float64),
 		},
 		IndexThresholds: []IndexThreshold{},
+		PerilRule:       PerilCombination{Rule: "MaxOf", CapPercent: 100},
 		MaxCoverage:     maxCoverage,
 		MinPremium:      minPremium,
 		Version:         1,
@@ -112,6 +150,8 @@ func (pt *PolicyTemplateChaincode) CreateTemplate(ctx contractapi.TransactionCon
 		CreatedBy:       callerID,
 		CreatedDate:     timestamp,
 		LastUpdated:     timestamp,
+
+		LineageID: templateID,
 	}
 
 	templateJSON, err := json.Marshal(template)
@@ -158,8 +198,8 @@ func (pt *PolicyTemplateChaincode) UpdateTemplate(ctx contractapi.TransactionCon
 	}
 
 	// Only allow updates to Draft templates
-	if template.Status != "Draft" && template.Status != "Active" {
-		return fmt.Errorf("cannot update template with status: %s", template.Status)
+	if err := requireDraft(template); err != nil {
+		return err
 	}
 
 	// Update mutable fields
@@ -193,6 +233,49 @@ func (pt *PolicyTemplateChaincode) UpdateTemplate(ctx contractapi.TransactionCon
 	return nil
 }
 
+// SetPolicyWindows configures the waiting period and cooling-off window for new policies
+func (pt *PolicyTemplateChaincode) SetPolicyWindows(ctx contractapi.TransactionContextInterface,
+	templateID string, waitingDays int, coolingOffDays int) error {
+
+	template, err := pt.GetTemplate(ctx, templateID)
+	if err != nil {
+		return err
+	}
+
+	if err := requireDraft(template); err != nil {
+		return err
+	}
+
+	if waitingDays < 0 || coolingOffDays < 0 {
+		return fmt.Errorf("policy windows cannot be negative")
+	}
+	if waitingDays >= template.CoveragePeriod || coolingOffDays >= template.CoveragePeriod {
+		return fmt.Errorf("policy windows must be shorter than the coverage period")
+	}
+
+	template.WaitingDays = waitingDays
+	template.CoolingOffDays = coolingOffDays
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	template.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(templateID, templateJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set policy windows: %v", err)
+	}
+
+	return nil
+}
+
 // ========================================
 // PRICING MODEL CONFIGURATION
 // ========================================
@@ -206,6 +289,10 @@ func (pt *PolicyTemplateChaincode) SetPricingModel(ctx contractapi.TransactionCo
 		return err
 	}
 
+	if err := requireDraft(template); err != nil {
+		return err
+	}
+
 	// Validate pricing parameters
 	if baseRate < 0 || baseRate > 1 {
 		return fmt.Errorf("base rate must be between 0 and 1")
@@ -251,6 +338,14 @@ func (pt *PolicyTemplateChaincode) AddPricingParameter(ctx contractapi.Transacti
 		return err
 	}
 
+	if err := requireDraft(template); err != nil {
+		return err
+	}
+
+	if err := pricing.ValidatePricingParameter(paramName); err != nil {
+		return err
+	}
+
 	if template.PricingModel.Parameters == nil {
 		template.PricingModel.Parameters = make(map[string]float64)
 	}
@@ -277,93 +372,77 @@ func (pt *PolicyTemplateChaincode) AddPricingParameter(ctx contractapi.Transacti
 	return nil
 }
 
-// CalculatePremium computes premium based on pricing model
-func (pt *PolicyTemplateChaincode) CalculatePremium(ctx contractapi.TransactionContextInterface,
-	templateID string, coverageAmount float64, farmSize float64, claimFreeYears int) (float64, error) {
+// SetPricingFormula validates and stores a pricing formula; an empty formula restores the default
+func (pt *PolicyTemplateChaincode) SetPricingFormula(ctx contractapi.TransactionContextInterface,
+	templateID string, formulaSource string) error {
 
 	template, err := pt.GetTemplate(ctx, templateID)
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	if err := requireDraft(template); err != nil {
+		return err
 	}
 
-	// Base premium calculation: coverage * base rate
-	basePremium := coverageAmount * template.PricingModel.BaseRate
+	if formulaSource != "" {
+		if err := pricing.ValidateFormula(template, formulaSource); err != nil {
+			return fmt.Errorf("invalid pricing formula: %v", err)
+		}
+	}
 
-	// Apply risk multiplier
-	adjustedPremium := basePremium * template.PricingModel.RiskMultiplier
+	template.PricingModel.Formula = formulaSource
 
-	// Apply farm size factor
-	if farmSize > 0 {
-		adjustedPremium *= template.PricingModel.FarmSizeFactor
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
 	}
+	template.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
-	// Apply claim-free history discount
-	if claimFreeYears > 0 {
-		discount := template.PricingModel.HistoryDiscount * float64(claimFreeYears)
-		if discount > 0.3 { // Cap at 30% discount
-			discount = 0.3
-		}
-		adjustedPremium *= (1.0 - discount)
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %v", err)
 	}
 
-	// Ensure minimum premium
-	if adjustedPremium < template.MinPremium {
-		adjustedPremium = template.MinPremium
+	err = ctx.GetStub().PutState(templateID, templateJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set pricing formula: %v", err)
 	}
 
-	return adjustedPremium, nil
+	return nil
 }
 
-// ========================================
-// INDEX THRESHOLD CONFIGURATION
-// ========================================
-
-// SetIndexThreshold defines trigger conditions for payouts
-func (pt *PolicyTemplateChaincode) SetIndexThreshold(ctx contractapi.TransactionContextInterface,
-	templateID string, indexType string, metric string, thresholdValue float64,
-	operator string, measurementDays int, payoutPercent float64, severity string) error {
+// SetRateTable stores a named rate table for pricing formulas, e.g. rates by region.
+// The "*" entry, when present, applies to keys not listed.
+func (pt *PolicyTemplateChaincode) SetRateTable(ctx contractapi.TransactionContextInterface,
+	templateID string, tableName string, ratesJSON string) error {
 
 	template, err := pt.GetTemplate(ctx, templateID)
 	if err != nil {
 		return err
 	}
 
-	// Validate index type
-	validIndexTypes := map[string]bool{
-		"Rainfall": true, "Temperature": true, "Drought": true, "Humidity": true,
-	}
-	if !validIndexTypes[indexType] {
-		return fmt.Errorf("invalid index type: %s", indexType)
+	if err := requireDraft(template); err != nil {
+		return err
 	}
 
-	// Validate operator
-	validOperators := map[string]bool{"<": true, ">": true, "<=": true, ">=": true, "==": true}
-	if !validOperators[operator] {
-		return fmt.Errorf("invalid operator: %s", operator)
+	if tableName == "" {
+		return fmt.Errorf("table name is required")
 	}
 
-	// Validate payout percentage
-	if payoutPercent < 0 || payoutPercent > 100 {
-		return fmt.Errorf("payout percent must be between 0 and 100")
+	var rates map[string]float64
+	if err := json.Unmarshal([]byte(ratesJSON), &rates); err != nil {
+		return fmt.Errorf("failed to parse rates: %v", err)
 	}
-
-	// Validate severity
-	validSeverities := map[string]bool{"Mild": true, "Moderate": true, "Severe": true}
-	if !validSeverities[severity] {
-		return fmt.Errorf("invalid severity: %s", severity)
+	if err := pricing.ValidateRateTable(rates); err != nil {
+		return err
 	}
 
-	threshold := IndexThreshold{
-		IndexType:       indexType,
-		Metric:          metric,
-		ThresholdValue:  thresholdValue,
-		Operator:        operator,
-		MeasurementDays: measurementDays,
-		PayoutPercent:   payoutPercent,
-		Severity:        severity,
+	if template.PricingModel.RateTables == nil {
+		template.PricingModel.RateTables = make(map[string]map[string]float64)
 	}
-
-	template.IndexThresholds = append(template.IndexThresholds, threshold)
+	template.PricingModel.RateTables[tableName] = rates
 
 	// Get deterministic transaction timestamp
 	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
@@ -379,114 +458,132 @@ func (pt *PolicyTemplateChaincode) SetIndexThreshold(ctx contractapi.Transaction
 
 	err = ctx.GetStub().PutState(templateID, templateJSON)
 	if err != nil {
-		return fmt.Errorf("failed to set index threshold: %v", err)
+		return fmt.Errorf("failed to set rate table: %v", err)
 	}
 
 	return nil
 }
 
-// GetIndexThresholds retrieves all trigger conditions for a template
-func (pt *PolicyTemplateChaincode) GetIndexThresholds(ctx contractapi.TransactionContextInterface,
-	templateID string) ([]IndexThreshold, error) {
+// ImportRegionalRates replaces a template's regional rate table in one step and bumps the
+// table version. The JSON is {"rates": [{"district": "Sidama", "riskLoading": 0.15,
+// "baselineID": "..."}, {"cell": "CELL_136_765", ...}]}.
+func (pt *PolicyTemplateChaincode) ImportRegionalRates(ctx contractapi.TransactionContextInterface,
+	templateID string, tableJSON string) (*RegionalRateTable, error) {
 
 	template, err := pt.GetTemplate(ctx, templateID)
 	if err != nil {
 		return nil, err
 	}
 
-	return template.IndexThresholds, nil
-}
-
-// ========================================
-// TEMPLATE VERSIONING & STATUS
-// ========================================
-
-// VersionTemplate creates a new version of an existing template
-func (pt *PolicyTemplateChaincode) VersionTemplate(ctx contractapi.TransactionContextInterface,
-	oldTemplateID string, newTemplateID string) error {
+	if err := requireDraft(template); err != nil {
+		return nil, err
+	}
 
-	// Get existing template
-	oldTemplate, err := pt.GetTemplate(ctx, oldTemplateID)
-	if err != nil {
-		return err
+	var table RegionalRateTable
+	if err := json.Unmarshal([]byte(tableJSON), &table); err != nil {
+		return nil, fmt.Errorf("failed to parse regional rates: %v", err)
+	}
+	if err := pricing.ValidateRegionalRates(&table); err != nil {
+		return nil, err
 	}
 
-	// Check if new template ID already exists
-	exists, err := pt.templateExists(ctx, newTemplateID)
+	// Get caller identity
+	callerID, err := ctx.GetClientIdentity().GetID()
 	if err != nil {
-		return err
-	}
-	if exists {
-		return fmt.Errorf("template %s already exists", newTemplateID)
+		return nil, fmt.Errorf("failed to get caller identity: %v", err)
 	}
 
 	// Get deterministic transaction timestamp
 	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
-		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
 	}
 	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
-	// Create new version
-	newTemplate := *oldTemplate
-	newTemplate.TemplateID = newTemplateID
-	newTemplate.Version = oldTemplate.Version + 1
-	newTemplate.Status = "Draft"
-	newTemplate.CreatedDate = timestamp
-	newTemplate.LastUpdated = timestamp
+	table.Version = 1
+	if template.RegionalRates != nil {
+		table.Version = template.RegionalRates.Version + 1
+	}
+	table.ImportedBy = callerID
+	table.ImportedDate = timestamp
 
-	templateJSON, err := json.Marshal(newTemplate)
+	template.RegionalRates = &table
+	template.LastUpdated = timestamp
+
+	templateJSON, err := json.Marshal(template)
 	if err != nil {
-		return fmt.Errorf("failed to marshal template: %v", err)
+		return nil, fmt.Errorf("failed to marshal template: %v", err)
 	}
 
-	err = ctx.GetStub().PutState(newTemplateID, templateJSON)
+	err = ctx.GetStub().PutState(templateID, templateJSON)
 	if err != nil {
-		return fmt.Errorf("failed to create template version: %v", err)
+		return nil, fmt.Errorf("failed to import regional rates: %v", err)
 	}
 
-	// Mark old template as deprecated
-	oldTemplate.Status = "Deprecated"
-	oldTemplate.LastUpdated = timestamp
+	return &table, nil
+}
+
+// CalculatePremium evaluates the template's pricing formula and returns a line-by-line breakdown.
+// Regionally rated templates resolve the farmer's district and apply its risk loading.
+func (pt *PolicyTemplateChaincode) CalculatePremium(ctx contractapi.TransactionContextInterface,
+	templateID string, farmerID string, coverageAmount float64, farmSize float64, claimFreeYears int) (*PremiumBreakdown, error) {
 
-	oldTemplateJSON, err := json.Marshal(oldTemplate)
+	template, err := pt.GetTemplate(ctx, templateID)
 	if err != nil {
-		return fmt.Errorf("failed to marshal old template: %v", err)
+		return nil, err
 	}
 
-	err = ctx.GetStub().PutState(oldTemplateID, oldTemplateJSON)
-	if err != nil {
-		return fmt.Errorf("failed to deprecate old template: %v", err)
+	var location *PricingLocation
+	if template.RegionalRates != nil {
+		if farmerID == "" {
+			return nil, fmt.Errorf("template %s is rated by district; farmer ID is required", templateID)
+		}
+		if location, err = farmerLocation(ctx, farmerID); err != nil {
+			return nil, err
+		}
 	}
 
-	return nil
+	return pricing.QuotePremium(template, coverageAmount, farmSize, claimFreeYears, location)
 }
 
-// ActivateTemplate changes template status to Active
-func (pt *PolicyTemplateChaincode) ActivateTemplate(ctx contractapi.TransactionContextInterface,
-	templateID string) error {
+// ========================================
+// INDEX THRESHOLD CONFIGURATION
+// ========================================
+
+// SetIndexThreshold defines trigger conditions for payouts
+func (pt *PolicyTemplateChaincode) SetIndexThreshold(ctx contractapi.TransactionContextInterface,
+	templateID string, indexType string, metric string, thresholdValue float64,
+	operator string, measurementDays int, payoutPercent float64, severity string) error {
 
 	template, err := pt.GetTemplate(ctx, templateID)
 	if err != nil {
 		return err
 	}
 
-	if template.Status != "Draft" {
-		return fmt.Errorf("can only activate draft templates")
+	if err := requireDraft(template); err != nil {
+		return err
 	}
 
-	// Validate template has required configurations
-	if len(template.IndexThresholds) == 0 {
-		return fmt.Errorf("template must have at least one index threshold")
+	threshold := IndexThreshold{
+		IndexType:       indexType,
+		Metric:          metric,
+		ThresholdValue:  thresholdValue,
+		Operator:        operator,
+		MeasurementDays: measurementDays,
+		PayoutPercent:   payoutPercent,
+		Severity:        severity,
+	}
+	if err := pricing.ValidateIndexThreshold(threshold); err != nil {
+		return err
 	}
 
+	template.IndexThresholds = append(template.IndexThresholds, threshold)
+
 	// Get deterministic transaction timestamp
 	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
 		return fmt.Errorf("failed to get transaction timestamp: %v", err)
 	}
-
-	template.Status = "Active"
 	template.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
 	templateJSON, err := json.Marshal(template)
@@ -496,136 +593,978 @@ func (pt *PolicyTemplateChaincode) ActivateTemplate(ctx contractapi.TransactionC
 
 	err = ctx.GetStub().PutState(templateID, templateJSON)
 	if err != nil {
-		return fmt.Errorf("failed to activate template: %v", err)
+		return fmt.Errorf("failed to set index threshold: %v", err)
 	}
 
 	return nil
 }
 
-// ========================================
-// TEMPLATE QUERIES
-// ========================================
+// SetPerilCombination defines how triggered index thresholds combine for a policy season
+func (pt *PolicyTemplateChaincode) SetPerilCombination(ctx contractapi.TransactionContextInterface,
+	templateID string, rule string, capPercent float64, eventWindowDays int) error {
 
-// ListTemplates retrieves all templates matching criteria
-func (pt *PolicyTemplateChaincode) ListTemplates(ctx contractapi.TransactionContextInterface,
-	region string, cropType string, riskLevel string) ([]*PolicyTemplate, error) {
+	template, err := pt.GetTemplate(ctx, templateID)
+	if err != nil {
+		return err
+	}
 
-	// Build query selector
-	selector := make(map[string]interface{})
-	if region != "" {
-		selector["region"] = region
+	if err := requireDraft(template); err != nil {
+		return err
 	}
-	if cropType != "" {
-		selector["cropType"] = cropType
+
+	combination := PerilCombination{
+		Rule:            rule,
+		CapPercent:      capPercent,
+		EventWindowDays: eventWindowDays,
 	}
-	if riskLevel != "" {
-		selector["riskLevel"] = riskLevel
+	if err := pricing.ValidatePerilCombination(combination); err != nil {
+		return err
 	}
 
-	queryMap := map[string]interface{}{
-		"selector": selector,
-	}
+	template.PerilRule = combination
 
-	queryJSON, err := json.Marshal(queryMap)
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal query: %v", err)
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
 	}
+	template.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
-	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryJSON))
+	templateJSON, err := json.Marshal(template)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query templates: %v", err)
+		return fmt.Errorf("failed to marshal template: %v", err)
 	}
-	defer resultsIterator.Close()
 
-	var templates []*PolicyTemplate
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var template PolicyTemplate
-		err = json.Unmarshal(queryResponse.Value, &template)
-		if err != nil {
-			return nil, err
-		}
-		templates = append(templates, &template)
+	err = ctx.GetStub().PutState(templateID, templateJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set peril combination: %v", err)
 	}
 
-	return templates, nil
+	return nil
 }
 
-// GetActiveTemplates retrieves all currently active templates
-func (pt *PolicyTemplateChaincode) GetActiveTemplates(ctx contractapi.TransactionContextInterface) ([]*PolicyTemplate, error) {
-	queryString := `{"selector":{"status":"Active"}}`
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+// SetPayoutCurve replaces the step payout of a threshold, identified by its position in
+// IndexThresholds, with a continuous curve; an empty curve restores the step payout
+func (pt *PolicyTemplateChaincode) SetPayoutCurve(ctx contractapi.TransactionContextInterface,
+	templateID string, thresholdIndex int, curveJSON string) error {
+
+	template, err := pt.GetTemplate(ctx, templateID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query active templates: %v", err)
+		return err
 	}
-	defer resultsIterator.Close()
 
-	var templates []*PolicyTemplate
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
+	if err := requireDraft(template); err != nil {
+		return err
+	}
 
-		var template PolicyTemplate
-		err = json.Unmarshal(queryResponse.Value, &template)
-		if err != nil {
-			return nil, err
-		}
-		templates = append(templates, &template)
+	if thresholdIndex < 0 || thresholdIndex >= len(template.IndexThresholds) {
+		return fmt.Errorf("template %s has no threshold %d", templateID, thresholdIndex)
 	}
 
-	return templates, nil
+	var curve *PayoutCurve
+	if curveJSON != "" {
+		curve = &PayoutCurve{}
+		if err := json.Unmarshal([]byte(curveJSON), curve); err != nil {
+			return fmt.Errorf("failed to parse payout curve: %v", err)
+		}
+		if err := pricing.ValidatePayoutCurve(curve); err != nil {
+			return err
+		}
+	}
+	template.IndexThresholds[thresholdIndex].PayoutCurve = curve
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	template.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(templateID, templateJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set payout curve: %v", err)
+	}
+
+	return nil
 }
 
-// ValidateTemplateParameters ensures template consistency
-func (pt *PolicyTemplateChaincode) ValidateTemplateParameters(ctx contractapi.TransactionContextInterface,
-	templateID string) (bool, error) {
+// SetGrowthPhases replaces the template's crop growth phases. Phase payouts are summed
+// and capped at capPercent; an empty list removes the phases.
+func (pt *PolicyTemplateChaincode) SetGrowthPhases(ctx contractapi.TransactionContextInterface,
+	templateID string, phasesJSON string, capPercent float64) error {
 
 	template, err := pt.GetTemplate(ctx, templateID)
 	if err != nil {
-		return false, err
+		return err
+	}
+
+	if err := requireDraft(template); err != nil {
+		return err
+	}
+
+	var phases []GrowthPhase
+	if err := json.Unmarshal([]byte(phasesJSON), &phases); err != nil {
+		return fmt.Errorf("failed to parse growth phases: %v", err)
+	}
+	template.Phases = phases
+	template.PhaseCapPercent = capPercent
+	if len(phases) == 0 {
+		template.Phases = nil
+		template.PhaseCapPercent = 0
+	}
+	if err := pricing.ValidateGrowthPhases(template); err != nil {
+		return err
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	template.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(templateID, templateJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set growth phases: %v", err)
+	}
+
+	return nil
+}
+
+// SetOnsetRule configures the onset-of-rains trigger that FromOnset phases start from;
+// an empty rule removes it
+func (pt *PolicyTemplateChaincode) SetOnsetRule(ctx contractapi.TransactionContextInterface,
+	templateID string, ruleJSON string) error {
+
+	template, err := pt.GetTemplate(ctx, templateID)
+	if err != nil {
+		return err
+	}
+
+	if err := requireDraft(template); err != nil {
+		return err
+	}
+
+	template.OnsetRule = nil
+	if ruleJSON != "" {
+		template.OnsetRule = &OnsetRule{}
+		if err := json.Unmarshal([]byte(ruleJSON), template.OnsetRule); err != nil {
+			return fmt.Errorf("failed to parse onset rule: %v", err)
+		}
+	}
+	if err := pricing.ValidateGrowthPhases(template); err != nil {
+		return err
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	template.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(templateID, templateJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set onset rule: %v", err)
+	}
+
+	return nil
+}
+
+// GetIndexThresholds retrieves all trigger conditions for a template
+func (pt *PolicyTemplateChaincode) GetIndexThresholds(ctx contractapi.TransactionContextInterface,
+	templateID string) ([]IndexThreshold, error) {
+
+	template, err := pt.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	return template.IndexThresholds, nil
+}
+
+// ========================================
+// TEMPLATE VERSIONING & STATUS
+// ========================================
+
+// VersionTemplate creates a Draft copy of a template for changes. Active templates are
+// immutable; the existing version stays Active until the new one is activated.
+func (pt *PolicyTemplateChaincode) VersionTemplate(ctx contractapi.TransactionContextInterface,
+	oldTemplateID string, newTemplateID string) error {
+
+	// Get existing template
+	oldTemplate, err := pt.GetTemplate(ctx, oldTemplateID)
+	if err != nil {
+		return err
+	}
+
+	if oldTemplate.Status == "Draft" {
+		return fmt.Errorf("template %s is still a draft; change it directly", oldTemplateID)
+	}
+
+	// Check if new template ID already exists
+	exists, err := pt.templateExists(ctx, newTemplateID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("template %s already exists", newTemplateID)
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	// Create new version
+	newTemplate := *oldTemplate
+	newTemplate.TemplateID = newTemplateID
+	newTemplate.Version = oldTemplate.Version + 1
+	newTemplate.LineageID = templateLineage(oldTemplate)
+	newTemplate.PreviousVersionID = oldTemplateID
+	newTemplate.ApprovalRequestID = ""
+	newTemplate.Status = "Draft"
+	newTemplate.CreatedDate = timestamp
+	newTemplate.LastUpdated = timestamp
+
+	templateJSON, err := json.Marshal(newTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(newTemplateID, templateJSON)
+	if err != nil {
+		return fmt.Errorf("failed to create template version: %v", err)
+	}
+
+	return nil
+}
+
+// ActivateTemplate changes a Draft template to Active once an approval-manager request
+// for it has been executed. The request must target policy-template's
+// ValidateTemplateParameters with the template ID and its content hash, so executing it
+// re-validates the template, and must carry approvals from an Insurer org and a Regulator
+// or Auditor org. Edits made after approval change the hash and void the request.
+// Other Active versions in the lineage are deprecated.
+func (pt *PolicyTemplateChaincode) ActivateTemplate(ctx contractapi.TransactionContextInterface,
+	templateID string, approvalRequestID string) error {
+
+	template, err := pt.GetTemplate(ctx, templateID)
+	if err != nil {
+		return err
+	}
+
+	if template.Status != "Draft" {
+		return fmt.Errorf("can only activate draft templates")
+	}
+
+	// Validate template has required configurations
+	if err := pricing.ValidateTemplate(template); err != nil {
+		return err
+	}
+
+	contentHash, err := templateContentHash(template)
+	if err != nil {
+		return err
+	}
+
+	err = verifyActivationApproval(ctx, approvalRequestID, templateID, contentHash)
+	if err != nil {
+		return err
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	// One Active version per lineage
+	superseded, err := pt.activeVersions(ctx, template)
+	if err != nil {
+		return err
+	}
+	for _, previous := range superseded {
+		previous.Status = "Deprecated"
+		previous.LastUpdated = timestamp
+
+		previousJSON, err := json.Marshal(previous)
+		if err != nil {
+			return fmt.Errorf("failed to marshal old template: %v", err)
+		}
+
+		err = ctx.GetStub().PutState(previous.TemplateID, previousJSON)
+		if err != nil {
+			return fmt.Errorf("failed to deprecate old template: %v", err)
+		}
+	}
+
+	template.Status = "Active"
+	template.ApprovalRequestID = approvalRequestID
+	template.LastUpdated = timestamp
+
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(templateID, templateJSON)
+	if err != nil {
+		return fmt.Errorf("failed to activate template: %v", err)
+	}
+
+	return nil
+}
+
+// GetLatestActiveVersion returns the highest Active version in a template's lineage
+func (pt *PolicyTemplateChaincode) GetLatestActiveVersion(ctx contractapi.TransactionContextInterface,
+	templateID string) (*PolicyTemplate, error) {
+
+	template, err := pt.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	lineageID := templateLineage(template)
+
+	queryString := fmt.Sprintf(`{"selector":{"lineageID":"%s","status":"Active"}}`, lineageID)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query template lineage: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	// Templates created before lineage tracking only match themselves
+	var latest *PolicyTemplate
+	if template.Status == "Active" {
+		latest = template
+	}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var candidate PolicyTemplate
+		err = json.Unmarshal(queryResponse.Value, &candidate)
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil || candidate.Version > latest.Version ||
+			(candidate.Version == latest.Version && candidate.TemplateID > latest.TemplateID) {
+			latest = &candidate
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("template lineage %s has no active version", lineageID)
+	}
+
+	return latest, nil
+}
+
+// ========================================
+// TEMPLATE LINEAGE
+// ========================================
+
+// GetTemplateLineage lists every version in a template's lineage with the policies bound to each
+func (pt *PolicyTemplateChaincode) GetTemplateLineage(ctx contractapi.TransactionContextInterface,
+	templateID string) (*TemplateLineage, error) {
+
+	template, err := pt.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	lineageID := templateLineage(template)
+
+	queryString := fmt.Sprintf(`{"selector":{"lineageID":"%s"}}`, lineageID)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query template lineage: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	versions := make(map[string]*PolicyTemplate)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var version PolicyTemplate
+		err = json.Unmarshal(queryResponse.Value, &version)
+		if err != nil {
+			return nil, err
+		}
+		versions[version.TemplateID] = &version
 	}
 
-	// Check required fields
-	if template.TemplateName == "" {
-		return false, fmt.Errorf("template name is required")
+	// A root created before lineage tracking does not carry its own lineage ID
+	if versions[lineageID] == nil {
+		root, err := pt.GetTemplate(ctx, lineageID)
+		if err != nil {
+			return nil, err
+		}
+		versions[lineageID] = root
+	}
+
+	lineage := &TemplateLineage{LineageID: lineageID}
+	for _, version := range versions {
+		exposureJSON, err := invokeChaincode(ctx, policyChaincode, "GetExposure", "Template", version.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		var exposure struct {
+			PolicyCount   int `json:"policyCount"`
+			BoundPolicies int `json:"boundPolicies"`
+		}
+		if err := json.Unmarshal(exposureJSON, &exposure); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal template exposure: %v", err)
+		}
+
+		lineage.Versions = append(lineage.Versions, &TemplateVersionSummary{
+			TemplateID:        version.TemplateID,
+			Version:           version.Version,
+			Status:            version.Status,
+			PreviousVersionID: version.PreviousVersionID,
+			ApprovalRequestID: version.ApprovalRequestID,
+			CreatedDate:       version.CreatedDate,
+			ActivePolicies:    exposure.PolicyCount,
+			BoundPolicies:     exposure.BoundPolicies,
+		})
+	}
+
+	sort.Slice(lineage.Versions, func(i, j int) bool {
+		if lineage.Versions[i].Version != lineage.Versions[j].Version {
+			return lineage.Versions[i].Version < lineage.Versions[j].Version
+		}
+		return lineage.Versions[i].TemplateID < lineage.Versions[j].TemplateID
+	})
+
+	return lineage, nil
+}
+
+// DiffTemplates compares two templates, typically consecutive versions, field by field
+func (pt *PolicyTemplateChaincode) DiffTemplates(ctx contractapi.TransactionContextInterface,
+	fromTemplateID string, toTemplateID string) (*TemplateDiff, error) {
+
+	from, err := pt.GetTemplate(ctx, fromTemplateID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := pt.GetTemplate(ctx, toTemplateID)
+	if err != nil {
+		return nil, err
 	}
-	if template.CropType == "" {
-		return false, fmt.Errorf("crop type is required")
+
+	return diffTemplates(from, to), nil
+}
+
+// ========================================
+// TEMPLATE QUERIES
+// ========================================
+
+// ListTemplates retrieves all templates matching criteria
+func (pt *PolicyTemplateChaincode) ListTemplates(ctx contractapi.TransactionContextInterface,
+	region string, cropType string, riskLevel string) ([]*PolicyTemplate, error) {
+
+	// Build query selector
+	selector := make(map[string]interface{})
+	if region != "" {
+		selector["region"] = region
+	}
+	if cropType != "" {
+		selector["cropType"] = cropType
+	}
+	if riskLevel != "" {
+		selector["riskLevel"] = riskLevel
+	}
+
+	queryMap := map[string]interface{}{
+		"selector": selector,
+	}
+
+	queryJSON, err := json.Marshal(queryMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query templates: %v", err)
 	}
-	if template.Region == "" {
-		return false, fmt.Errorf("region is required")
+	defer resultsIterator.Close()
+
+	var templates []*PolicyTemplate
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var template PolicyTemplate
+		err = json.Unmarshal(queryResponse.Value, &template)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, &template)
 	}
-	if template.CoveragePeriod <= 0 {
-		return false, fmt.Errorf("coverage period must be positive")
+
+	return templates, nil
+}
+
+// GetActiveTemplates retrieves all currently active templates
+func (pt *PolicyTemplateChaincode) GetActiveTemplates(ctx contractapi.TransactionContextInterface) ([]*PolicyTemplate, error) {
+	queryString := `{"selector":{"status":"Active"}}`
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active templates: %v", err)
 	}
-	if template.MaxCoverage <= 0 {
-		return false, fmt.Errorf("max coverage must be positive")
+	defer resultsIterator.Close()
+
+	var templates []*PolicyTemplate
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var template PolicyTemplate
+		err = json.Unmarshal(queryResponse.Value, &template)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, &template)
 	}
-	if len(template.IndexThresholds) == 0 {
-		return false, fmt.Errorf("at least one index threshold required")
+
+	return templates, nil
+}
+
+// ValidateTemplateParameters ensures template consistency. A non-empty content hash must
+// match the template's current content, so an approval only covers what its approvers saw.
+func (pt *PolicyTemplateChaincode) ValidateTemplateParameters(ctx contractapi.TransactionContextInterface,
+	templateID string, contentHash string) (bool, error) {
+
+	template, err := pt.GetTemplate(ctx, templateID)
+	if err != nil {
+		return false, err
 	}
 
-	// Validate pricing model
-	if template.PricingModel.BaseRate <= 0 {
-		return false, fmt.Errorf("base rate must be positive")
+	if err := pricing.ValidateTemplate(template); err != nil {
+		return false, err
 	}
-	if template.PricingModel.RiskMultiplier <= 0 {
-		return false, fmt.Errorf("risk multiplier must be positive")
+
+	if contentHash != "" {
+		currentHash, err := templateContentHash(template)
+		if err != nil {
+			return false, err
+		}
+		if currentHash != contentHash {
+			return false, fmt.Errorf("template %s content has changed since hash %s was taken", templateID, contentHash)
+		}
 	}
 
 	return true, nil
 }
 
+// GetTemplateContentHash returns the hash an activation approval request must carry
+func (pt *PolicyTemplateChaincode) GetTemplateContentHash(ctx contractapi.TransactionContextInterface,
+	templateID string) (*TemplateDigest, error) {
+
+	template, err := pt.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	contentHash, err := templateContentHash(template)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TemplateDigest{
+		TemplateID:  template.TemplateID,
+		Version:     template.Version,
+		Status:      template.Status,
+		ContentHash: contentHash,
+	}, nil
+}
+
 // ========================================
 // HELPER FUNCTIONS
 // ========================================
 
+// requireDraft rejects changes to templates that are Active or Deprecated
+func requireDraft(template *PolicyTemplate) error {
+	if template.Status != "Draft" {
+		return fmt.Errorf("template %s is %s; create a new version with VersionTemplate to change it",
+			template.TemplateID, template.Status)
+	}
+	return nil
+}
+
+// activeVersions returns the other Active templates in a template's lineage
+func (pt *PolicyTemplateChaincode) activeVersions(ctx contractapi.TransactionContextInterface,
+	template *PolicyTemplate) ([]*PolicyTemplate, error) {
+
+	queryString := fmt.Sprintf(`{"selector":{"lineageID":"%s","status":"Active"}}`, templateLineage(template))
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query template lineage: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	seen := make(map[string]bool)
+	var templates []*PolicyTemplate
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var candidate PolicyTemplate
+		err = json.Unmarshal(queryResponse.Value, &candidate)
+		if err != nil {
+			return nil, err
+		}
+		if candidate.TemplateID != template.TemplateID {
+			seen[candidate.TemplateID] = true
+			templates = append(templates, &candidate)
+		}
+	}
+
+	// Templates created before lineage tracking are only linked to their successor
+	if template.PreviousVersionID != "" && !seen[template.PreviousVersionID] {
+		previous, err := pt.GetTemplate(ctx, template.PreviousVersionID)
+		if err != nil {
+			return nil, err
+		}
+		if previous.Status == "Active" {
+			templates = append(templates, previous)
+		}
+	}
+
+	return templates, nil
+}
+
+// templateContentHash hashes a template's terms, leaving out the status fields activation changes
+func templateContentHash(template *PolicyTemplate) (string, error) {
+	content := *template
+	content.Status = ""
+	content.LastUpdated = time.Time{}
+	content.ApprovalRequestID = ""
+
+	contentJSON, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal template content: %v", err)
+	}
+
+	hash := sha256.Sum256(contentJSON)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// verifyActivationApproval checks that an executed approval request authorises activating a template
+// with exactly the content it has now
+func verifyActivationApproval(ctx contractapi.TransactionContextInterface,
+	requestID string, templateID string, contentHash string) error {
+
+	requestJSON, err := invokeChaincode(ctx, approvalManagerChaincode, "GetApprovalRequest", requestID)
+	if err != nil {
+		return err
+	}
+
+	var request struct {
+		ChaincodeName   string          `json:"chaincodeName"`
+		FunctionName    string          `json:"functionName"`
+		Arguments       []string        `json:"arguments"`
+		Approvals       map[string]bool `json:"approvals"`
+		Status          string          `json:"status"`
+		ExecutionResult string          `json:"executionResult"`
+	}
+	err = json.Unmarshal(requestJSON, &request)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal approval request: %v", err)
+	}
+
+	if request.Status != "EXECUTED" || !strings.HasPrefix(request.ExecutionResult, "SUCCESS") {
+		return fmt.Errorf("approval request %s has not been executed successfully (status: %s)", requestID, request.Status)
+	}
+	if request.ChaincodeName != "policy-template" || request.FunctionName != "ValidateTemplateParameters" ||
+		len(request.Arguments) < 2 || request.Arguments[0] != templateID {
+		return fmt.Errorf("approval request %s does not cover template %s", requestID, templateID)
+	}
+	if request.Arguments[1] != contentHash {
+		return fmt.Errorf("template %s has changed since approval request %s was created", templateID, requestID)
+	}
+
+	// Sort approving MSPs so lookups happen in a deterministic order
+	var msps []string
+	for msp, approved := range request.Approvals {
+		if approved {
+			msps = append(msps, msp)
+		}
+	}
+	sort.Strings(msps)
+
+	insurer, oversight := false, false
+	for _, msp := range msps {
+		orgJSON, err := invokeChaincode(ctx, accessControlChaincode, "GetOrganizationByMSP", msp)
+		if err != nil {
+			return err
+		}
+
+		var org struct {
+			OrgType string `json:"orgType"`
+			Status  string `json:"status"`
+		}
+		err = json.Unmarshal(orgJSON, &org)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal organization: %v", err)
+		}
+		if org.Status != "Active" {
+			continue
+		}
+
+		switch org.OrgType {
+		case "Insurer":
+			insurer = true
+		case "Regulator", "Auditor":
+			oversight = true
+		}
+	}
+
+	if !insurer {
+		return fmt.Errorf("approval request %s lacks an insurer approval", requestID)
+	}
+	if !oversight {
+		return fmt.Errorf("approval request %s lacks a regulator or auditor approval", requestID)
+	}
+
+	return nil
+}
+
+// farmerLocation resolves a farmer's district and coordinates from the farmer chaincode
+func farmerLocation(ctx contractapi.TransactionContextInterface, farmerID string) (*PricingLocation, error) {
+	farmerJSON, err := invokeChaincode(ctx, farmerChaincode, "GetFarmer", farmerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var farmer struct {
+		FarmLocation PricingLocation `json:"farmLocation"`
+	}
+	err = json.Unmarshal(farmerJSON, &farmer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal farmer: %v", err)
+	}
+
+	return &farmer.FarmLocation, nil
+}
+
+// invokeChaincode calls a function on another chaincode in the same channel
+func invokeChaincode(ctx contractapi.TransactionContextInterface,
+	chaincodeName string, functionName string, args ...string) ([]byte, error) {
+
+	invokeArgs := make([][]byte, len(args)+1)
+	invokeArgs[0] = []byte(functionName)
+	for i, arg := range args {
+		invokeArgs[i+1] = []byte(arg)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(chaincodeName, invokeArgs, ctx.GetStub().GetChannelID())
+	if response.Status != 200 {
+		return nil, fmt.Errorf("%s.%s failed: %s", chaincodeName, functionName, response.Message)
+	}
+
+	return response.Payload, nil
+}
+
+// diffTemplates lists pricing and coverage changes and the thresholds added or removed
+func diffTemplates(from *PolicyTemplate, to *PolicyTemplate) *TemplateDiff {
+	diff := &TemplateDiff{
+		FromTemplateID:    from.TemplateID,
+		ToTemplateID:      to.TemplateID,
+		SameLineage:       templateLineage(from) == templateLineage(to),
+		PricingChanges:    []TemplateChange{},
+		CoverageChanges:   []TemplateChange{},
+		AddedThresholds:   []IndexThreshold{},
+		RemovedThresholds: []IndexThreshold{},
+	}
+
+	fromModel, toModel := from.PricingModel, to.PricingModel
+	diff.PricingChanges = appendChange(diff.PricingChanges, "baseRate",
+		formatNumber(fromModel.BaseRate), formatNumber(toModel.BaseRate))
+	diff.PricingChanges = appendChange(diff.PricingChanges, "riskMultiplier",
+		formatNumber(fromModel.RiskMultiplier), formatNumber(toModel.RiskMultiplier))
+	diff.PricingChanges = appendChange(diff.PricingChanges, "farmSizeFactor",
+		formatNumber(fromModel.FarmSizeFactor), formatNumber(toModel.FarmSizeFactor))
+	diff.PricingChanges = appendChange(diff.PricingChanges, "historyDiscount",
+		formatNumber(fromModel.HistoryDiscount), formatNumber(toModel.HistoryDiscount))
+	diff.PricingChanges = appendChange(diff.PricingChanges, "minPremium",
+		formatNumber(from.MinPremium), formatNumber(to.MinPremium))
+	diff.PricingChanges = appendChange(diff.PricingChanges, "formula", fromModel.Formula, toModel.Formula)
+	diff.PricingChanges = append(diff.PricingChanges,
+		diffRates("parameters.", fromModel.Parameters, toModel.Parameters)...)
+
+	tables := make(map[string]bool)
+	for name := range fromModel.RateTables {
+		tables[name] = true
+	}
+	for name := range toModel.RateTables {
+		tables[name] = true
+	}
+	for _, name := range sortedKeys(tables) {
+		diff.PricingChanges = append(diff.PricingChanges,
+			diffRates("rateTables."+name+".", fromModel.RateTables[name], toModel.RateTables[name])...)
+	}
+
+	fromVersion, fromLoadings := regionalLoadings(from.RegionalRates)
+	toVersion, toLoadings := regionalLoadings(to.RegionalRates)
+	diff.PricingChanges = appendChange(diff.PricingChanges, "regionalRates.version",
+		strconv.Itoa(fromVersion), strconv.Itoa(toVersion))
+	diff.PricingChanges = append(diff.PricingChanges,
+		diffRates("regionalRates.", fromLoadings, toLoadings)...)
+
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "cropType", from.CropType, to.CropType)
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "region", from.Region, to.Region)
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "riskLevel", from.RiskLevel, to.RiskLevel)
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "coveragePeriod",
+		strconv.Itoa(from.CoveragePeriod), strconv.Itoa(to.CoveragePeriod))
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "maxCoverage",
+		formatNumber(from.MaxCoverage), formatNumber(to.MaxCoverage))
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "waitingDays",
+		strconv.Itoa(from.WaitingDays), strconv.Itoa(to.WaitingDays))
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "coolingOffDays",
+		strconv.Itoa(from.CoolingOffDays), strconv.Itoa(to.CoolingOffDays))
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "perilRule.rule",
+		from.PerilRule.Rule, to.PerilRule.Rule)
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "perilRule.capPercent",
+		formatNumber(from.PerilRule.CapPercent), formatNumber(to.PerilRule.CapPercent))
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "perilRule.eventWindowDays",
+		strconv.Itoa(from.PerilRule.EventWindowDays), strconv.Itoa(to.PerilRule.EventWindowDays))
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "phaseCapPercent",
+		formatNumber(from.PhaseCapPercent), formatNumber(to.PhaseCapPercent))
+
+	// Phases and the onset rule are compared as whole configurations
+	fromPhases, _ := json.Marshal(from.Phases)
+	toPhases, _ := json.Marshal(to.Phases)
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "phases", string(fromPhases), string(toPhases))
+	fromOnset, _ := json.Marshal(from.OnsetRule)
+	toOnset, _ := json.Marshal(to.OnsetRule)
+	diff.CoverageChanges = appendChange(diff.CoverageChanges, "onsetRule", string(fromOnset), string(toOnset))
+
+	// Thresholds have no identity of their own; compare them as a multiset of values
+	remaining := make(map[string]int)
+	for _, threshold := range from.IndexThresholds {
+		remaining[thresholdKey(threshold)]++
+	}
+	for _, threshold := range to.IndexThresholds {
+		if key := thresholdKey(threshold); remaining[key] > 0 {
+			remaining[key]--
+			continue
+		}
+		diff.AddedThresholds = append(diff.AddedThresholds, threshold)
+	}
+	for _, threshold := range from.IndexThresholds {
+		if key := thresholdKey(threshold); remaining[key] > 0 {
+			remaining[key]--
+			diff.RemovedThresholds = append(diff.RemovedThresholds, threshold)
+		}
+	}
+
+	return diff
+}
+
+// regionalLoadings returns a rate table's version and its loadings keyed by district or cell
+func regionalLoadings(table *RegionalRateTable) (int, map[string]float64) {
+	loadings := make(map[string]float64)
+	if table == nil {
+		return 0, loadings
+	}
+	for _, rate := range table.Rates {
+		if rate.Cell != "" {
+			loadings["cell:"+rate.Cell] = rate.RiskLoading
+		} else {
+			loadings[rate.District] = rate.RiskLoading
+		}
+	}
+	return table.Version, loadings
+}
+
+// thresholdKey identifies a threshold by its full configuration, payout curve included
+func thresholdKey(threshold IndexThreshold) string {
+	key, _ := json.Marshal(threshold)
+	return string(key)
+}
+
+// diffRates compares two named rate maps, reporting absent entries as empty values
+func diffRates(prefix string, from map[string]float64, to map[string]float64) []TemplateChange {
+	names := make(map[string]bool)
+	for name := range from {
+		names[name] = true
+	}
+	for name := range to {
+		names[name] = true
+	}
+
+	var changes []TemplateChange
+	for _, name := range sortedKeys(names) {
+		fromValue, toValue := "", ""
+		if value, ok := from[name]; ok {
+			fromValue = formatNumber(value)
+		}
+		if value, ok := to[name]; ok {
+			toValue = formatNumber(value)
+		}
+		changes = appendChange(changes, prefix+name, fromValue, toValue)
+	}
+	return changes
+}
+
+func appendChange(changes []TemplateChange, field string, from string, to string) []TemplateChange {
+	if from == to {
+		return changes
+	}
+	return append(changes, TemplateChange{Field: field, From: from, To: to})
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// templateLineage treats templates created before lineage tracking as their own lineage root
+func templateLineage(template *PolicyTemplate) string {
+	if template.LineageID == "" {
+		return template.TemplateID
+	}
+	return template.LineageID
+}
+
 func (pt *PolicyTemplateChaincode) templateExists(ctx contractapi.TransactionContextInterface, templateID string) (bool, error) {
 	templateJSON, err := ctx.GetStub().GetState(templateID)
 	if err != nil {
@@ -0,0 +1,586 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"payoutcurve"
+)
+
+// Backtesting replays a template's index thresholds over historical weather
+// readings, one coverage season per year, and prices the template as it would
+// be sold. RunBacktest is a pure function of its inputs, so the chaincode query
+// and the offline command report the same figures for the same data.
+const dryDayRainfall = 1.0 // Days with less rainfall (mm) count towards a drought spell
+
+// BacktestObservation is a historical weather reading; it decodes WeatherData ledger exports
+type BacktestObservation struct {
+	Location    string    `json:"location"`    // Geographic location/region
+	Timestamp   time.Time `json:"timestamp"`   // Observation timestamp
+	Rainfall    float64   `json:"rainfall"`    // Rainfall in mm
+	Temperature float64   `json:"temperature"` // Temperature in Celsius
+	Humidity    float64   `json:"humidity"`    // Humidity percentage
+	Status      string    `json:"status"`      // Anomalous readings are ignored
+}
+
+// BacktestBaseline holds historical seasonal averages; it decodes RegionalBaseline ledger exports
+type BacktestBaseline struct {
+	BaselineID     string             `json:"baselineID"`     // Baseline identifier
+	BaselineValues map[string]float64 `json:"baselineValues"` // Seasonal averages by metric
+}
+
+// BacktestOptions describes the policy being simulated
+type BacktestOptions struct {
+	SeasonStart    string  `json:"seasonStart"`    // MM-DD each season's coverage starts
+	CoverageAmount float64 `json:"coverageAmount"` // Sum insured per season
+	FarmSize       float64 `json:"farmSize"`       // Farm size used for pricing
+	District       string  `json:"district"`       // District for regional rates; empty prices without a loading
+}
+
+// BacktestTrigger is a threshold breach found in a historical season
+type BacktestTrigger struct {
+	IndexType      string    `json:"indexType"`      // Threshold index type
+	Operator       string    `json:"operator"`       // Threshold operator
+	ThresholdValue float64   `json:"thresholdValue"` // Threshold trigger value
+	WindowStart    time.Time `json:"windowStart"`    // First day of the breaching window
+	ObservedValue  float64   `json:"observedValue"`  // Index value in that window
+	PayoutPercent  float64   `json:"payoutPercent"`  // Threshold payout (% of coverage)
+	Counted        bool      `json:"counted"`        // Whether the peril rule counted it
+}
+
+// BacktestSeason is the simulated outcome of one coverage season
+type BacktestSeason struct {
+	Season        string             `json:"season"`        // Year the season started
+	StartDate     time.Time          `json:"startDate"`     // Coverage start
+	EndDate       time.Time          `json:"endDate"`       // Coverage end (exclusive)
+	DaysObserved  int                `json:"daysObserved"`  // Days in the season with readings
+	Triggers      []*BacktestTrigger `json:"triggers"`      // Breaches in event order
+	OnsetDate     *time.Time         `json:"onsetDate"`     // Onset of rains, when the template has a rule
+	Phases        []*BacktestPhase   `json:"phases"`        // Growth phase outcomes
+	PhasePercent  float64            `json:"phasePercent"`  // Summed phase payouts after the phase cap
+	PayoutPercent float64            `json:"payoutPercent"` // Combined payout after the peril rule and phases
+	Loss          float64            `json:"loss"`          // Payout amount
+}
+
+// BacktestPhase is the simulated outcome of one growth phase
+type BacktestPhase struct {
+	Name          string             `json:"name"`          // Growth phase name
+	StartDate     time.Time          `json:"startDate"`     // Phase start after any onset shift
+	EndDate       time.Time          `json:"endDate"`       // Phase end (exclusive)
+	Triggers      []*BacktestTrigger `json:"triggers"`      // Phase threshold breaches
+	PayoutPercent float64            `json:"payoutPercent"` // Weighted phase payout (% of coverage)
+}
+
+// BacktestReport summarises how a template would have performed historically
+type BacktestReport struct {
+	TemplateID          string            `json:"templateID"`          // Template replayed
+	SeasonStart         string            `json:"seasonStart"`         // MM-DD season start
+	CoverageAmount      float64           `json:"coverageAmount"`      // Sum insured per season
+	Premium             float64           `json:"premium"`             // Premium the template charges
+	SeasonsEvaluated    int               `json:"seasonsEvaluated"`    // Seasons with enough data to evaluate
+	SeasonsSkipped      int               `json:"seasonsSkipped"`      // Seasons with readings but no complete window
+	SeasonsTriggered    int               `json:"seasonsTriggered"`    // Seasons that would have paid out
+	TriggerFrequency    float64           `json:"triggerFrequency"`    // Share of evaluated seasons that paid out
+	ExpectedLoss        float64           `json:"expectedLoss"`        // Mean payout per season
+	ExpectedLossPercent float64           `json:"expectedLossPercent"` // Mean payout as % of coverage
+	LossRatio           float64           `json:"lossRatio"`           // Expected loss over premium
+	WorstSeason         string            `json:"worstSeason"`         // Season with the largest payout
+	WorstSeasonLoss     float64           `json:"worstSeasonLoss"`     // Largest season payout
+	Seasons             []*BacktestSeason `json:"seasons"`             // Evaluated seasons in order
+}
+
+// backtestDay averages the readings for one calendar day
+type backtestDay struct {
+	rainfall, temperature, humidity float64
+	readings                        int
+}
+
+// ========================================
+// BACKTEST ENGINE
+// ========================================
+
+// RunBacktest simulates one policy per season over the observations and summarises the losses
+func RunBacktest(template *PolicyTemplate, observations []*BacktestObservation,
+	baseline *BacktestBaseline, options BacktestOptions) (*BacktestReport, error) {
+
+	if len(template.IndexThresholds) == 0 && len(template.Phases) == 0 {
+		return nil, fmt.Errorf("template %s has no index thresholds or growth phases", template.TemplateID)
+	}
+	if template.CoveragePeriod <= 0 {
+		return nil, fmt.Errorf("template %s has no coverage period", template.TemplateID)
+	}
+	if options.CoverageAmount <= 0 {
+		return nil, fmt.Errorf("coverage amount must be positive")
+	}
+	if template.MaxCoverage > 0 && options.CoverageAmount > template.MaxCoverage {
+		return nil, fmt.Errorf("coverage amount exceeds template maximum of %.2f", template.MaxCoverage)
+	}
+	seasonStart, err := time.Parse("01-02", options.SeasonStart)
+	if err != nil {
+		return nil, fmt.Errorf("season start must be MM-DD: %v", err)
+	}
+	thresholds := append([]IndexThreshold{}, template.IndexThresholds...)
+	for _, phase := range template.Phases {
+		thresholds = append(thresholds, phase.Thresholds...)
+	}
+	for _, threshold := range thresholds {
+		if thresholdUsesBaseline(threshold) && baselineValue(baseline, threshold.IndexType) == 0 {
+			return nil, fmt.Errorf("%s threshold is measured against a baseline but none was provided",
+				threshold.IndexType)
+		}
+	}
+
+	var location *PricingLocation
+	if options.District != "" {
+		location = &PricingLocation{District: options.District}
+	}
+	breakdown, err := QuotePremium(template, options.CoverageAmount, options.FarmSize, 0, location)
+	if err != nil {
+		return nil, err
+	}
+
+	days := make(map[string]*backtestDay)
+	var first, last time.Time
+	for _, observation := range observations {
+		if observation.Status == "Anomalous" {
+			continue
+		}
+		date := observation.Timestamp.UTC()
+		date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		key := date.Format("2006-01-02")
+		day, ok := days[key]
+		if !ok {
+			day = &backtestDay{}
+			days[key] = day
+		}
+		day.rainfall += observation.Rainfall
+		day.temperature += observation.Temperature
+		day.humidity += observation.Humidity
+		day.readings++
+
+		if first.IsZero() || date.Before(first) {
+			first = date
+		}
+		if last.IsZero() || date.After(last) {
+			last = date
+		}
+	}
+	for _, day := range days {
+		n := float64(day.readings)
+		day.rainfall /= n
+		day.temperature /= n
+		day.humidity /= n
+	}
+
+	report := &BacktestReport{
+		TemplateID:     template.TemplateID,
+		SeasonStart:    options.SeasonStart,
+		CoverageAmount: options.CoverageAmount,
+		Premium:        breakdown.Premium,
+		Seasons:        []*BacktestSeason{},
+	}
+	if len(days) == 0 {
+		return report, nil
+	}
+
+	// A season starting the year before the first reading may still overlap it
+	totalLoss := 0.0
+	for year := first.Year() - 1; year <= last.Year(); year++ {
+		start := time.Date(year, seasonStart.Month(), seasonStart.Day(), 0, 0, 0, 0, time.UTC)
+		end := start.AddDate(0, 0, template.CoveragePeriod)
+		if !end.After(first) || start.After(last) {
+			continue
+		}
+
+		season, evaluated := backtestSeason(template, days, baseline, start, end)
+		if !evaluated {
+			report.SeasonsSkipped++
+			continue
+		}
+		season.Season = fmt.Sprintf("%d", year)
+		season.Loss = math.Round(options.CoverageAmount*season.PayoutPercent) / 100
+
+		report.Seasons = append(report.Seasons, season)
+		report.SeasonsEvaluated++
+		if season.Loss > 0 {
+			report.SeasonsTriggered++
+		}
+		if season.Loss > report.WorstSeasonLoss {
+			report.WorstSeason = season.Season
+			report.WorstSeasonLoss = season.Loss
+		}
+		totalLoss += season.Loss
+	}
+
+	if report.SeasonsEvaluated > 0 {
+		seasons := float64(report.SeasonsEvaluated)
+		report.TriggerFrequency = float64(report.SeasonsTriggered) / seasons
+		report.ExpectedLoss = math.Round(totalLoss/seasons*100) / 100
+		report.ExpectedLossPercent = report.ExpectedLoss / options.CoverageAmount * 100
+	}
+	if report.Premium > 0 {
+		report.LossRatio = report.ExpectedLoss / report.Premium
+	}
+
+	return report, nil
+}
+
+// backtestSeason evaluates every threshold over one season. Only windows with a
+// reading for every day are evaluated, so gaps in the history never look like
+// a dry spell; a season with no complete window is reported as not evaluated.
+func backtestSeason(template *PolicyTemplate, days map[string]*backtestDay, baseline *BacktestBaseline,
+	start time.Time, end time.Time) (*BacktestSeason, bool) {
+
+	season := &BacktestSeason{StartDate: start, EndDate: end, Triggers: []*BacktestTrigger{},
+		Phases: []*BacktestPhase{}}
+
+	var full []*backtestDay
+	for date := start; date.Before(end); date = date.AddDate(0, 0, 1) {
+		day := days[date.Format("2006-01-02")]
+		if day != nil {
+			season.DaysObserved++
+		}
+		full = append(full, day)
+	}
+
+	// Index events during the waiting period do not count
+	waiting := template.WaitingDays
+	if waiting > len(full) {
+		waiting = len(full)
+	}
+	eligible := start.AddDate(0, 0, waiting)
+	series := full[waiting:]
+
+	evaluated := false
+	for _, threshold := range template.IndexThresholds {
+		var trigger *BacktestTrigger
+		var complete bool
+		if threshold.IndexType == "Drought" {
+			trigger, complete = droughtTrigger(threshold, series, eligible)
+		} else {
+			trigger, complete = windowTrigger(threshold, series, eligible, baseline, template.CoveragePeriod)
+		}
+		evaluated = evaluated || complete
+		if trigger != nil {
+			season.Triggers = append(season.Triggers, trigger)
+		}
+	}
+
+	// Phases starting from the onset of rains are only placed once the onset is known
+	onset, onsetKnown := 0, false
+	if template.OnsetRule != nil {
+		onset, onsetKnown = detectOnset(template.OnsetRule, full)
+		if onsetKnown {
+			onsetDate := start.AddDate(0, 0, onset)
+			season.OnsetDate = &onsetDate
+		}
+	}
+
+	phaseTotal := 0.0
+	for _, phase := range template.Phases {
+		if phase.FromOnset && !onsetKnown {
+			continue
+		}
+		offset := phase.StartOffset
+		if phase.FromOnset {
+			offset += onset
+		}
+		outcome, complete := phaseOutcome(phase, full, offset, waiting, start, baseline, template.CoveragePeriod)
+		evaluated = evaluated || complete
+		if complete {
+			season.Phases = append(season.Phases, outcome)
+			phaseTotal += outcome.PayoutPercent
+		}
+	}
+	if !evaluated {
+		return nil, false
+	}
+
+	// Event order; thresholds breached on the same day keep template order
+	sort.SliceStable(season.Triggers, func(i, j int) bool {
+		return season.Triggers[i].WindowStart.Before(season.Triggers[j].WindowStart)
+	})
+	season.PhasePercent = math.Min(phaseTotal, template.PhaseCapPercent)
+	season.PayoutPercent = math.Min(combineBacktestPerils(template.PerilRule, season.Triggers)+season.PhasePercent, 100)
+
+	return season, true
+}
+
+// detectOnset finds the onset of rains as a day offset into the season. Windows
+// with missing readings cannot detect it; the fallback to the latest offset only
+// applies once the season has readings from that day on.
+func detectOnset(rule *OnsetRule, full []*backtestDay) (int, bool) {
+	for offset := rule.EarliestOffset; offset <= rule.LatestOffset && offset+rule.WindowDays <= len(full); offset++ {
+		total, gap := 0.0, false
+		for _, day := range full[offset : offset+rule.WindowDays] {
+			if day == nil {
+				gap = true
+				break
+			}
+			total += day.rainfall
+		}
+		if !gap && total >= rule.RainfallMM {
+			return offset, true
+		}
+	}
+	for offset := rule.LatestOffset; offset < len(full); offset++ {
+		if full[offset] != nil {
+			return rule.LatestOffset, true
+		}
+	}
+	return 0, false
+}
+
+// phaseOutcome evaluates a growth phase's thresholds within the phase and weights
+// the largest payout. Thresholds without measurement days span the whole phase.
+func phaseOutcome(phase GrowthPhase, full []*backtestDay, offset int, waiting int, start time.Time,
+	baseline *BacktestBaseline, coveragePeriod int) (*BacktestPhase, bool) {
+
+	outcome := &BacktestPhase{
+		Name:      phase.Name,
+		StartDate: start.AddDate(0, 0, offset),
+		EndDate:   start.AddDate(0, 0, offset+phase.Duration),
+		Triggers:  []*BacktestTrigger{},
+	}
+
+	from, to := offset, offset+phase.Duration
+	if from < waiting {
+		from = waiting
+	}
+	if to > len(full) {
+		to = len(full)
+	}
+	if from >= to {
+		return nil, false
+	}
+	series := full[from:to]
+	seriesStart := start.AddDate(0, 0, from)
+
+	complete := false
+	var best *BacktestTrigger
+	for _, threshold := range phase.Thresholds {
+		if threshold.MeasurementDays <= 0 || threshold.MeasurementDays > len(series) {
+			threshold.MeasurementDays = len(series)
+		}
+
+		var trigger *BacktestTrigger
+		var evaluated bool
+		if threshold.IndexType == "Drought" {
+			trigger, evaluated = droughtTrigger(threshold, series, seriesStart)
+		} else {
+			trigger, evaluated = windowTrigger(threshold, series, seriesStart, baseline, coveragePeriod)
+		}
+		complete = complete || evaluated
+		if trigger == nil {
+			continue
+		}
+		outcome.Triggers = append(outcome.Triggers, trigger)
+		if best == nil || trigger.PayoutPercent > best.PayoutPercent {
+			best = trigger
+		}
+	}
+
+	if best != nil {
+		best.Counted = true
+		outcome.PayoutPercent = best.PayoutPercent * phase.Weight / 100
+	}
+	return outcome, complete
+}
+
+// windowTrigger slides the threshold's measurement window across the season and
+// returns the first breach, or for a payout curve the window paying most. Rainfall
+// is totalled over the window; other indices are averaged. Thresholds with a
+// percent metric compare the deviation from the baseline, with seasonal rainfall
+// scaled to the window length.
+func windowTrigger(threshold IndexThreshold, series []*backtestDay, eligible time.Time,
+	baseline *BacktestBaseline, coveragePeriod int) (*BacktestTrigger, bool) {
+
+	length := threshold.MeasurementDays
+	if length <= 0 {
+		length = 1
+	}
+
+	complete := false
+	var best *BacktestTrigger
+	for offset := 0; offset+length <= len(series); offset++ {
+		total := 0.0
+		gap := false
+		for _, day := range series[offset : offset+length] {
+			if day == nil {
+				gap = true
+				break
+			}
+			total += dayMetric(day, threshold.IndexType)
+		}
+		if gap {
+			continue
+		}
+		complete = true
+
+		value := total
+		if threshold.IndexType != "Rainfall" {
+			value = total / float64(length)
+		}
+		if thresholdUsesBaseline(threshold) {
+			reference := baselineValue(baseline, threshold.IndexType)
+			if threshold.IndexType == "Rainfall" {
+				reference = reference * float64(length) / float64(coveragePeriod)
+			}
+			value = (value - reference) / reference * 100
+		}
+
+		if threshold.PayoutCurve != nil {
+			payout := payoutcurve.Evaluate(threshold.PayoutCurve, value)
+			if payout > 0 && (best == nil || payout > best.PayoutPercent) {
+				best = newBacktestTrigger(threshold, eligible.AddDate(0, 0, offset), value)
+			}
+			continue
+		}
+		if compareThreshold(value, threshold.Operator, threshold.ThresholdValue) {
+			return newBacktestTrigger(threshold, eligible.AddDate(0, 0, offset), value), true
+		}
+	}
+	return best, complete
+}
+
+// droughtTrigger compares the longest run of dry days in the season with the threshold.
+// Missing days end a run.
+func droughtTrigger(threshold IndexThreshold, series []*backtestDay, eligible time.Time) (*BacktestTrigger, bool) {
+	longest, longestStart, run := 0, 0, 0
+	complete := false
+	for offset, day := range series {
+		if day == nil || day.rainfall >= dryDayRainfall {
+			run = 0
+			complete = complete || day != nil
+			continue
+		}
+		complete = true
+		run++
+		if run > longest {
+			longest = run
+			longestStart = offset - run + 1
+		}
+	}
+	if !complete {
+		return nil, false
+	}
+
+	value := float64(longest)
+	if threshold.PayoutCurve != nil && payoutcurve.Evaluate(threshold.PayoutCurve, value) > 0 ||
+		threshold.PayoutCurve == nil && compareThreshold(value, threshold.Operator, threshold.ThresholdValue) {
+		return newBacktestTrigger(threshold, eligible.AddDate(0, 0, longestStart), value), true
+	}
+	return nil, true
+}
+
+// combineBacktestPerils applies the template's peril rule to triggers in event
+// order, as claim-processor does for a live season
+func combineBacktestPerils(rule PerilCombination, triggers []*BacktestTrigger) float64 {
+	combined := 0.0
+
+	switch rule.Rule {
+	case "AdditiveCapped":
+		for _, trigger := range triggers {
+			trigger.Counted = true
+			combined += trigger.PayoutPercent
+		}
+
+	case "FirstTrigger":
+		// Only the first trigger of each event window pays
+		var windowEnd time.Time
+		for _, trigger := range triggers {
+			if !windowEnd.IsZero() && trigger.WindowStart.Before(windowEnd) {
+				continue
+			}
+			trigger.Counted = true
+			combined += trigger.PayoutPercent
+			windowEnd = trigger.WindowStart.AddDate(0, 0, rule.EventWindowDays)
+		}
+
+	default: // MaxOf
+		var best *BacktestTrigger
+		for _, trigger := range triggers {
+			if best == nil || trigger.PayoutPercent > best.PayoutPercent {
+				best = trigger
+			}
+		}
+		if best != nil {
+			best.Counted = true
+			combined = best.PayoutPercent
+		}
+	}
+
+	capPercent := rule.CapPercent
+	if capPercent <= 0 || capPercent > 100 {
+		capPercent = 100
+	}
+	if combined > capPercent {
+		combined = capPercent
+	}
+
+	return combined
+}
+
+// newBacktestTrigger records a breach, paying along the threshold's curve when it has one
+func newBacktestTrigger(threshold IndexThreshold, windowStart time.Time, value float64) *BacktestTrigger {
+	payoutPercent := threshold.PayoutPercent
+	if threshold.PayoutCurve != nil {
+		payoutPercent = payoutcurve.Evaluate(threshold.PayoutCurve, value)
+	}
+	return &BacktestTrigger{
+		IndexType:      threshold.IndexType,
+		Operator:       threshold.Operator,
+		ThresholdValue: threshold.ThresholdValue,
+		WindowStart:    windowStart,
+		ObservedValue:  value,
+		PayoutPercent:  payoutPercent,
+	}
+}
+
+// dayMetric reads the daily value an index type is measured on
+func dayMetric(day *backtestDay, indexType string) float64 {
+	switch indexType {
+	case "Temperature":
+		return day.temperature
+	case "Humidity":
+		return day.humidity
+	}
+	return day.rainfall
+}
+
+// thresholdUsesBaseline reports whether a threshold is a deviation from the baseline
+func thresholdUsesBaseline(threshold IndexThreshold) bool {
+	return threshold.IndexType != "Drought" && (threshold.Metric == "%" || threshold.Metric == "percent")
+}
+
+func baselineValue(baseline *BacktestBaseline, indexType string) float64 {
+	if baseline == nil {
+		return 0
+	}
+	switch indexType {
+	case "Temperature":
+		return baseline.BaselineValues["temperature"]
+	case "Humidity":
+		return baseline.BaselineValues["humidity"]
+	}
+	return baseline.BaselineValues["rainfall"]
+}
+
+// compareThreshold applies a threshold operator; == allows for rounding in oracle readings
+func compareThreshold(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case "<":
+		return value < threshold
+	case ">":
+		return value > threshold
+	case "<=":
+		return value <= threshold
+	case ">=":
+		return value >= threshold
+	case "==":
+		return math.Abs(value-threshold) < 0.01
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package pricing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateBundleVersion is the bundle format the chaincode and the offline tools read and write
+const TemplateBundleVersion = 1
+
+// TemplateBundle is the portable authoring format for a template: everything that
+// defines the product, without ledger state such as status, lineage or timestamps.
+// Bundles are JSON or YAML with the same field names.
+type TemplateBundle struct {
+	BundleVersion     int    `json:"bundleVersion"`               // Bundle format version
+	TemplateID        string `json:"templateID"`                  // Template to create
+	PreviousVersionID string `json:"previousVersionID,omitempty"` // Released template this bundle is a new version of
+
+	TemplateName   string  `json:"templateName"`   // Descriptive name
+	CropType       string  `json:"cropType"`       // Coffee type (Arabica, Robusta, etc.)
+	Region         string  `json:"region"`         // Geographic region
+	RiskLevel      string  `json:"riskLevel"`      // Low, Medium, High
+	CoveragePeriod int     `json:"coveragePeriod"` // Coverage duration in days
+	MaxCoverage    float64 `json:"maxCoverage"`    // Maximum coverage amount
+	MinPremium     float64 `json:"minPremium"`     // Minimum premium required
+	WaitingDays    int     `json:"waitingDays"`    // Days after start before index events count
+	CoolingOffDays int     `json:"coolingOffDays"` // Days after start the farmer may cancel for a full refund
+
+	Pricing         PricingModel       `json:"pricing"`                 // Premium calculation formula
+	PerilRule       PerilCombination   `json:"perilRule"`               // How triggered perils combine
+	IndexThresholds []IndexThreshold   `json:"indexThresholds"`         // Payout trigger conditions
+	Phases          []GrowthPhase      `json:"phases,omitempty"`        // Crop growth phases
+	PhaseCapPercent float64            `json:"phaseCapPercent"`         // Cap on the summed phase payouts
+	OnsetRule       *OnsetRule         `json:"onsetRule,omitempty"`     // Onset of rains that phases may start from
+	RegionalRates   *RegionalRateTable `json:"regionalRates,omitempty"` // Risk loadings by district or grid cell
+}
+
+// ParseTemplateBundle decodes a JSON or YAML bundle and checks its format version.
+// YAML is converted to JSON first so both formats share the JSON field names.
+func ParseTemplateBundle(content []byte) (*TemplateBundle, error) {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 {
+		return nil, fmt.Errorf("bundle is empty")
+	}
+
+	if content[0] != '{' {
+		var document interface{}
+		if err := yaml.Unmarshal(content, &document); err != nil {
+			return nil, fmt.Errorf("failed to parse bundle YAML: %v", err)
+		}
+		converted, err := json.Marshal(document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert bundle YAML: %v", err)
+		}
+		content = converted
+	}
+
+	var bundle TemplateBundle
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %v", err)
+	}
+
+	if bundle.BundleVersion != TemplateBundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d; expected %d", bundle.BundleVersion, TemplateBundleVersion)
+	}
+	if bundle.TemplateID == "" {
+		return nil, fmt.Errorf("template ID is required")
+	}
+
+	return &bundle, nil
+}
+
+// BundleTemplate builds the Draft template a bundle describes
+func BundleTemplate(bundle *TemplateBundle) *PolicyTemplate {
+	template := &PolicyTemplate{
+		TemplateID:      bundle.TemplateID,
+		TemplateName:    bundle.TemplateName,
+		CropType:        bundle.CropType,
+		Region:          bundle.Region,
+		RiskLevel:       bundle.RiskLevel,
+		CoveragePeriod:  bundle.CoveragePeriod,
+		PricingModel:    bundle.Pricing,
+		IndexThresholds: bundle.IndexThresholds,
+		PerilRule:       bundle.PerilRule,
+		MaxCoverage:     bundle.MaxCoverage,
+		MinPremium:      bundle.MinPremium,
+		WaitingDays:     bundle.WaitingDays,
+		CoolingOffDays:  bundle.CoolingOffDays,
+		Version:         1,
+		Status:          "Draft",
+
+		LineageID: bundle.TemplateID,
+
+		Phases:          bundle.Phases,
+		PhaseCapPercent: bundle.PhaseCapPercent,
+		OnsetRule:       bundle.OnsetRule,
+		RegionalRates:   bundle.RegionalRates,
+	}
+
+	if template.PricingModel.Parameters == nil {
+		template.PricingModel.Parameters = make(map[string]float64)
+	}
+	if template.IndexThresholds == nil {
+		template.IndexThresholds = []IndexThreshold{}
+	}
+	if template.PerilRule.Rule == "" {
+		template.PerilRule = PerilCombination{Rule: "MaxOf", CapPercent: 100}
+	}
+
+	return template
+}
+
+// NewTemplateBundle extracts the bundle that recreates a template
+func NewTemplateBundle(template *PolicyTemplate) *TemplateBundle {
+	return &TemplateBundle{
+		BundleVersion:     TemplateBundleVersion,
+		TemplateID:        template.TemplateID,
+		PreviousVersionID: template.PreviousVersionID,
+		TemplateName:      template.TemplateName,
+		CropType:          template.CropType,
+		Region:            template.Region,
+		RiskLevel:         template.RiskLevel,
+		CoveragePeriod:    template.CoveragePeriod,
+		MaxCoverage:       template.MaxCoverage,
+		MinPremium:        template.MinPremium,
+		WaitingDays:       template.WaitingDays,
+		CoolingOffDays:    template.CoolingOffDays,
+		Pricing:           template.PricingModel,
+		PerilRule:         template.PerilRule,
+		IndexThresholds:   template.IndexThresholds,
+		Phases:            template.Phases,
+		PhaseCapPercent:   template.PhaseCapPercent,
+		OnsetRule:         template.OnsetRule,
+		RegionalRates:     template.RegionalRates,
+	}
+}
+
+// EncodeTemplateBundle writes a bundle as indented JSON or block-style YAML. YAML is
+// produced from the JSON encoding so field names and order match.
+func EncodeTemplateBundle(bundle *TemplateBundle, format string) ([]byte, error) {
+	content, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %v", err)
+	}
+
+	switch format {
+	case "", "json":
+		return content, nil
+	case "yaml":
+		var document yaml.Node
+		if err := yaml.Unmarshal(content, &document); err != nil {
+			return nil, fmt.Errorf("failed to convert bundle to YAML: %v", err)
+		}
+		blockStyle(&document)
+
+		var buffer bytes.Buffer
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&document); err != nil {
+			return nil, fmt.Errorf("failed to marshal bundle YAML: %v", err)
+		}
+		return buffer.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported bundle format: %s", format)
+}
+
+// blockStyle drops the flow style and quoting YAML keeps from JSON input; the
+// encoder still quotes strings that would otherwise read as numbers or booleans
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,596 @@
+package pricing
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Pricing formulas are small programs of named lines, one assignment per line:
+//
+//	base     = coverage * baseRate
+//	loaded   = base * rate("region", region) * (1 + loading)
+//	discount = min(historyDiscount * claimFreeYears, 0.3)
+//	premium  = round(loaded * (1 - discount), 2)
+//
+// Lines may use the pricing inputs, template parameters and earlier lines. There
+// are no loops, assignments are final and evaluation uses exact rational
+// arithmetic, so every endorser computes the same premium bit for bit.
+const (
+	maxFormulaLength = 4096
+	maxFormulaLines  = 64
+	maxFormulaDepth  = 32
+	maxFormulaBits   = 4096 // Bound on numerator plus denominator size of any intermediate value
+	maxRoundDigits   = 12
+)
+
+// defaultPricingFormula reproduces the pricing used before formulas were configurable
+const defaultPricingFormula = `base = coverage * baseRate
+risk = base * riskMultiplier
+sized = if(farmSize > 0, risk * farmSizeFactor, risk)
+discount = min(historyDiscount * claimFreeYears, 0.3)
+premium = sized * (1 - discount)`
+
+// formulaFunctions maps built-in functions to their argument counts; -1 is variadic
+var formulaFunctions = map[string]int{
+	"min":   -1,
+	"max":   -1,
+	"clamp": 3,
+	"round": 2,
+	"floor": 1,
+	"ceil":  1,
+	"abs":   1,
+	"if":    3,
+	"rate":  2,
+}
+
+// formula is a parsed pricing program
+type formula struct {
+	lines []*formulaLine
+}
+
+// formulaLine is one named step of a pricing program
+type formulaLine struct {
+	name   string
+	source string
+	expr   formulaNode
+}
+
+// formulaNode is an expression tree node
+type formulaNode interface{}
+
+type numberNode struct{ value *big.Rat }
+type stringNode struct{ value string }
+type identNode struct{ name string }
+type unaryNode struct {
+	op      string
+	operand formulaNode
+}
+type binaryNode struct {
+	op          string
+	left, right formulaNode
+}
+type callNode struct {
+	function string
+	args     []formulaNode
+}
+
+// formulaScope describes the names a formula may reference
+type formulaScope struct {
+	numbers map[string]bool // Numeric inputs and parameters
+	strings map[string]bool // String inputs usable as rate table keys
+}
+
+// parseFormula parses and statically checks a pricing program against a scope
+func parseFormula(source string, scope *formulaScope) (*formula, error) {
+	if len(source) > maxFormulaLength {
+		return nil, fmt.Errorf("formula exceeds %d characters", maxFormulaLength)
+	}
+
+	defined := make(map[string]bool)
+	f := &formula{}
+	for number, raw := range strings.Split(strings.ReplaceAll(source, ";", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(f.lines) == maxFormulaLines {
+			return nil, fmt.Errorf("formula exceeds %d lines", maxFormulaLines)
+		}
+
+		eq := strings.Index(line, "=")
+		if eq < 0 || strings.HasPrefix(line[eq:], "==") {
+			return nil, fmt.Errorf("line %d: expected name = expression", number+1)
+		}
+		name := strings.TrimSpace(line[:eq])
+		rhs := strings.TrimSpace(line[eq+1:])
+
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("line %d: invalid name %q", number+1, name)
+		}
+		if scope.numbers[name] || scope.strings[name] || formulaFunctions[name] != 0 {
+			return nil, fmt.Errorf("line %d: %s is reserved", number+1, name)
+		}
+		if defined[name] {
+			return nil, fmt.Errorf("line %d: %s is already defined", number+1, name)
+		}
+
+		p := &formulaParser{input: rhs}
+		expr, err := p.parse()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", number+1, err)
+		}
+		if err := checkNumeric(expr, scope, defined); err != nil {
+			return nil, fmt.Errorf("line %d: %v", number+1, err)
+		}
+
+		defined[name] = true
+		f.lines = append(f.lines, &formulaLine{name: name, source: rhs, expr: expr})
+	}
+
+	if !defined["premium"] {
+		return nil, fmt.Errorf("formula must define premium")
+	}
+
+	return f, nil
+}
+
+// checkNumeric verifies that an expression is numeric and references only known names
+func checkNumeric(node formulaNode, scope *formulaScope, defined map[string]bool) error {
+	switch n := node.(type) {
+	case *numberNode:
+		return nil
+	case *stringNode:
+		return fmt.Errorf("string %q can only be used as a rate argument", n.value)
+	case *identNode:
+		if scope.numbers[n.name] || defined[n.name] {
+			return nil
+		}
+		if scope.strings[n.name] {
+			return fmt.Errorf("%s can only be used as a rate key", n.name)
+		}
+		return fmt.Errorf("unknown name %s", n.name)
+	case *unaryNode:
+		return checkNumeric(n.operand, scope, defined)
+	case *binaryNode:
+		if err := checkNumeric(n.left, scope, defined); err != nil {
+			return err
+		}
+		return checkNumeric(n.right, scope, defined)
+	case *callNode:
+		arity, ok := formulaFunctions[n.function]
+		if !ok {
+			return fmt.Errorf("unknown function %s", n.function)
+		}
+		if arity >= 0 && len(n.args) != arity {
+			return fmt.Errorf("%s takes %d arguments", n.function, arity)
+		}
+		if arity < 0 && len(n.args) == 0 {
+			return fmt.Errorf("%s needs at least one argument", n.function)
+		}
+
+		switch n.function {
+		case "rate":
+			if _, ok := n.args[0].(*stringNode); !ok {
+				return fmt.Errorf("rate table name must be a string")
+			}
+			switch key := n.args[1].(type) {
+			case *stringNode:
+			case *identNode:
+				if !scope.strings[key.name] {
+					return fmt.Errorf("rate key %s is not a string input", key.name)
+				}
+			default:
+				return fmt.Errorf("rate key must be a string or string input")
+			}
+			return nil
+		case "round":
+			digits, ok := n.args[1].(*numberNode)
+			if !ok || !digits.value.IsInt() || digits.value.Sign() < 0 ||
+				digits.value.Cmp(big.NewRat(maxRoundDigits, 1)) > 0 {
+				return fmt.Errorf("round digits must be a whole number from 0 to %d", maxRoundDigits)
+			}
+		}
+
+		for _, arg := range n.args {
+			if err := checkNumeric(arg, scope, defined); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported expression")
+}
+
+// ========================================
+// PARSER
+// ========================================
+
+// formulaParser is a recursive descent parser over one expression
+type formulaParser struct {
+	input string
+	pos   int
+	depth int
+}
+
+func (p *formulaParser) parse() (formulaNode, error) {
+	node, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected %q", p.input[p.pos:])
+	}
+	return node, nil
+}
+
+func (p *formulaParser) comparison() (formulaNode, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"<=", ">=", "==", "!=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryNode{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) additive() (formulaNode, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		if p.accept("+") {
+			op = "+"
+		} else if p.accept("-") {
+			op = "-"
+		} else {
+			return left, nil
+		}
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *formulaParser) multiplicative() (formulaNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		if p.accept("*") {
+			op = "*"
+		} else if p.accept("/") {
+			op = "/"
+		} else {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *formulaParser) unary() (formulaNode, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxFormulaDepth {
+		return nil, fmt.Errorf("expression nested deeper than %d", maxFormulaDepth)
+	}
+
+	if p.accept("-") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "-", operand: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *formulaParser) primary() (formulaNode, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		node, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return node, nil
+
+	case c == '"':
+		end := strings.IndexByte(p.input[p.pos+1:], '"')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		value := p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return &stringNode{value: value}, nil
+
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		value, ok := new(big.Rat).SetString(p.input[start:p.pos])
+		if !ok {
+			return nil, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+		}
+		return &numberNode{value: value}, nil
+
+	case isIdentifierStart(c):
+		start := p.pos
+		for p.pos < len(p.input) && isIdentifierPart(p.input[p.pos]) {
+			p.pos++
+		}
+		name := p.input[start:p.pos]
+		if !p.accept("(") {
+			return &identNode{name: name}, nil
+		}
+
+		call := &callNode{function: name}
+		if p.accept(")") {
+			return call, nil
+		}
+		for {
+			arg, err := p.comparison()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.accept(")") {
+				return call, nil
+			}
+			if !p.accept(",") {
+				return nil, fmt.Errorf("expected , or ) in call to %s", name)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q", string(c))
+}
+
+// accept consumes token if it is next in the input
+func (p *formulaParser) accept(token string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.input[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *formulaParser) skipSpace() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t' || p.input[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+func isIdentifier(name string) bool {
+	if name == "" || !isIdentifierStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isIdentifierPart(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || c >= '0' && c <= '9'
+}
+
+// ========================================
+// EVALUATION
+// ========================================
+
+// formulaEnv holds the values a formula is evaluated against
+type formulaEnv struct {
+	numbers map[string]*big.Rat
+	strings map[string]string
+	tables  map[string]map[string]float64
+}
+
+// evaluate runs every line in order, returning each line's value
+func (f *formula) evaluate(env *formulaEnv) ([]*big.Rat, error) {
+	values := make([]*big.Rat, len(f.lines))
+	for i, line := range f.lines {
+		value, err := env.eval(line.expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", line.name, err)
+		}
+		env.numbers[line.name] = value
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (env *formulaEnv) eval(node formulaNode) (*big.Rat, error) {
+	switch n := node.(type) {
+	case *numberNode:
+		return n.value, nil
+
+	case *identNode:
+		value, ok := env.numbers[n.name]
+		if !ok {
+			return nil, fmt.Errorf("%s has no value", n.name)
+		}
+		return value, nil
+
+	case *unaryNode:
+		operand, err := env.eval(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Rat).Neg(operand), nil
+
+	case *binaryNode:
+		left, err := env.eval(n.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := env.eval(n.right)
+		if err != nil {
+			return nil, err
+		}
+		return binaryOp(n.op, left, right)
+
+	case *callNode:
+		return env.call(n)
+	}
+	return nil, fmt.Errorf("unsupported expression")
+}
+
+func binaryOp(op string, left *big.Rat, right *big.Rat) (*big.Rat, error) {
+	result := new(big.Rat)
+	switch op {
+	case "+":
+		result.Add(left, right)
+	case "-":
+		result.Sub(left, right)
+	case "*":
+		result.Mul(left, right)
+	case "/":
+		if right.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		result.Quo(left, right)
+	default:
+		cmp := left.Cmp(right)
+		holds := op == "<" && cmp < 0 || op == "<=" && cmp <= 0 || op == ">" && cmp > 0 ||
+			op == ">=" && cmp >= 0 || op == "==" && cmp == 0 || op == "!=" && cmp != 0
+		if holds {
+			result.SetInt64(1)
+		}
+	}
+
+	if result.Num().BitLen()+result.Denom().BitLen() > maxFormulaBits {
+		return nil, fmt.Errorf("value exceeds formula precision limit")
+	}
+	return result, nil
+}
+
+func (env *formulaEnv) call(n *callNode) (*big.Rat, error) {
+	switch n.function {
+	case "if":
+		// Only the chosen branch is evaluated
+		condition, err := env.eval(n.args[0])
+		if err != nil {
+			return nil, err
+		}
+		if condition.Sign() != 0 {
+			return env.eval(n.args[1])
+		}
+		return env.eval(n.args[2])
+
+	case "rate":
+		tableName := n.args[0].(*stringNode).value
+		var key string
+		switch k := n.args[1].(type) {
+		case *stringNode:
+			key = k.value
+		case *identNode:
+			key = env.strings[k.name]
+		}
+		table, ok := env.tables[tableName]
+		if !ok {
+			return nil, fmt.Errorf("rate table %s does not exist", tableName)
+		}
+		rate, ok := table[key]
+		if !ok {
+			// "*" is the table's default entry
+			if rate, ok = table["*"]; !ok {
+				return nil, fmt.Errorf("rate table %s has no entry for %s", tableName, key)
+			}
+		}
+		return new(big.Rat).SetFloat64(rate), nil
+	}
+
+	args := make([]*big.Rat, len(n.args))
+	for i, arg := range n.args {
+		value, err := env.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+
+	switch n.function {
+	case "min", "max":
+		best := args[0]
+		for _, arg := range args[1:] {
+			if n.function == "min" && arg.Cmp(best) < 0 || n.function == "max" && arg.Cmp(best) > 0 {
+				best = arg
+			}
+		}
+		return best, nil
+	case "clamp":
+		if args[1].Cmp(args[2]) > 0 {
+			return nil, fmt.Errorf("clamp lower bound exceeds upper bound")
+		}
+		if args[0].Cmp(args[1]) < 0 {
+			return args[1], nil
+		}
+		if args[0].Cmp(args[2]) > 0 {
+			return args[2], nil
+		}
+		return args[0], nil
+	case "round":
+		return roundRat(args[0], int(args[1].Num().Int64())), nil
+	case "floor":
+		return new(big.Rat).SetInt(floorRat(args[0])), nil
+	case "ceil":
+		return new(big.Rat).SetInt(new(big.Int).Neg(floorRat(new(big.Rat).Neg(args[0])))), nil
+	case "abs":
+		return new(big.Rat).Abs(args[0]), nil
+	}
+	return nil, fmt.Errorf("unknown function %s", n.function)
+}
+
+// floorRat rounds towards negative infinity; Euclidean division by the
+// always-positive denominator floors
+func floorRat(x *big.Rat) *big.Int {
+	return new(big.Int).Div(x.Num(), x.Denom())
+}
+
+// roundRat rounds half away from zero to a number of decimal digits
+func roundRat(x *big.Rat, digits int) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	scaled := new(big.Rat).Mul(new(big.Rat).Abs(x), new(big.Rat).SetInt(scale))
+	scaled.Add(scaled, big.NewRat(1, 2))
+	rounded := new(big.Rat).SetFrac(floorRat(scaled), scale)
+	if x.Sign() < 0 {
+		rounded.Neg(rounded)
+	}
+	return rounded
+}
@@ -0,0 +1,123 @@
+package pricing
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// pricingInputs are the numeric inputs every pricing formula may reference
+var pricingInputs = []string{"coverage", "farmSize", "claimFreeYears", "baseRate", "riskMultiplier",
+	"farmSizeFactor", "historyDiscount", "minPremium", "maxCoverage"}
+
+// pricingScope lists the names a template's pricing formula may reference
+func pricingScope(template *PolicyTemplate) *formulaScope {
+	scope := &formulaScope{
+		numbers: make(map[string]bool),
+		strings: map[string]bool{"region": true, "cropType": true, "riskLevel": true},
+	}
+	for _, name := range pricingInputs {
+		scope.numbers[name] = true
+	}
+	for name := range template.PricingModel.Parameters {
+		if isIdentifier(name) && !scope.strings[name] && formulaFunctions[name] == 0 {
+			scope.numbers[name] = true
+		}
+	}
+	return scope
+}
+
+// ValidateFormula checks that a pricing formula parses and only references names the template defines
+func ValidateFormula(template *PolicyTemplate, source string) error {
+	_, err := parseFormula(source, pricingScope(template))
+	return err
+}
+
+// pricingEnv binds pricing inputs, template settings and parameters for evaluation
+func pricingEnv(template *PolicyTemplate, coverageAmount float64, farmSize float64, claimFreeYears int) *formulaEnv {
+	model := template.PricingModel
+	env := &formulaEnv{
+		numbers: map[string]*big.Rat{
+			"coverage":        new(big.Rat).SetFloat64(coverageAmount),
+			"farmSize":        new(big.Rat).SetFloat64(farmSize),
+			"claimFreeYears":  new(big.Rat).SetInt64(int64(claimFreeYears)),
+			"baseRate":        new(big.Rat).SetFloat64(model.BaseRate),
+			"riskMultiplier":  new(big.Rat).SetFloat64(model.RiskMultiplier),
+			"farmSizeFactor":  new(big.Rat).SetFloat64(model.FarmSizeFactor),
+			"historyDiscount": new(big.Rat).SetFloat64(model.HistoryDiscount),
+			"minPremium":      new(big.Rat).SetFloat64(template.MinPremium),
+			"maxCoverage":     new(big.Rat).SetFloat64(template.MaxCoverage),
+		},
+		strings: map[string]string{
+			"region":    template.Region,
+			"cropType":  template.CropType,
+			"riskLevel": template.RiskLevel,
+		},
+		tables: model.RateTables,
+	}
+	// Parameters cannot shadow the pricing inputs
+	for name, value := range model.Parameters {
+		if _, exists := env.numbers[name]; !exists && isIdentifier(name) {
+			env.numbers[name] = new(big.Rat).SetFloat64(value)
+		}
+	}
+	return env
+}
+
+// QuotePremium prices a template without touching the ledger, so offline tools price identically.
+// The regional risk loading applies when the template is regionally rated and a location is given.
+func QuotePremium(template *PolicyTemplate, coverageAmount float64, farmSize float64, claimFreeYears int,
+	location *PricingLocation) (*PremiumBreakdown, error) {
+	source := template.PricingModel.Formula
+	if source == "" {
+		source = defaultPricingFormula
+	}
+
+	program, err := parseFormula(source, pricingScope(template))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pricing formula: %v", err)
+	}
+
+	env := pricingEnv(template, coverageAmount, farmSize, claimFreeYears)
+	values, err := program.evaluate(env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate pricing formula: %v", err)
+	}
+
+	breakdown := &PremiumBreakdown{
+		TemplateID:     template.TemplateID,
+		DefaultFormula: template.PricingModel.Formula == "",
+		Lines:          make([]PremiumLine, len(values)),
+	}
+	for i, line := range program.lines {
+		value, _ := values[i].Float64()
+		breakdown.Lines[i] = PremiumLine{Name: line.name, Expression: line.source, Value: value}
+	}
+
+	premium := roundRat(env.numbers["premium"], 2)
+	if premium.Sign() < 0 {
+		return nil, fmt.Errorf("pricing formula produced a negative premium")
+	}
+	breakdown.FormulaPremium, _ = premium.Float64()
+
+	if template.RegionalRates != nil && location != nil {
+		rate, err := resolveRegionalRate(template.RegionalRates, location)
+		if err != nil {
+			return nil, err
+		}
+		loading := new(big.Rat).Add(big.NewRat(1, 1), new(big.Rat).SetFloat64(rate.RiskLoading))
+		premium = roundRat(new(big.Rat).Mul(premium, loading), 2)
+		breakdown.District = location.District
+		breakdown.RateTableVersion = template.RegionalRates.Version
+		breakdown.RegionalRate = rate
+	}
+	breakdown.LoadedPremium, _ = premium.Float64()
+	breakdown.Premium = breakdown.LoadedPremium
+
+	// Ensure minimum premium
+	if breakdown.Premium < template.MinPremium {
+		breakdown.Premium = template.MinPremium
+		breakdown.MinPremiumApplied = true
+	}
+
+	return breakdown, nil
+}
@@ -0,0 +1,489 @@
+// Package pricing holds the policy template terms and the ledger-free code that
+// validates, prices, bundles and backtests them. The policy-template chaincode and
+// the template-tools command both use it, so they accept and price templates alike.
+package pricing
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"geogrid"
+	"payoutcurve"
+)
+
+// PolicyTemplate defines a reusable insurance policy structure
+type PolicyTemplate struct {
+	TemplateID      string           `json:"templateID"`      // Unique template identifier
+	TemplateName    string           `json:"templateName"`    // Descriptive name
+	CropType        string           `json:"cropType"`        // Coffee type (Arabica, Robusta, etc.)
+	Region          string           `json:"region"`          // Geographic region
+	RiskLevel       string           `json:"riskLevel"`       // Low, Medium, High
+	CoveragePeriod  int              `json:"coveragePeriod"`  // Coverage duration in days
+	PricingModel    PricingModel     `json:"pricingModel"`    // Premium calculation formula
+	IndexThresholds []IndexThreshold `json:"indexThresholds"` // Payout trigger conditions
+	PerilRule       PerilCombination `json:"perilRule"`       // How triggered perils combine into one payout
+	MaxCoverage     float64          `json:"maxCoverage"`     // Maximum coverage amount
+	MinPremium      float64          `json:"minPremium"`      // Minimum premium required
+	WaitingDays     int              `json:"waitingDays"`     // Days after start before index events count
+	CoolingOffDays  int              `json:"coolingOffDays"`  // Days after start the farmer may cancel for a full refund
+	Version         int              `json:"version"`         // Template version number
+	Status          string           `json:"status"`          // Active, Deprecated, Draft
+	CreatedBy       string           `json:"createdBy"`       // Creator organization
+	CreatedDate     time.Time        `json:"createdDate"`     // Creation timestamp
+	LastUpdated     time.Time        `json:"lastUpdated"`     // Last modification timestamp
+
+	LineageID         string `json:"lineageID"`         // Template ID of the first version in the lineage
+	PreviousVersionID string `json:"previousVersionID"` // Template this version was derived from
+	ApprovalRequestID string `json:"approvalRequestID"` // Executed approval request that activated this version
+
+	Phases          []GrowthPhase `json:"phases"`              // Crop growth phases with their own triggers
+	PhaseCapPercent float64       `json:"phaseCapPercent"`     // Cap on the summed phase payouts (% of coverage)
+	OnsetRule       *OnsetRule    `json:"onsetRule,omitempty"` // Onset of rains that phases may start from
+
+	RegionalRates *RegionalRateTable `json:"regionalRates,omitempty"` // Risk loadings by district or grid cell
+}
+
+// PricingModel defines how premiums are calculated
+type PricingModel struct {
+	BaseRate        float64            `json:"baseRate"`        // Base premium rate (% of coverage)
+	RiskMultiplier  float64            `json:"riskMultiplier"`  // Risk adjustment factor
+	FarmSizeFactor  float64            `json:"farmSizeFactor"`  // Farm size adjustment
+	HistoryDiscount float64            `json:"historyDiscount"` // Discount for claim-free history
+	Parameters      map[string]float64 `json:"parameters"`      // Additional pricing parameters
+
+	Formula    string                        `json:"formula"`    // Pricing program; empty uses the default formula
+	RateTables map[string]map[string]float64 `json:"rateTables"` // Named rate tables keyed by region, crop, etc.
+}
+
+// RegionalRateTable loads a template's premium by the farmer's district or grid cell,
+// so one template can price a product across many districts
+type RegionalRateTable struct {
+	Version      int            `json:"version"`      // Bumped by every import
+	Rates        []RegionalRate `json:"rates"`        // Rates by district or grid cell
+	ImportedBy   string         `json:"importedBy"`   // Identity that imported this version
+	ImportedDate time.Time      `json:"importedDate"` // Import timestamp
+}
+
+// RegionalRate is the risk loading of one district or grid cell
+type RegionalRate struct {
+	District    string  `json:"district,omitempty"` // Administrative district, or "*" for unlisted districts
+	Cell        string  `json:"cell,omitempty"`     // Coverage grid cell ID, e.g. CELL_136_765
+	RiskLoading float64 `json:"riskLoading"`        // Premium loading as a fraction, e.g. 0.15 adds 15%
+	BaselineID  string  `json:"baselineID"`         // Regional baseline the loading was derived from
+}
+
+// PricingLocation is where a farm lies for regional rating
+type PricingLocation struct {
+	District  string  `json:"district"`  // Administrative district
+	Latitude  float64 `json:"latitude"`  // Farm latitude
+	Longitude float64 `json:"longitude"` // Farm longitude
+}
+
+// PremiumLine is one evaluated line of a pricing formula
+type PremiumLine struct {
+	Name       string  `json:"name"`       // Line name
+	Expression string  `json:"expression"` // Formula expression
+	Value      float64 `json:"value"`      // Evaluated value
+}
+
+// PremiumBreakdown explains how a premium was calculated
+type PremiumBreakdown struct {
+	TemplateID        string        `json:"templateID"`        // Template priced
+	DefaultFormula    bool          `json:"defaultFormula"`    // Whether the built-in formula was used
+	Lines             []PremiumLine `json:"lines"`             // Formula lines in evaluation order
+	FormulaPremium    float64       `json:"formulaPremium"`    // Premium produced by the formula, to the cent
+	MinPremiumApplied bool          `json:"minPremiumApplied"` // Whether the template minimum raised the premium
+	Premium           float64       `json:"premium"`           // Premium charged
+
+	District         string        `json:"district"`               // Farmer's district, when regionally rated
+	RateTableVersion int           `json:"rateTableVersion"`       // Regional rate table version applied, 0 if none
+	RegionalRate     *RegionalRate `json:"regionalRate,omitempty"` // Matching district or grid cell rate
+	LoadedPremium    float64       `json:"loadedPremium"`          // Formula premium after the risk loading
+}
+
+// PerilCombination defines how several triggered indices combine in a season
+type PerilCombination struct {
+	Rule            string  `json:"rule"`            // MaxOf, AdditiveCapped, FirstTrigger
+	CapPercent      float64 `json:"capPercent"`      // Combined payout cap (% of coverage)
+	EventWindowDays int     `json:"eventWindowDays"` // FirstTrigger: later triggers inside the window are ignored
+}
+
+// IndexThreshold defines conditions that trigger payouts
+type IndexThreshold struct {
+	IndexType       string  `json:"indexType"`       // Rainfall, Temperature, Drought, etc.
+	Metric          string  `json:"metric"`          // Measurement unit
+	ThresholdValue  float64 `json:"thresholdValue"`  // Trigger value
+	Operator        string  `json:"operator"`        // <, >, <=, >=, ==
+	MeasurementDays int     `json:"measurementDays"` // Days to measure over
+	PayoutPercent   float64 `json:"payoutPercent"`   // Percentage of coverage to pay
+	Severity        string  `json:"severity"`        // Mild, Moderate, Severe
+
+	PayoutCurve *PayoutCurve `json:"payoutCurve,omitempty"` // Continuous payout; nil pays PayoutPercent as a step
+}
+
+// Payout curves are defined in the shared payoutcurve module, so the index calculator
+// and claim processor pay out exactly what templates price
+type (
+	PayoutCurve = payoutcurve.Curve
+	CurvePoint  = payoutcurve.Point
+)
+
+// GrowthPhase is a crop development stage, such as flowering, with its own triggers.
+// The phase pays Weight percent of coverage times the largest payout among its thresholds.
+type GrowthPhase struct {
+	Name        string           `json:"name"`        // Planting, Flowering, CherryDevelopment, Harvest, etc.
+	StartOffset int              `json:"startOffset"` // Days after the season start, or after the onset of rains
+	Duration    int              `json:"duration"`    // Phase length in days
+	FromOnset   bool             `json:"fromOnset"`   // Whether the phase starts relative to the onset of rains
+	Weight      float64          `json:"weight"`      // Share of coverage the phase insures (%)
+	Thresholds  []IndexThreshold `json:"thresholds"`  // Triggers measured within the phase
+}
+
+// OnsetRule detects the onset of rains: the first day from which WindowDays of
+// readings total at least RainfallMM. Without such a day by LatestOffset, the
+// onset is taken to be LatestOffset.
+type OnsetRule struct {
+	RainfallMM     float64 `json:"rainfallMM"`     // Rain needed within the window
+	WindowDays     int     `json:"windowDays"`     // Consecutive days the rain is totalled over
+	EarliestOffset int     `json:"earliestOffset"` // First day after the season start onset may fall on
+	LatestOffset   int     `json:"latestOffset"`   // Day onset is assumed when not detected earlier
+}
+
+// ========================================
+// VALIDATION
+// ========================================
+
+// ValidateTemplate applies every template rule without touching the ledger, so bundle
+// imports and the offline validator accept exactly what ValidateTemplateParameters does
+func ValidateTemplate(template *PolicyTemplate) error {
+	// Check required fields
+	if template.TemplateName == "" {
+		return fmt.Errorf("template name is required")
+	}
+	if template.CropType == "" {
+		return fmt.Errorf("crop type is required")
+	}
+	if template.Region == "" {
+		return fmt.Errorf("region is required")
+	}
+	if template.CoveragePeriod <= 0 {
+		return fmt.Errorf("coverage period must be positive")
+	}
+	if template.MaxCoverage <= 0 {
+		return fmt.Errorf("max coverage must be positive")
+	}
+	validRiskLevels := map[string]bool{"Low": true, "Medium": true, "High": true}
+	if !validRiskLevels[template.RiskLevel] {
+		return fmt.Errorf("invalid risk level: %s", template.RiskLevel)
+	}
+	if len(template.IndexThresholds) == 0 && len(template.Phases) == 0 {
+		return fmt.Errorf("at least one index threshold or growth phase required")
+	}
+	if template.WaitingDays < 0 || template.WaitingDays >= template.CoveragePeriod {
+		return fmt.Errorf("waiting period must be shorter than the coverage period")
+	}
+	if template.CoolingOffDays < 0 || template.CoolingOffDays >= template.CoveragePeriod {
+		return fmt.Errorf("cooling-off window must be shorter than the coverage period")
+	}
+
+	// Templates created before peril rules default to MaxOf
+	if template.PerilRule.Rule != "" {
+		if err := ValidatePerilCombination(template.PerilRule); err != nil {
+			return err
+		}
+	}
+
+	for i, threshold := range template.IndexThresholds {
+		if err := ValidateIndexThreshold(threshold); err != nil {
+			return fmt.Errorf("threshold %d: %v", i, err)
+		}
+	}
+	if err := ValidateGrowthPhases(template); err != nil {
+		return err
+	}
+
+	// Validate pricing model
+	if template.PricingModel.BaseRate <= 0 || template.PricingModel.BaseRate > 1 {
+		return fmt.Errorf("base rate must be positive and at most 1")
+	}
+	if template.PricingModel.RiskMultiplier <= 0 {
+		return fmt.Errorf("risk multiplier must be positive")
+	}
+	if template.PricingModel.HistoryDiscount < 0 || template.PricingModel.HistoryDiscount > 1 {
+		return fmt.Errorf("history discount must be between 0 and 1")
+	}
+	for _, name := range sortedRateKeys(template.PricingModel.Parameters) {
+		if err := ValidatePricingParameter(name); err != nil {
+			return err
+		}
+	}
+	tables := make([]string, 0, len(template.PricingModel.RateTables))
+	for name := range template.PricingModel.RateTables {
+		tables = append(tables, name)
+	}
+	sort.Strings(tables)
+	for _, name := range tables {
+		if name == "" {
+			return fmt.Errorf("table name is required")
+		}
+		if err := ValidateRateTable(template.PricingModel.RateTables[name]); err != nil {
+			return fmt.Errorf("rate table %s: %v", name, err)
+		}
+	}
+	if template.PricingModel.Formula != "" {
+		if _, err := parseFormula(template.PricingModel.Formula, pricingScope(template)); err != nil {
+			return fmt.Errorf("invalid pricing formula: %v", err)
+		}
+	}
+	if template.RegionalRates != nil {
+		if err := ValidateRegionalRates(template.RegionalRates); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidatePricingParameter checks that a parameter can be referenced by name in pricing formulas
+func ValidatePricingParameter(name string) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("parameter name must be a letter or underscore followed by letters, digits or underscores")
+	}
+	scope := pricingScope(&PolicyTemplate{})
+	if scope.numbers[name] || scope.strings[name] || formulaFunctions[name] != 0 {
+		return fmt.Errorf("parameter name %s is reserved", name)
+	}
+	return nil
+}
+
+// ValidateRateTable checks that a formula rate table has entries and no negative rates
+func ValidateRateTable(rates map[string]float64) error {
+	if len(rates) == 0 {
+		return fmt.Errorf("rate table must have at least one entry")
+	}
+	for _, key := range sortedRateKeys(rates) {
+		if rates[key] < 0 {
+			return fmt.Errorf("rate for %s cannot be negative", key)
+		}
+	}
+	return nil
+}
+
+// sortedRateKeys returns the keys of a rate or parameter map in order, so errors are deterministic
+func sortedRateKeys(rates map[string]float64) []string {
+	keys := make([]string, 0, len(rates))
+	for key := range rates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ValidatePerilCombination checks a combination rule and its parameters
+func ValidatePerilCombination(combination PerilCombination) error {
+	validRules := map[string]bool{"MaxOf": true, "AdditiveCapped": true, "FirstTrigger": true}
+	if !validRules[combination.Rule] {
+		return fmt.Errorf("invalid peril combination rule: %s", combination.Rule)
+	}
+	if combination.CapPercent <= 0 || combination.CapPercent > 100 {
+		return fmt.Errorf("combined payout cap must be between 0 and 100")
+	}
+	if combination.EventWindowDays < 0 {
+		return fmt.Errorf("event window days cannot be negative")
+	}
+	if combination.Rule == "FirstTrigger" && combination.EventWindowDays == 0 {
+		return fmt.Errorf("first-trigger rule requires an event window")
+	}
+	return nil
+}
+
+// ValidateIndexThreshold checks a threshold's index type, operator, payout and curve
+func ValidateIndexThreshold(threshold IndexThreshold) error {
+	validIndexTypes := map[string]bool{
+		"Rainfall": true, "Temperature": true, "Drought": true, "Humidity": true,
+	}
+	if !validIndexTypes[threshold.IndexType] {
+		return fmt.Errorf("invalid index type: %s", threshold.IndexType)
+	}
+
+	validOperators := map[string]bool{"<": true, ">": true, "<=": true, ">=": true, "==": true}
+	if !validOperators[threshold.Operator] {
+		return fmt.Errorf("invalid operator: %s", threshold.Operator)
+	}
+
+	if threshold.PayoutPercent < 0 || threshold.PayoutPercent > 100 {
+		return fmt.Errorf("payout percent must be between 0 and 100")
+	}
+
+	validSeverities := map[string]bool{"Mild": true, "Moderate": true, "Severe": true}
+	if !validSeverities[threshold.Severity] {
+		return fmt.Errorf("invalid severity: %s", threshold.Severity)
+	}
+
+	if threshold.PayoutCurve != nil {
+		return ValidatePayoutCurve(threshold.PayoutCurve)
+	}
+	return nil
+}
+
+// ValidateGrowthPhases checks that phases fit in the coverage period, whether or not
+// they shift with the onset of rains, and that their weights and thresholds are valid
+func ValidateGrowthPhases(template *PolicyTemplate) error {
+	if rule := template.OnsetRule; rule != nil {
+		if rule.RainfallMM <= 0 || rule.WindowDays <= 0 {
+			return fmt.Errorf("onset rule needs positive rainfall and window days")
+		}
+		if rule.EarliestOffset < 0 || rule.LatestOffset < rule.EarliestOffset {
+			return fmt.Errorf("onset rule latest offset must not precede its earliest offset")
+		}
+		if rule.LatestOffset >= template.CoveragePeriod {
+			return fmt.Errorf("onset rule must fall inside the coverage period")
+		}
+	}
+
+	if len(template.Phases) == 0 {
+		return nil
+	}
+	if template.PhaseCapPercent <= 0 || template.PhaseCapPercent > 100 {
+		return fmt.Errorf("phase payout cap must be between 0 and 100")
+	}
+
+	names := make(map[string]bool)
+	for _, phase := range template.Phases {
+		if phase.Name == "" {
+			return fmt.Errorf("growth phase name is required")
+		}
+		if names[phase.Name] {
+			return fmt.Errorf("growth phase %s is defined twice", phase.Name)
+		}
+		names[phase.Name] = true
+
+		if phase.StartOffset < 0 || phase.Duration <= 0 {
+			return fmt.Errorf("growth phase %s needs a non-negative start offset and a positive duration", phase.Name)
+		}
+		latestEnd := phase.StartOffset + phase.Duration
+		if phase.FromOnset {
+			if template.OnsetRule == nil {
+				return fmt.Errorf("growth phase %s starts from the onset of rains but no onset rule is set", phase.Name)
+			}
+			latestEnd += template.OnsetRule.LatestOffset
+		}
+		if latestEnd > template.CoveragePeriod {
+			return fmt.Errorf("growth phase %s can end after the coverage period", phase.Name)
+		}
+
+		if phase.Weight <= 0 || phase.Weight > 100 {
+			return fmt.Errorf("growth phase %s weight must be between 0 and 100", phase.Name)
+		}
+		if len(phase.Thresholds) == 0 {
+			return fmt.Errorf("growth phase %s needs at least one threshold", phase.Name)
+		}
+		for _, threshold := range phase.Thresholds {
+			if err := ValidateIndexThreshold(threshold); err != nil {
+				return fmt.Errorf("growth phase %s: %v", phase.Name, err)
+			}
+			// Phases are evaluated from raw readings, without a seasonal baseline
+			if threshold.Metric == "%" || threshold.Metric == "percent" {
+				return fmt.Errorf("growth phase %s thresholds must use absolute units", phase.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatePayoutCurve checks a curve's levels and payouts
+func ValidatePayoutCurve(curve *PayoutCurve) error {
+	switch curve.Type {
+	case "Linear":
+		if curve.Trigger == curve.Exit {
+			return fmt.Errorf("payout curve trigger and exit must differ")
+		}
+		if curve.Tick < 0 {
+			return fmt.Errorf("payout curve tick cannot be negative")
+		}
+		if curve.MaxPayoutPercent <= 0 || curve.MaxPayoutPercent > 100 {
+			return fmt.Errorf("payout curve maximum must be between 0 and 100")
+		}
+	case "Piecewise":
+		if len(curve.Points) < 2 {
+			return fmt.Errorf("piecewise payout curve needs at least two points")
+		}
+		for i, point := range curve.Points {
+			if i > 0 && point.Value <= curve.Points[i-1].Value {
+				return fmt.Errorf("piecewise payout curve points must increase in value")
+			}
+			if point.PayoutPercent < 0 || point.PayoutPercent > 100 {
+				return fmt.Errorf("piecewise payout curve payouts must be between 0 and 100")
+			}
+		}
+	default:
+		return fmt.Errorf("invalid payout curve type: %s", curve.Type)
+	}
+	return nil
+}
+
+// ValidateRegionalRates checks that every rate names one district or grid cell, that keys
+// are unique and that loadings cannot make a premium negative
+func ValidateRegionalRates(table *RegionalRateTable) error {
+	if len(table.Rates) == 0 {
+		return fmt.Errorf("regional rate table must have at least one rate")
+	}
+
+	seen := make(map[string]bool)
+	for i, rate := range table.Rates {
+		var key string
+		switch {
+		case rate.District != "" && rate.Cell != "":
+			return fmt.Errorf("rate %d: set either a district or a grid cell, not both", i)
+		case rate.District != "":
+			key = "district " + rate.District
+		case rate.Cell != "":
+			var latIndex, lonIndex int64
+			_, err := fmt.Sscanf(rate.Cell, "CELL_%d_%d", &latIndex, &lonIndex)
+			if err != nil || fmt.Sprintf("CELL_%d_%d", latIndex, lonIndex) != rate.Cell {
+				return fmt.Errorf("rate %d: invalid grid cell %s", i, rate.Cell)
+			}
+			key = "cell " + rate.Cell
+		default:
+			return fmt.Errorf("rate %d: a district or grid cell is required", i)
+		}
+		if seen[key] {
+			return fmt.Errorf("duplicate rate for %s", key)
+		}
+		seen[key] = true
+
+		if rate.RiskLoading <= -1 {
+			return fmt.Errorf("rate for %s: risk loading must be greater than -1", key)
+		}
+	}
+
+	return nil
+}
+
+// resolveRegionalRate finds the rate for a location: its grid cell first, then its
+// district, then the "*" catch-all
+func resolveRegionalRate(table *RegionalRateTable, location *PricingLocation) (*RegionalRate, error) {
+	cell := geogrid.CellID(location.Latitude, location.Longitude)
+
+	var byDistrict, fallback *RegionalRate
+	for i := range table.Rates {
+		rate := &table.Rates[i]
+		switch {
+		case rate.Cell == cell:
+			return rate, nil
+		case location.District != "" && rate.District == location.District:
+			byDistrict = rate
+		case rate.District == "*":
+			fallback = rate
+		}
+	}
+	if byDistrict != nil {
+		return byDistrict, nil
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+
+	return nil, fmt.Errorf("no regional rate for district %q", location.District)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"geogrid"
+)
+
+// Coverage areas are mapped onto the geogrid lat/lon grid shared with the weather
+// oracle and index calculator
+const (
+	earthRadiusKm      = 6371.0
+	maxCoverageCells   = 2500
+	maxCoverageRadius  = 100.0
+	maxPolygonVertices = 200
+)
+
+// GeoPoint is a WGS84 coordinate
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`  // Latitude coordinate
+	Longitude float64 `json:"longitude"` // Longitude coordinate
+}
+
+// CoverageArea describes the land a policy covers
+type CoverageArea struct {
+	Shape    string     `json:"shape"`    // Point (centre plus radius) or Polygon
+	Center   GeoPoint   `json:"center"`   // Centre for Point areas
+	RadiusKm float64    `json:"radiusKm"` // Radius in km for Point areas
+	Polygon  []GeoPoint `json:"polygon"`  // Vertices for Polygon areas, in order
+}
+
+// validateCoverageArea checks shape, coordinate ranges and size limits
+func validateCoverageArea(area *CoverageArea) error {
+	switch area.Shape {
+	case "Point":
+		if err := validateGeoPoint(area.Center); err != nil {
+			return err
+		}
+		if area.RadiusKm <= 0 || area.RadiusKm > maxCoverageRadius {
+			return fmt.Errorf("radius must be between 0 and %.0f km", maxCoverageRadius)
+		}
+	case "Polygon":
+		if len(area.Polygon) < 3 {
+			return fmt.Errorf("polygon needs at least 3 vertices")
+		}
+		if len(area.Polygon) > maxPolygonVertices {
+			return fmt.Errorf("polygon cannot have more than %d vertices", maxPolygonVertices)
+		}
+		for _, vertex := range area.Polygon {
+			if err := validateGeoPoint(vertex); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("invalid coverage shape: %s", area.Shape)
+	}
+	return nil
+}
+
+func validateGeoPoint(p GeoPoint) error {
+	if p.Latitude < -90 || p.Latitude > 90 {
+		return fmt.Errorf("invalid latitude: %.6f", p.Latitude)
+	}
+	if p.Longitude < -180 || p.Longitude > 180 {
+		return fmt.Errorf("invalid longitude: %.6f", p.Longitude)
+	}
+	return nil
+}
+
+// cellCenter returns the centre coordinate of a grid cell
+func cellCenter(latIndex int64, lonIndex int64) GeoPoint {
+	return GeoPoint{
+		Latitude:  float64(latIndex*geogrid.CellMicro+geogrid.CellMicro/2) / geogrid.MicroDegrees,
+		Longitude: float64(lonIndex*geogrid.CellMicro+geogrid.CellMicro/2) / geogrid.MicroDegrees,
+	}
+}
+
+// distanceKm is the haversine great-circle distance between two points
+func distanceKm(a GeoPoint, b GeoPoint) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// pointInPolygon uses ray casting on micro-degree coordinates
+func pointInPolygon(p GeoPoint, polygon []GeoPoint) bool {
+	x, y := geogrid.ToMicro(p.Longitude), geogrid.ToMicro(p.Latitude)
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		xi, yi := geogrid.ToMicro(polygon[i].Longitude), geogrid.ToMicro(polygon[i].Latitude)
+		xj, yj := geogrid.ToMicro(polygon[j].Longitude), geogrid.ToMicro(polygon[j].Latitude)
+		if (yi > y) != (yj > y) {
+			// Compare x against the edge crossing using integer cross-multiplication
+			lhs := (x - xi) * (yj - yi)
+			rhs := (xj - xi) * (y - yi)
+			if (yj-yi > 0 && lhs < rhs) || (yj-yi < 0 && lhs > rhs) {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// areaContains reports whether a coordinate lies inside a coverage area
+func areaContains(area *CoverageArea, p GeoPoint) bool {
+	switch area.Shape {
+	case "Point":
+		return distanceKm(area.Center, p) <= area.RadiusKm
+	case "Polygon":
+		return pointInPolygon(p, area.Polygon)
+	}
+	return false
+}
+
+// areaBounds returns the micro-degree bounding box of a coverage area
+func areaBounds(area *CoverageArea) (minLat int64, maxLat int64, minLon int64, maxLon int64) {
+	if area.Shape == "Point" {
+		// One degree of latitude is ~111.2 km; widen longitude by latitude
+		latSpan := area.RadiusKm / 111.2
+		lonSpan := latSpan / math.Max(0.01, math.Cos(area.Center.Latitude*math.Pi/180))
+		return geogrid.ToMicro(area.Center.Latitude - latSpan), geogrid.ToMicro(area.Center.Latitude + latSpan),
+			geogrid.ToMicro(area.Center.Longitude - lonSpan), geogrid.ToMicro(area.Center.Longitude + lonSpan)
+	}
+
+	minLat, maxLat = geogrid.ToMicro(area.Polygon[0].Latitude), geogrid.ToMicro(area.Polygon[0].Latitude)
+	minLon, maxLon = geogrid.ToMicro(area.Polygon[0].Longitude), geogrid.ToMicro(area.Polygon[0].Longitude)
+	for _, vertex := range area.Polygon[1:] {
+		lat, lon := geogrid.ToMicro(vertex.Latitude), geogrid.ToMicro(vertex.Longitude)
+		if lat < minLat {
+			minLat = lat
+		}
+		if lat > maxLat {
+			maxLat = lat
+		}
+		if lon < minLon {
+			minLon = lon
+		}
+		if lon > maxLon {
+			maxLon = lon
+		}
+	}
+	return minLat, maxLat, minLon, maxLon
+}
+
+// coverageCells maps a coverage area to the grid cells that govern it.
+// A cell is included when its centre lies in the area; the cells holding the
+// area's centre or vertices are always included so small areas are never empty.
+// Cells are returned in row-major order.
+func coverageCells(area *CoverageArea) ([]string, error) {
+	minLat, maxLat, minLon, maxLon := areaBounds(area)
+	latFrom, latTo := geogrid.FloorDiv(minLat, geogrid.CellMicro), geogrid.FloorDiv(maxLat, geogrid.CellMicro)
+	lonFrom, lonTo := geogrid.FloorDiv(minLon, geogrid.CellMicro), geogrid.FloorDiv(maxLon, geogrid.CellMicro)
+
+	if (latTo-latFrom+1)*(lonTo-lonFrom+1) > maxCoverageCells {
+		return nil, fmt.Errorf("coverage area spans more than %d grid cells", maxCoverageCells)
+	}
+
+	anchors := make(map[string]bool)
+	if area.Shape == "Point" {
+		anchors[geogrid.CellID(area.Center.Latitude, area.Center.Longitude)] = true
+	} else {
+		for _, vertex := range area.Polygon {
+			anchors[geogrid.CellID(vertex.Latitude, vertex.Longitude)] = true
+		}
+	}
+
+	var cells []string
+	for latIndex := latFrom; latIndex <= latTo; latIndex++ {
+		for lonIndex := lonFrom; lonIndex <= lonTo; lonIndex++ {
+			cell := geogrid.CellKey(latIndex, lonIndex)
+			if anchors[cell] || areaContains(area, cellCenter(latIndex, lonIndex)) {
+				cells = append(cells, cell)
+			}
+		}
+	}
+
+	return cells, nil
+}
@@ -3,11 +3,46 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	"geogrid"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// Chaincodes invoked from the policy chaincode
+const (
+	premiumPoolChaincode     = "premium-pool"
+	policyTemplateChaincode  = "policy-template"
+	farmerChaincode          = "farmer"
+	accessControlChaincode   = "access-control"
+	approvalManagerChaincode = "approval-manager"
+	claimProcessorChaincode  = "claim-processor"
+)
+
+// Composite key object types
+const (
+	policyVersionObjectType = "PolicyVersion"
+	policyCellObjectType    = "PolicyCell"
+	policyTermObjectType    = "PolicyTerm"
+	exposureObjectType      = "Exposure"
+	exposureDeltaObjectType = "ExposureDelta"
+)
+
+// Expiry index: plain keys EXPIRY_<end date>_<policy ID> for active policies, ordered by
+// end date so the expiry sweep can range-scan from a bookmark
+const (
+	expiryKeyPrefix = "EXPIRY_"
+	expiryKeyLayout = "20060102T150405Z"
+)
+
+// usedApprovalPrefix marks approval requests already spent on a policy change
+const usedApprovalPrefix = "APPROVAL_USED_"
+
 // PolicyChaincode manages insurance policy lifecycle and operations
 type PolicyChaincode struct {
 	contractapi.Contract
@@ -26,6 +61,7 @@ type Policy struct {
 	EndDate        time.Time `json:"endDate"`        // Policy end date
 	Status         string    `json:"status"`         // Active, Expired, Claimed, Cancelled
 	FarmLocation   string    `json:"farmLocation"`   // Farm region for weather tracking
+	District       string    `json:"district"`       // Farmer's administrative district
 	CropType       string    `json:"cropType"`       // Type of coffee covered
 	FarmSize       float64   `json:"farmSize"`       // Farm size in hectares
 	PolicyTerms    string    `json:"policyTerms"`    // Terms and conditions hash
@@ -34,6 +70,29 @@ type Policy struct {
 	LastUpdated    time.Time `json:"lastUpdated"`    // Last modification timestamp
 	ClaimCount     int       `json:"claimCount"`     // Number of claims made
 	TotalPayouts   float64   `json:"totalPayouts"`   // Total amount paid out
+	Version        int       `json:"version"`        // Terms version, bumped by each endorsement
+	VersionDate    time.Time `json:"versionDate"`    // Date the current terms took effect
+	TermPremium    float64   `json:"termPremium"`    // Full-term premium at the current terms
+	EndorsementID  string    `json:"endorsementID"`  // Endorsement that introduced the current terms
+	MasterPolicyID string    `json:"masterPolicyID"` // Group master policy for member certificates
+
+	CoverageArea *CoverageArea `json:"coverageArea,omitempty"` // Geographic area insured
+	GridCells    []string      `json:"gridCells,omitempty"`    // Grid cells governing index matching
+
+	WaitingPeriodEnd time.Time `json:"waitingPeriodEnd"` // Index events before this date do not count
+	CoolingOffEnd    time.Time `json:"coolingOffEnd"`    // Full-refund cancellation allowed until this date
+
+	Beneficiary *Beneficiary `json:"beneficiary,omitempty"` // Payee designated in place of the farmer
+
+	TermID        string    `json:"termID"`        // Current renewal term
+	TermNumber    int       `json:"termNumber"`    // 1 for the inception term, bumped by each renewal
+	TermStartDate time.Time `json:"termStartDate"` // Start of the current term
+
+	PendingRenewal *PendingRenewal `json:"pendingRenewal,omitempty"` // Renewal booked to start when the current term ends
+
+	RiskLoading      float64 `json:"riskLoading"`      // District or grid cell loading priced into the premium
+	RateTableVersion int     `json:"rateTableVersion"` // Template regional rate table version, 0 if not regionally rated
+	BaselineID       string  `json:"baselineID"`       // Regional baseline referenced by the rate
 }
 
 // PolicyHistory tracks policy lifecycle events
@@ -93,6 +152,27 @@ func (pc *PolicyChaincode) CreatePolicy(ctx contractapi.TransactionContextInterf
 	startDate := timestamp
 	endDate := startDate.AddDate(0, 0, coverageDays)
 
+	// Waiting period and cooling-off window come from the template
+	windows, err := getTemplateWindows(ctx, templateID)
+	if err != nil {
+		return err
+	}
+
+	district, err := farmerDistrict(ctx, farmerID)
+	if err != nil {
+		return err
+	}
+
+	// Regionally rated templates price the farmer's district; the premium cannot undercut it
+	quote, err := quotePremium(ctx, templateID, farmerID, coverageAmount, farmSize, 0)
+	if err != nil {
+		return err
+	}
+	if quote.RateTableVersion > 0 && premiumAmount < quote.Premium {
+		return fmt.Errorf("premium %.2f is below the %.2f quoted for district %s",
+			premiumAmount, quote.Premium, quote.District)
+	}
+
 	// Create policy
 	policy := Policy{
 		PolicyID:       policyID,
@@ -106,6 +186,7 @@ func (pc *PolicyChaincode) CreatePolicy(ctx contractapi.TransactionContextInterf
 		EndDate:        endDate,
 		Status:         "Active",
 		FarmLocation:   farmLocation,
+		District:       district,
 		CropType:       cropType,
 		FarmSize:       farmSize,
 		PolicyTerms:    policyTermsHash,
@@ -114,6 +195,20 @@ func (pc *PolicyChaincode) CreatePolicy(ctx contractapi.TransactionContextInterf
 		LastUpdated:    timestamp,
 		ClaimCount:     0,
 		TotalPayouts:   0,
+		Version:        1,
+		VersionDate:    startDate,
+		TermPremium:    premiumAmount,
+
+		WaitingPeriodEnd: startDate.AddDate(0, 0, windows.WaitingDays),
+		CoolingOffEnd:    startDate.AddDate(0, 0, windows.CoolingOffDays),
+
+		TermID:        initialTermID(policyID),
+		TermNumber:    1,
+		TermStartDate: startDate,
+
+		RiskLoading:      quote.riskLoading(),
+		RateTableVersion: quote.RateTableVersion,
+		BaselineID:       quote.baselineID(),
 	}
 
 	policyJSON, err := json.Marshal(policy)
@@ -127,6 +222,19 @@ func (pc *PolicyChaincode) CreatePolicy(ctx contractapi.TransactionContextInterf
 		return fmt.Errorf("failed to put policy: %v", err)
 	}
 
+	err = indexPolicyExpiry(ctx, &policy)
+	if err != nil {
+		return err
+	}
+
+	exposure := exposureDeltas{}
+	exposure.addPolicy(&policy, 1)
+	exposure.bindPolicy(&policy, 1)
+	err = pc.applyExposure(ctx, exposure, timestamp)
+	if err != nil {
+		return err
+	}
+
 	// Record policy creation in history
 	err = pc.recordHistory(ctx, policyID, "Created", callerID,
 		fmt.Sprintf("Policy created with coverage %.2f, premium %.2f", coverageAmount, premiumAmount))
@@ -134,7 +242,7 @@ func (pc *PolicyChaincode) CreatePolicy(ctx contractapi.TransactionContextInterf
 		return err
 	}
 
-	return nil
+	return pc.recordWindows(ctx, &policy, callerID)
 }
 
 // GetPolicy retrieves policy details by policy ID
@@ -194,6 +302,22 @@ func (pc *PolicyChaincode) UpdatePolicyStatus(ctx contractapi.TransactionContext
 		return fmt.Errorf("failed to update policy status: %v", err)
 	}
 
+	// Only active policies count towards exposure
+	exposure := exposureDeltas{}
+	if oldStatus == "Active" && newStatus != "Active" {
+		exposure.addPolicy(policy, -1)
+	} else if oldStatus != "Active" && newStatus == "Active" {
+		exposure.addPolicy(policy, 1)
+		err = indexPolicyExpiry(ctx, policy)
+		if err != nil {
+			return err
+		}
+	}
+	err = pc.applyExposure(ctx, exposure, policy.LastUpdated)
+	if err != nil {
+		return err
+	}
+
 	callerID, _ := ctx.GetClientIdentity().GetID()
 	err = pc.recordHistory(ctx, policyID, "StatusChanged", callerID,
 		fmt.Sprintf("Status changed from %s to %s", oldStatus, newStatus))
@@ -201,53 +325,261 @@ func (pc *PolicyChaincode) UpdatePolicyStatus(ctx contractapi.TransactionContext
 	return err
 }
 
-// RenewPolicy extends policy coverage period
+// RenewPolicy re-underwrites a policy for a new term under the latest Active version of
+// its template lineage. The premium is recomputed with claim-free years taken from the
+// policy's claims, and the new template's thresholds apply from the renewal. Renewing an
+// active policy before its end date books the term as pending; ExpirePolicies starts it
+// when the current term ends.
 func (pc *PolicyChaincode) RenewPolicy(ctx contractapi.TransactionContextInterface,
-	policyID string, additionalDays int, additionalPremium float64) error {
+	policyID string, termID string) (*PolicyTerm, error) {
+
+	if termID == "" {
+		return nil, fmt.Errorf("term ID is required")
+	}
 
 	policy, err := pc.GetPolicy(ctx, policyID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if policy.Status != "Active" && policy.Status != "Expired" {
-		return fmt.Errorf("cannot renew policy with status: %s", policy.Status)
+		return nil, fmt.Errorf("cannot renew policy with status: %s", policy.Status)
+	}
+	if policy.MasterPolicyID != "" {
+		return nil, fmt.Errorf("policy %s is a member certificate of master policy %s", policyID, policy.MasterPolicyID)
+	}
+	if policy.PendingRenewal != nil {
+		return nil, fmt.Errorf("policy %s already has renewal term %s pending", policyID, policy.PendingRenewal.Term.TermID)
+	}
+
+	terms, err := pc.GetPolicyTerms(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+	for _, term := range terms {
+		if term.TermID == termID {
+			return nil, fmt.Errorf("term %s already exists on policy %s", termID, policyID)
+		}
+	}
+
+	templateJSON, err := invokeChaincode(ctx, policyTemplateChaincode, "GetLatestActiveVersion", policy.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	var template struct {
+		TemplateID      string          `json:"templateID"`
+		Version         int             `json:"version"`
+		CoveragePeriod  int             `json:"coveragePeriod"`
+		MaxCoverage     float64         `json:"maxCoverage"`
+		IndexThresholds []TermThreshold `json:"indexThresholds"`
+	}
+	err = json.Unmarshal(templateJSON, &template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal template: %v", err)
 	}
 
-	if additionalDays <= 0 {
-		return fmt.Errorf("additional days must be positive")
+	if template.CoveragePeriod <= 0 {
+		return nil, fmt.Errorf("template %s has no coverage period", template.TemplateID)
+	}
+	if template.MaxCoverage > 0 && policy.CoverageAmount > template.MaxCoverage {
+		return nil, fmt.Errorf("coverage %.2f exceeds template %s maximum of %.2f; endorse before renewing",
+			policy.CoverageAmount, template.TemplateID, template.MaxCoverage)
 	}
-	if additionalPremium <= 0 {
-		return fmt.Errorf("additional premium must be positive")
+
+	claims, err := policyClaims(ctx, policyID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get deterministic transaction timestamp
 	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
-		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	// Count consecutive claim-free terms, most recent first
+	claimFreeYears := 0
+	for i := len(terms) - 1; i >= 0; i-- {
+		claimed := false
+		for _, claim := range claims {
+			if claimInTerm(claim, terms[i]) {
+				claimed = true
+				break
+			}
+		}
+		if claimed {
+			break
+		}
+		claimFreeYears++
+	}
+
+	breakdown, err := quotePremium(ctx, template.TemplateID, policy.FarmerID,
+		policy.CoverageAmount, policy.FarmSize, claimFreeYears)
+	if err != nil {
+		return nil, err
+	}
+	premium := breakdown.Premium
+
+	// Advance renewals start when the current term ends; lapsed policies restart now
+	startDate := policy.EndDate
+	if timestamp.After(startDate) {
+		startDate = timestamp
+	}
+
+	renewed := &PolicyTerm{
+		PolicyID:        policyID,
+		TermID:          termID,
+		TermNumber:      terms[len(terms)-1].TermNumber + 1,
+		TemplateID:      template.TemplateID,
+		TemplateVersion: template.Version,
+		StartDate:       startDate,
+		EndDate:         startDate.AddDate(0, 0, template.CoveragePeriod),
+		CoverageAmount:  policy.CoverageAmount,
+		Premium:         premium,
+		ClaimFreeYears:  claimFreeYears,
+		IndexThresholds: template.IndexThresholds,
+	}
+
+	renewal := &PendingRenewal{
+		Term:             *renewed,
+		RiskLoading:      breakdown.riskLoading(),
+		RateTableVersion: breakdown.RateTableVersion,
+		BaselineID:       breakdown.baselineID(),
+	}
+
+	callerID, _ := ctx.GetClientIdentity().GetID()
+
+	// Renewing ahead of the end date books the term; the expiry sweep starts it once
+	// the current term ends, so cover and claims stay on the current term until then
+	if policy.Status == "Active" && timestamp.Before(policy.EndDate) {
+		policy.PendingRenewal = renewal
+		policy.LastUpdated = timestamp
+
+		policyJSON, err := json.Marshal(policy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal policy: %v", err)
+		}
+
+		err = ctx.GetStub().PutState(policyID, policyJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to renew policy: %v", err)
+		}
+
+		err = pc.recordHistory(ctx, policyID, "RenewalScheduled", callerID,
+			fmt.Sprintf("Term %s under template %s v%d from %s until %s with premium %.2f (%d claim-free terms)",
+				termID, template.TemplateID, template.Version, renewed.StartDate.Format(time.RFC3339),
+				renewed.EndDate.Format(time.RFC3339), premium, claimFreeYears))
+		if err != nil {
+			return nil, err
+		}
+
+		return renewed, nil
+	}
+
+	exposure := exposureDeltas{}
+	err = pc.startRenewalTerm(ctx, policy, renewal, claims, exposure, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	err = pc.applyExposure(ctx, exposure, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expiry took the policy out of the pool's active count; the renewal premium's
+	// DepositPremium counts it back in, so it is not adjusted here
+	err = pc.recordHistory(ctx, policyID, "Renewed", callerID,
+		fmt.Sprintf("Term %s under template %s v%d until %s with premium %.2f (%d claim-free terms)",
+			termID, template.TemplateID, template.Version, renewed.EndDate.Format(time.RFC3339),
+			premium, claimFreeYears))
+	if err != nil {
+		return nil, err
+	}
+
+	return renewed, nil
+}
+
+// startRenewalTerm closes a policy's current term with the claims made during it and
+// moves the policy onto the renewal term. Exposure changes are added to exposure.
+func (pc *PolicyChaincode) startRenewalTerm(ctx contractapi.TransactionContextInterface,
+	policy *Policy, renewal *PendingRenewal, claims []*termClaim, exposure exposureDeltas, timestamp time.Time) error {
+
+	terms, err := pc.GetPolicyTerms(ctx, policy.PolicyID)
+	if err != nil {
+		return err
+	}
+
+	closing := terms[len(terms)-1]
+	for _, claim := range claims {
+		if claimInTerm(claim, closing) {
+			closing.ClaimCount++
+			closing.TotalPayouts += claim.PayoutAmount
+		}
+	}
+	err = pc.putPolicyTerm(ctx, closing)
+	if err != nil {
+		return err
+	}
+
+	term := renewal.Term
+
+	// The renewal supersedes the current version of the coverage terms
+	err = pc.putPolicyVersion(ctx, &PolicyVersion{
+		PolicyID:      policy.PolicyID,
+		Version:       currentVersion(policy),
+		EffectiveFrom: versionDate(policy),
+		EffectiveTo:   term.StartDate,
+		EndorsementID: policy.EndorsementID,
+		Terms:         currentTerms(policy),
+	})
+	if err != nil {
+		return err
 	}
 
-	// Extend end date
-	policy.EndDate = policy.EndDate.AddDate(0, 0, additionalDays)
-	policy.PremiumAmount += additionalPremium
+	// The renewed term may be underwritten by a newer template version
+	if policy.Status == "Active" {
+		exposure.addPolicy(policy, -1)
+	}
+	previousTemplateID := policy.TemplateID
+
+	policy.TemplateID = term.TemplateID
+	policy.EndDate = term.EndDate
+	policy.PremiumAmount += term.Premium
+	policy.TermPremium = term.Premium
+	policy.RiskLoading = renewal.RiskLoading
+	policy.RateTableVersion = renewal.RateTableVersion
+	policy.BaselineID = renewal.BaselineID
+	policy.Version = currentVersion(policy) + 1
+	policy.VersionDate = term.StartDate
+	policy.EndorsementID = ""
+	policy.TermID = term.TermID
+	policy.TermNumber = term.TermNumber
+	policy.TermStartDate = term.StartDate
+	policy.PendingRenewal = nil
 	policy.Status = "Active"
-	policy.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+	policy.LastUpdated = timestamp
 
 	policyJSON, err := json.Marshal(policy)
 	if err != nil {
 		return fmt.Errorf("failed to marshal policy: %v", err)
 	}
 
-	err = ctx.GetStub().PutState(policyID, policyJSON)
+	err = ctx.GetStub().PutState(policy.PolicyID, policyJSON)
 	if err != nil {
 		return fmt.Errorf("failed to renew policy: %v", err)
 	}
 
-	callerID, _ := ctx.GetClientIdentity().GetID()
-	err = pc.recordHistory(ctx, policyID, "Renewed", callerID,
-		fmt.Sprintf("Policy renewed for %d days with premium %.2f", additionalDays, additionalPremium))
+	err = indexPolicyExpiry(ctx, policy)
+	if err != nil {
+		return err
+	}
 
-	return err
+	exposure.addPolicy(policy, 1)
+	if policy.TemplateID != previousTemplateID {
+		exposure.bindTemplate(policy.TemplateID)
+	}
+	return nil
 }
 
 // CancelPolicy terminates an active policy
@@ -262,15 +594,58 @@ func (pc *PolicyChaincode) CancelPolicy(ctx contractapi.TransactionContextInterf
 	if policy.Status != "Active" {
 		return fmt.Errorf("can only cancel active policies")
 	}
+	if policy.MasterPolicyID != "" {
+		return fmt.Errorf("policy %s is a member certificate; remove it from master policy %s instead",
+			policyID, policy.MasterPolicyID)
+	}
 
 	// Get deterministic transaction timestamp
 	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
 		return fmt.Errorf("failed to get transaction timestamp: %v", err)
 	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	// Cancelling inside the cooling-off window refunds the premium actually deposited;
+	// the refund takes the policy out of the pool's active count, and a policy with no
+	// deposit was never counted
+	coolingOff := inCoolingOff(policy, timestamp)
+	if coolingOff {
+		paidJSON, err := invokeChaincode(ctx, premiumPoolChaincode, "GetPolicyPremiumPaid", policyID)
+		if err != nil {
+			return err
+		}
+		var paid float64
+		if err := json.Unmarshal(paidJSON, &paid); err != nil {
+			return fmt.Errorf("failed to parse deposited premium: %v", err)
+		}
+
+		refund := policy.PremiumAmount
+		if paid < refund {
+			refund = paid
+		}
+		if refund > 0 {
+			_, err = invokeChaincode(ctx, premiumPoolChaincode, "RefundPremium",
+				"REFUND_"+policyID, policy.FarmerID, policyID, strconv.FormatFloat(refund, 'f', -1, 64))
+			if err != nil {
+				return err
+			}
+			reason = fmt.Sprintf("%s (cooling-off refund %.2f)", reason, refund)
+		} else {
+			reason = fmt.Sprintf("%s (cooling-off, no premium deposited to refund)", reason)
+		}
+	}
+
+	// Outside the window nothing is refunded, so the active count is adjusted directly
+	if !coolingOff {
+		_, err = invokeChaincode(ctx, premiumPoolChaincode, "AdjustActivePolicies", "-1")
+		if err != nil {
+			return err
+		}
+	}
 
 	policy.Status = "Cancelled"
-	policy.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+	policy.LastUpdated = timestamp
 
 	policyJSON, err := json.Marshal(policy)
 	if err != nil {
@@ -282,6 +657,13 @@ func (pc *PolicyChaincode) CancelPolicy(ctx contractapi.TransactionContextInterf
 		return fmt.Errorf("failed to cancel policy: %v", err)
 	}
 
+	exposure := exposureDeltas{}
+	exposure.addPolicy(policy, -1)
+	err = pc.applyExposure(ctx, exposure, timestamp)
+	if err != nil {
+		return err
+	}
+
 	callerID, _ := ctx.GetClientIdentity().GetID()
 	err = pc.recordHistory(ctx, policyID, "Cancelled", callerID, reason)
 
@@ -289,300 +671,2865 @@ func (pc *PolicyChaincode) CancelPolicy(ctx contractapi.TransactionContextInterf
 }
 
 // ========================================
-// POLICY CLAIMS & PAYOUTS
+// POLICY ENDORSEMENTS & VERSIONS
 // ========================================
 
-// RecordClaim updates policy with claim information
-func (pc *PolicyChaincode) RecordClaim(ctx contractapi.TransactionContextInterface,
-	policyID string, payoutAmount float64) error {
+// CoverageTerms captures the insured terms that an endorsement can change
+type CoverageTerms struct {
+	CoverageAmount float64 `json:"coverageAmount"` // Total coverage value
+	FarmSize       float64 `json:"farmSize"`       // Farm size in hectares
+	CropType       string  `json:"cropType"`       // Type of coffee covered
+	FarmLocation   string  `json:"farmLocation"`   // Farm region for weather tracking
+	TermPremium    float64 `json:"termPremium"`    // Full-term premium at these terms
+}
+
+// PolicyVersion is a superseded or current set of terms with its validity window
+type PolicyVersion struct {
+	PolicyID      string        `json:"policyID"`      // Associated policy
+	Version       int           `json:"version"`       // Terms version number
+	EffectiveFrom time.Time     `json:"effectiveFrom"` // First instant the terms apply
+	EffectiveTo   time.Time     `json:"effectiveTo"`   // Instant the terms were superseded (zero if current)
+	EndorsementID string        `json:"endorsementID"` // Endorsement that introduced the terms
+	Terms         CoverageTerms `json:"terms"`         // Insured terms
+}
+
+// Endorsement records a mid-term change to a policy's terms
+type Endorsement struct {
+	EndorsementID     string        `json:"endorsementID"`     // Unique endorsement identifier
+	PolicyID          string        `json:"policyID"`          // Associated policy
+	FromVersion       int           `json:"fromVersion"`       // Version superseded
+	ToVersion         int           `json:"toVersion"`         // Version introduced
+	EffectiveDate     time.Time     `json:"effectiveDate"`     // When the new terms apply
+	PreviousTerms     CoverageTerms `json:"previousTerms"`     // Terms before the change
+	NewTerms          CoverageTerms `json:"newTerms"`          // Terms after the change
+	RemainingDays     int           `json:"remainingDays"`     // Days left in the term at the effective date
+	TermDays          int           `json:"termDays"`          // Total days in the policy term
+	PremiumAdjustment float64       `json:"premiumAdjustment"` // Pro-rata additional (+) or return (-) premium
+	Reason            string        `json:"reason"`            // Why the endorsement was made
+	CreatedBy         string        `json:"createdBy"`         // Entity that endorsed the policy
+	CreatedDate       time.Time     `json:"createdDate"`       // Endorsement timestamp
+}
+
+// EndorsePolicy changes coverage, farm size, crop type or location from an effective date.
+// Zero or empty arguments leave the corresponding term unchanged. newTermPremium is the
+// full-term premium at the new terms; when zero the current premium is scaled by coverage.
+func (pc *PolicyChaincode) EndorsePolicy(ctx contractapi.TransactionContextInterface,
+	endorsementID string, policyID string, effectiveDateStr string,
+	newCoverageAmount float64, newFarmSize float64, newCropType string, newFarmLocation string,
+	newTermPremium float64, reason string) (*Endorsement, error) {
+
+	existing, err := ctx.GetStub().GetState("ENDORSEMENT_" + endorsementID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("endorsement %s already exists", endorsementID)
+	}
 
 	policy, err := pc.GetPolicy(ctx, policyID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if policy.Status != "Active" {
-		return fmt.Errorf("cannot claim on non-active policy")
+		return nil, fmt.Errorf("can only endorse active policies")
+	}
+
+	effectiveDate, err := time.Parse(time.RFC3339, effectiveDateStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid effective date: %v", err)
 	}
 
 	// Get deterministic transaction timestamp
 	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
-		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
 	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
-	policy.ClaimCount++
-	policy.TotalPayouts += payoutAmount
-	policy.Status = "Claimed"
-	policy.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+	// Backdating would let terms be changed after a loss is already known
+	if effectiveDate.Before(timestamp) {
+		return nil, fmt.Errorf("effective date cannot precede the endorsement time %s", timestamp.Format(time.RFC3339))
+	}
+	currentFrom := versionDate(policy)
+	if effectiveDate.Before(currentFrom) {
+		return nil, fmt.Errorf("effective date cannot precede current terms dated %s", currentFrom.Format(time.RFC3339))
+	}
+	if !effectiveDate.Before(policy.EndDate) {
+		return nil, fmt.Errorf("effective date must fall before policy end date")
+	}
+
+	if newCoverageAmount < 0 || newFarmSize < 0 || newTermPremium < 0 {
+		return nil, fmt.Errorf("endorsed amounts cannot be negative")
+	}
+
+	previous := currentTerms(policy)
+	terms := previous
+	if newCoverageAmount > 0 {
+		terms.CoverageAmount = newCoverageAmount
+	}
+	if newFarmSize > 0 {
+		terms.FarmSize = newFarmSize
+	}
+	if newCropType != "" {
+		terms.CropType = newCropType
+	}
+	if newFarmLocation != "" {
+		terms.FarmLocation = newFarmLocation
+	}
+	if newTermPremium > 0 {
+		terms.TermPremium = newTermPremium
+	} else if previous.CoverageAmount > 0 {
+		terms.TermPremium = previous.TermPremium * terms.CoverageAmount / previous.CoverageAmount
+	}
+
+	if terms == previous {
+		return nil, fmt.Errorf("endorsement does not change any terms")
+	}
+
+	// Pro-rata the full-term premium difference over the days still to run
+	termDays := int(policy.EndDate.Sub(termStart(policy)).Hours() / 24)
+	remainingDays := int(policy.EndDate.Sub(effectiveDate).Hours() / 24)
+	adjustment := 0.0
+	if termDays > 0 {
+		adjustment = (terms.TermPremium - previous.TermPremium) * float64(remainingDays) / float64(termDays)
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	// Preserve the superseded terms so claims can be judged against them
+	fromVersion := currentVersion(policy)
+	superseded := PolicyVersion{
+		PolicyID:      policyID,
+		Version:       fromVersion,
+		EffectiveFrom: currentFrom,
+		EffectiveTo:   effectiveDate,
+		EndorsementID: policy.EndorsementID,
+		Terms:         previous,
+	}
+	err = pc.putPolicyVersion(ctx, &superseded)
+	if err != nil {
+		return nil, err
+	}
+
+	endorsement := Endorsement{
+		EndorsementID:     endorsementID,
+		PolicyID:          policyID,
+		FromVersion:       fromVersion,
+		ToVersion:         fromVersion + 1,
+		EffectiveDate:     effectiveDate,
+		PreviousTerms:     previous,
+		NewTerms:          terms,
+		RemainingDays:     remainingDays,
+		TermDays:          termDays,
+		PremiumAdjustment: adjustment,
+		Reason:            reason,
+		CreatedBy:         callerID,
+		CreatedDate:       timestamp,
+	}
+
+	endorsementJSON, err := json.Marshal(endorsement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal endorsement: %v", err)
+	}
+
+	err = ctx.GetStub().PutState("ENDORSEMENT_"+endorsementID, endorsementJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put endorsement: %v", err)
+	}
+
+	exposure := exposureDeltas{}
+	exposure.addPolicy(policy, -1)
+
+	policy.CoverageAmount = terms.CoverageAmount
+	policy.FarmSize = terms.FarmSize
+	policy.CropType = terms.CropType
+	policy.FarmLocation = terms.FarmLocation
+	policy.TermPremium = terms.TermPremium
+	policy.PremiumAmount += adjustment
+	policy.Version = endorsement.ToVersion
+	policy.VersionDate = effectiveDate
+	policy.EndorsementID = endorsementID
+	policy.LastUpdated = timestamp
 
 	policyJSON, err := json.Marshal(policy)
 	if err != nil {
-		return fmt.Errorf("failed to marshal policy: %v", err)
+		return nil, fmt.Errorf("failed to marshal policy: %v", err)
 	}
 
 	err = ctx.GetStub().PutState(policyID, policyJSON)
 	if err != nil {
-		return fmt.Errorf("failed to record claim: %v", err)
+		return nil, fmt.Errorf("failed to endorse policy: %v", err)
 	}
 
-	callerID, _ := ctx.GetClientIdentity().GetID()
-	err = pc.recordHistory(ctx, policyID, "Claimed", callerID,
-		fmt.Sprintf("Claim processed with payout %.2f", payoutAmount))
+	exposure.addPolicy(policy, 1)
+	err = pc.applyExposure(ctx, exposure, timestamp)
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	err = pc.recordHistory(ctx, policyID, "Endorsed", callerID,
+		fmt.Sprintf("Endorsement %s moved terms to version %d effective %s with premium adjustment %.2f",
+			endorsementID, endorsement.ToVersion, effectiveDate.Format(time.RFC3339), adjustment))
+	if err != nil {
+		return nil, err
+	}
+
+	return &endorsement, nil
 }
 
-// ClaimHistorySummary represents claim history data
-type ClaimHistorySummary struct {
-	ClaimCount   int     `json:"claimCount"`
-	TotalPayouts float64 `json:"totalPayouts"`
+// GetEndorsement retrieves an endorsement by ID
+func (pc *PolicyChaincode) GetEndorsement(ctx contractapi.TransactionContextInterface,
+	endorsementID string) (*Endorsement, error) {
+
+	endorsementJSON, err := ctx.GetStub().GetState("ENDORSEMENT_" + endorsementID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read endorsement: %v", err)
+	}
+	if endorsementJSON == nil {
+		return nil, fmt.Errorf("endorsement %s does not exist", endorsementID)
+	}
+
+	var endorsement Endorsement
+	err = json.Unmarshal(endorsementJSON, &endorsement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal endorsement: %v", err)
+	}
+
+	return &endorsement, nil
 }
 
-// GetPolicyClaimHistory retrieves claim count and total payouts
-func (pc *PolicyChaincode) GetPolicyClaimHistory(ctx contractapi.TransactionContextInterface,
-	policyID string) (*ClaimHistorySummary, error) {
+// GetPolicyVersions lists every version of a policy's terms, oldest first
+func (pc *PolicyChaincode) GetPolicyVersions(ctx contractapi.TransactionContextInterface,
+	policyID string) ([]*PolicyVersion, error) {
 
 	policy, err := pc.GetPolicy(ctx, policyID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ClaimHistorySummary{
-		ClaimCount:   policy.ClaimCount,
-		TotalPayouts: policy.TotalPayouts,
-	}, nil
-}
-
-// ========================================
-// POLICY QUERIES
-// ========================================
-
-// GetPoliciesByFarmer retrieves all policies for a specific farmer
-func (pc *PolicyChaincode) GetPoliciesByFarmer(ctx contractapi.TransactionContextInterface,
-	farmerID string) ([]*Policy, error) {
-
-	queryString := fmt.Sprintf(`{"selector":{"farmerID":"%s"}}`, farmerID)
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(policyVersionObjectType, []string{policyID})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query policies by farmer: %v", err)
+		return nil, fmt.Errorf("failed to query policy versions: %v", err)
 	}
 	defer resultsIterator.Close()
 
-	var policies []*Policy
+	var versions []*PolicyVersion
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
 			return nil, err
 		}
 
-		var policy Policy
-		err = json.Unmarshal(queryResponse.Value, &policy)
+		var version PolicyVersion
+		err = json.Unmarshal(queryResponse.Value, &version)
 		if err != nil {
 			return nil, err
 		}
-		policies = append(policies, &policy)
+		versions = append(versions, &version)
 	}
 
-	return policies, nil
+	versions = append(versions, &PolicyVersion{
+		PolicyID:      policyID,
+		Version:       currentVersion(policy),
+		EffectiveFrom: versionDate(policy),
+		EndorsementID: policy.EndorsementID,
+		Terms:         currentTerms(policy),
+	})
+
+	return versions, nil
 }
 
-// GetPoliciesByRegion retrieves all policies in a geographic area
-func (pc *PolicyChaincode) GetPoliciesByRegion(ctx contractapi.TransactionContextInterface,
-	region string) ([]*Policy, error) {
+// GetPolicyVersion retrieves a specific version of a policy's terms
+func (pc *PolicyChaincode) GetPolicyVersion(ctx contractapi.TransactionContextInterface,
+	policyID string, version int) (*PolicyVersion, error) {
 
-	queryString := fmt.Sprintf(`{"selector":{"farmLocation":"%s"}}`, region)
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	versions, err := pc.GetPolicyVersions(ctx, policyID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query policies by region: %v", err)
+		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	var policies []*Policy
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
 
-		var policy Policy
-		err = json.Unmarshal(queryResponse.Value, &policy)
-		if err != nil {
-			return nil, err
+	for _, v := range versions {
+		if v.Version == version {
+			return v, nil
 		}
-		policies = append(policies, &policy)
 	}
 
-	return policies, nil
+	return nil, fmt.Errorf("policy %s has no version %d", policyID, version)
 }
 
-// GetActivePolicies retrieves all currently active policies
-func (pc *PolicyChaincode) GetActivePolicies(ctx contractapi.TransactionContextInterface) ([]*Policy, error) {
-	queryString := `{"selector":{"status":"Active"}}`
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+// GetPolicyTermsAt returns the version of a policy's terms in force on a date
+func (pc *PolicyChaincode) GetPolicyTermsAt(ctx contractapi.TransactionContextInterface,
+	policyID string, dateStr string) (*PolicyVersion, error) {
+
+	date, err := time.Parse(time.RFC3339, dateStr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query active policies: %v", err)
+		return nil, fmt.Errorf("invalid date: %v", err)
 	}
-	defer resultsIterator.Close()
 
-	var policies []*Policy
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
+	versions, err := pc.GetPolicyVersions(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
 
-		var policy Policy
-		err = json.Unmarshal(queryResponse.Value, &policy)
-		if err != nil {
-			return nil, err
+	for _, v := range versions {
+		if date.Before(v.EffectiveFrom) {
+			continue
+		}
+		if v.EffectiveTo.IsZero() || date.Before(v.EffectiveTo) {
+			return v, nil
 		}
-		policies = append(policies, &policy)
 	}
 
-	return policies, nil
+	return nil, fmt.Errorf("policy %s had no terms in force on %s", policyID, dateStr)
 }
 
-// GetPoliciesByInsurer retrieves all policies from a specific insurer
-func (pc *PolicyChaincode) GetPoliciesByInsurer(ctx contractapi.TransactionContextInterface,
-	insurerID string) ([]*Policy, error) {
+// ========================================
+// POLICY RENEWAL TERMS
+// ========================================
 
-	queryString := fmt.Sprintf(`{"selector":{"insurerID":"%s"}}`, insurerID)
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+// TermThreshold is a template index threshold in force for a renewal term
+type TermThreshold struct {
+	IndexType       string  `json:"indexType"`       // Rainfall, Temperature, Drought, etc.
+	Metric          string  `json:"metric"`          // Measurement unit
+	ThresholdValue  float64 `json:"thresholdValue"`  // Trigger value
+	Operator        string  `json:"operator"`        // <, >, <=, >=, ==
+	MeasurementDays int     `json:"measurementDays"` // Days to measure over
+	PayoutPercent   float64 `json:"payoutPercent"`   // Percentage of coverage to pay
+	Severity        string  `json:"severity"`        // Mild, Moderate, Severe
+}
+
+// PolicyTerm is one season of cover, from inception or a renewal
+type PolicyTerm struct {
+	PolicyID        string          `json:"policyID"`        // Associated policy
+	TermID          string          `json:"termID"`          // Unique term identifier
+	TermNumber      int             `json:"termNumber"`      // 1 for the inception term
+	TemplateID      string          `json:"templateID"`      // Template version underwriting the term
+	TemplateVersion int             `json:"templateVersion"` // Version number of that template
+	StartDate       time.Time       `json:"startDate"`       // Term start
+	EndDate         time.Time       `json:"endDate"`         // Term end
+	CoverageAmount  float64         `json:"coverageAmount"`  // Coverage at the start of the term
+	Premium         float64         `json:"premium"`         // Premium charged for the term
+	ClaimFreeYears  int             `json:"claimFreeYears"`  // Claim-free terms used in pricing
+	IndexThresholds []TermThreshold `json:"indexThresholds"` // Thresholds in force, when known
+	ClaimCount      int             `json:"claimCount"`      // Claims made in the term, set when it closes
+	TotalPayouts    float64         `json:"totalPayouts"`    // Payouts claimed in the term, set when it closes
+}
+
+// PendingRenewal is a renewal term booked before the current term ends, with the rating it was priced at
+type PendingRenewal struct {
+	Term             PolicyTerm `json:"term"`             // Term to start when the current term ends
+	RiskLoading      float64    `json:"riskLoading"`      // Regional loading priced into the renewal premium
+	RateTableVersion int        `json:"rateTableVersion"` // Template regional rate table version, 0 if not regionally rated
+	BaselineID       string     `json:"baselineID"`       // Regional baseline referenced by the rate
+}
+
+// termClaim holds the claim fields used to attribute claims to terms
+type termClaim struct {
+	TermID       string    `json:"termID"`
+	TriggerDate  time.Time `json:"triggerDate"`
+	PayoutAmount float64   `json:"payoutAmount"`
+	Status       string    `json:"status"`
+}
+
+// GetPolicyTerms lists a policy's renewal terms, oldest first, ending with the current term
+func (pc *PolicyChaincode) GetPolicyTerms(ctx contractapi.TransactionContextInterface,
+	policyID string) ([]*PolicyTerm, error) {
+
+	policy, err := pc.GetPolicy(ctx, policyID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query policies by insurer: %v", err)
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(policyTermObjectType, []string{policyID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policy terms: %v", err)
 	}
 	defer resultsIterator.Close()
 
-	var policies []*Policy
+	var terms []*PolicyTerm
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
 			return nil, err
 		}
 
-		var policy Policy
-		err = json.Unmarshal(queryResponse.Value, &policy)
+		var term PolicyTerm
+		err = json.Unmarshal(queryResponse.Value, &term)
 		if err != nil {
 			return nil, err
 		}
-		policies = append(policies, &policy)
+		terms = append(terms, &term)
 	}
 
-	return policies, nil
+	terms = append(terms, &PolicyTerm{
+		PolicyID:       policyID,
+		TermID:         currentTermID(policy),
+		TermNumber:     currentTermNumber(policy),
+		TemplateID:     policy.TemplateID,
+		StartDate:      termStart(policy),
+		EndDate:        policy.EndDate,
+		CoverageAmount: policy.CoverageAmount,
+		Premium:        termPremium(policy),
+	})
+
+	return terms, nil
 }
 
-// GetExpiredPolicies retrieves policies that have expired
-func (pc *PolicyChaincode) GetExpiredPolicies(ctx contractapi.TransactionContextInterface) ([]*Policy, error) {
-	// Get all active policies first
-	activePolicies, err := pc.GetActivePolicies(ctx)
+// GetPolicyTerm retrieves one renewal term of a policy
+func (pc *PolicyChaincode) GetPolicyTerm(ctx contractapi.TransactionContextInterface,
+	policyID string, termID string) (*PolicyTerm, error) {
+
+	terms, err := pc.GetPolicyTerms(ctx, policyID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get deterministic transaction timestamp
-	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	for _, term := range terms {
+		if term.TermID == termID {
+			return term, nil
+		}
 	}
-	currentTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
-	var expiredPolicies []*Policy
+	return nil, fmt.Errorf("policy %s has no term %s", policyID, termID)
+}
 
-	// Check which ones have passed end date
-	for _, policy := range activePolicies {
-		if currentTime.After(policy.EndDate) {
-			// Update status to expired
-			policy.Status = "Expired"
-			policy.LastUpdated = currentTime
+// ========================================
+// WAITING PERIODS & COOLING-OFF
+// ========================================
 
-			policyJSON, err := json.Marshal(policy)
-			if err != nil {
-				continue
-			}
+// IndexEligibility explains whether an index event can be claimed against a policy
+type IndexEligibility struct {
+	Eligible         bool      `json:"eligible"`         // Event counts towards a claim
+	LocationMatched  bool      `json:"locationMatched"`  // Index location governs the policy
+	WithinTerm       bool      `json:"withinTerm"`       // Event falls inside the policy term
+	WaitingPeriodEnd time.Time `json:"waitingPeriodEnd"` // Index events before this date do not count
+	CoolingOffEnd    time.Time `json:"coolingOffEnd"`    // Cooling-off window end
+	Reason           string    `json:"reason"`           // Why the event is not eligible
+}
 
-			err = ctx.GetStub().PutState(policy.PolicyID, policyJSON)
-			if err != nil {
-				continue
-			}
+// CheckIndexEligibility decides whether an index event at a location and date counts for a policy.
+// Events must fall in the policy term, after both the waiting period and the cooling-off window.
+func (pc *PolicyChaincode) CheckIndexEligibility(ctx contractapi.TransactionContextInterface,
+	policyID string, location string, eventDateStr string) (*IndexEligibility, error) {
 
-			expiredPolicies = append(expiredPolicies, policy)
-		}
+	policy, err := pc.GetPolicy(ctx, policyID)
+	if err != nil {
+		return nil, err
 	}
 
-	return expiredPolicies, nil
+	eventDate, err := time.Parse(time.RFC3339, eventDateStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event date: %v", err)
+	}
+
+	result := &IndexEligibility{
+		LocationMatched:  policyCoversLocation(policy, location),
+		WithinTerm:       !eventDate.Before(policy.StartDate) && !eventDate.After(policy.EndDate),
+		WaitingPeriodEnd: policy.WaitingPeriodEnd,
+		CoolingOffEnd:    policy.CoolingOffEnd,
+	}
+
+	switch {
+	case !result.LocationMatched:
+		result.Reason = fmt.Sprintf("location %s does not govern policy %s", location, policyID)
+	case !result.WithinTerm:
+		result.Reason = "event falls outside the policy term"
+	case eventDate.Before(policy.WaitingPeriodEnd):
+		result.Reason = fmt.Sprintf("event falls inside the waiting period ending %s",
+			policy.WaitingPeriodEnd.Format(time.RFC3339))
+	case eventDate.Before(policy.CoolingOffEnd):
+		result.Reason = fmt.Sprintf("event falls inside the cooling-off window ending %s",
+			policy.CoolingOffEnd.Format(time.RFC3339))
+	default:
+		result.Eligible = true
+	}
+
+	return result, nil
 }
 
 // ========================================
-// POLICY HISTORY & AUDIT
+// GROUP MASTER POLICIES
 // ========================================
 
-// recordHistory logs a policy lifecycle event
-func (pc *PolicyChaincode) recordHistory(ctx contractapi.TransactionContextInterface,
-	policyID string, action string, performedBy string, details string) error {
+// MasterPolicy is a group policy bought by a cooperative on behalf of its members
+type MasterPolicy struct {
+	MasterPolicyID     string    `json:"masterPolicyID"`     // Unique master policy identifier
+	CoopID             string    `json:"coopID"`             // Cooperative holding the policy
+	TemplateID         string    `json:"templateID"`         // Policy template used
+	InsurerID          string    `json:"insurerID"`          // Insurance provider
+	CoveragePerHectare float64   `json:"coveragePerHectare"` // Coverage granted per insured hectare
+	PremiumRate        float64   `json:"premiumRate"`        // Full-term premium as a fraction of coverage
+	StartDate          time.Time `json:"startDate"`          // Group cover start date
+	EndDate            time.Time `json:"endDate"`            // Group cover end date
+	FarmLocation       string    `json:"farmLocation"`       // Region covered
+	CropType           string    `json:"cropType"`           // Type of coffee covered
+	PolicyTerms        string    `json:"policyTerms"`        // Terms and conditions hash
+	WaitingDays        int       `json:"waitingDays"`        // Template waiting period applied to certificates
+	CoolingOffDays     int       `json:"coolingOffDays"`     // Template cooling-off window applied to certificates
+	Status             string    `json:"status"`             // Active, Cancelled
+	MemberCount        int       `json:"memberCount"`        // Members currently enrolled
+	PremiumBilled      float64   `json:"premiumBilled"`      // Net premium billed to the cooperative
+	CreatedDate        time.Time `json:"createdDate"`        // Creation timestamp
+	CreatedBy          string    `json:"createdBy"`          // Entity that created the master policy
+	LastUpdated        time.Time `json:"lastUpdated"`        // Last modification timestamp
+}
 
-	txID := ctx.GetStub().GetTxID()
+// MemberEnrollment describes one member to enroll under a master policy
+type MemberEnrollment struct {
+	CertificateID string  `json:"certificateID"` // Policy ID for the member certificate
+	FarmerID      string  `json:"farmerID"`      // Member farmer
+	FarmSize      float64 `json:"farmSize"`      // Insured hectares
+}
+
+// MemberSummary reports a member certificate within a master policy roll-up
+type MemberSummary struct {
+	CertificateID  string  `json:"certificateID"`
+	FarmerID       string  `json:"farmerID"`
+	Status         string  `json:"status"`
+	CoverageAmount float64 `json:"coverageAmount"`
+	PremiumAmount  float64 `json:"premiumAmount"`
+	ClaimCount     int     `json:"claimCount"`
+	TotalPayouts   float64 `json:"totalPayouts"`
+}
+
+// MasterPolicyReport rolls member certificates up to the master policy
+type MasterPolicyReport struct {
+	MasterPolicyID string           `json:"masterPolicyID"`
+	CoopID         string           `json:"coopID"`
+	TotalMembers   int              `json:"totalMembers"`
+	ActiveMembers  int              `json:"activeMembers"`
+	TotalCoverage  float64          `json:"totalCoverage"`
+	PremiumBilled  float64          `json:"premiumBilled"`
+	ClaimCount     int              `json:"claimCount"`
+	TotalPayouts   float64          `json:"totalPayouts"`
+	Members        []*MemberSummary `json:"members"`
+}
+
+// CreateMasterPolicy opens a group policy for a cooperative
+func (pc *PolicyChaincode) CreateMasterPolicy(ctx contractapi.TransactionContextInterface,
+	masterPolicyID string, coopID string, templateID string, insurerID string,
+	coveragePerHectare float64, premiumRate float64, coverageDays int,
+	farmLocation string, cropType string, policyTermsHash string) error {
+
+	existing, err := ctx.GetStub().GetState("MASTER_" + masterPolicyID)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("master policy %s already exists", masterPolicyID)
+	}
+
+	if coopID == "" {
+		return fmt.Errorf("coop ID is required")
+	}
+	if coveragePerHectare <= 0 {
+		return fmt.Errorf("coverage per hectare must be positive")
+	}
+	if premiumRate <= 0 || premiumRate > 1 {
+		return fmt.Errorf("premium rate must be between 0 and 1")
+	}
+	if coverageDays <= 0 {
+		return fmt.Errorf("coverage days must be positive")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	windows, err := getTemplateWindows(ctx, templateID)
+	if err != nil {
+		return err
+	}
 
 	// Get deterministic transaction timestamp
 	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
 		return fmt.Errorf("failed to get transaction timestamp: %v", err)
 	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
-	history := PolicyHistory{
-		HistoryID:   txID,
-		PolicyID:    policyID,
-		Action:      action,
-		Timestamp:   time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)),
-		PerformedBy: performedBy,
-		Details:     details,
+	master := MasterPolicy{
+		MasterPolicyID:     masterPolicyID,
+		CoopID:             coopID,
+		TemplateID:         templateID,
+		InsurerID:          insurerID,
+		CoveragePerHectare: coveragePerHectare,
+		PremiumRate:        premiumRate,
+		StartDate:          timestamp,
+		EndDate:            timestamp.AddDate(0, 0, coverageDays),
+		FarmLocation:       farmLocation,
+		CropType:           cropType,
+		PolicyTerms:        policyTermsHash,
+		WaitingDays:        windows.WaitingDays,
+		CoolingOffDays:     windows.CoolingOffDays,
+		Status:             "Active",
+		MemberCount:        0,
+		PremiumBilled:      0,
+		CreatedDate:        timestamp,
+		CreatedBy:          callerID,
+		LastUpdated:        timestamp,
 	}
 
-	historyJSON, err := json.Marshal(history)
+	return pc.putMasterPolicy(ctx, &master)
+}
+
+// GetMasterPolicy retrieves a group master policy
+func (pc *PolicyChaincode) GetMasterPolicy(ctx contractapi.TransactionContextInterface,
+	masterPolicyID string) (*MasterPolicy, error) {
+
+	masterJSON, err := ctx.GetStub().GetState("MASTER_" + masterPolicyID)
 	if err != nil {
-		return fmt.Errorf("failed to marshal history: %v", err)
+		return nil, fmt.Errorf("failed to read master policy: %v", err)
+	}
+	if masterJSON == nil {
+		return nil, fmt.Errorf("master policy %s does not exist", masterPolicyID)
 	}
 
-	err = ctx.GetStub().PutState("HISTORY_"+txID, historyJSON)
+	var master MasterPolicy
+	err = json.Unmarshal(masterJSON, &master)
 	if err != nil {
-		return fmt.Errorf("failed to put history: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal master policy: %v", err)
 	}
 
-	return nil
+	return &master, nil
 }
 
-// GetPolicyHistory retrieves all lifecycle events for a policy
-func (pc *PolicyChaincode) GetPolicyHistory(ctx contractapi.TransactionContextInterface,
-	policyID string) ([]*PolicyHistory, error) {
+// EnrollMembers issues member certificates under a master policy in bulk.
+// Certificates inherit the master's terms; late joiners pay a pro-rata premium
+// which is billed to the cooperative on the master policy.
+func (pc *PolicyChaincode) EnrollMembers(ctx contractapi.TransactionContextInterface,
+	masterPolicyID string, membersJSON string) error {
 
-	queryString := fmt.Sprintf(`{"selector":{"policyID":"%s"}}`, policyID)
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	master, err := pc.GetMasterPolicy(ctx, masterPolicyID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query policy history: %v", err)
+		return err
+	}
+	if master.Status != "Active" {
+		return fmt.Errorf("cannot enroll members on master policy with status: %s", master.Status)
 	}
-	defer resultsIterator.Close()
 
-	var history []*PolicyHistory
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
+	var members []MemberEnrollment
+	if err := json.Unmarshal([]byte(membersJSON), &members); err != nil {
+		return fmt.Errorf("failed to parse members: %v", err)
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("no members to enroll")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	if !timestamp.Before(master.EndDate) {
+		return fmt.Errorf("master policy %s has ended", masterPolicyID)
+	}
+
+	startDate := master.StartDate
+	if timestamp.After(startDate) {
+		startDate = timestamp
+	}
+	termFraction := master.EndDate.Sub(startDate).Hours() / master.EndDate.Sub(master.StartDate).Hours()
+
+	exposure := exposureDeltas{}
+	seen := make(map[string]bool)
+	for _, member := range members {
+		if member.CertificateID == "" || member.FarmerID == "" {
+			return fmt.Errorf("certificate ID and farmer ID are required for every member")
+		}
+		if member.FarmSize <= 0 {
+			return fmt.Errorf("farm size must be positive for farmer %s", member.FarmerID)
+		}
+		if seen[member.CertificateID] {
+			return fmt.Errorf("duplicate certificate %s in enrollment", member.CertificateID)
+		}
+		seen[member.CertificateID] = true
+
+		exists, err := pc.policyExists(ctx, member.CertificateID)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if exists {
+			return fmt.Errorf("policy %s already exists", member.CertificateID)
 		}
 
-		var record PolicyHistory
-		err = json.Unmarshal(queryResponse.Value, &record)
+		district, err := memberDistrict(ctx, member.FarmerID, master.CoopID)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		history = append(history, &record)
+
+		coverage := master.CoveragePerHectare * member.FarmSize
+		fullTermPremium := coverage * master.PremiumRate
+		premium := fullTermPremium * termFraction
+
+		certificate := Policy{
+			PolicyID:       member.CertificateID,
+			FarmerID:       member.FarmerID,
+			TemplateID:     master.TemplateID,
+			CoopID:         master.CoopID,
+			InsurerID:      master.InsurerID,
+			CoverageAmount: coverage,
+			PremiumAmount:  premium,
+			StartDate:      startDate,
+			EndDate:        master.EndDate,
+			Status:         "Active",
+			FarmLocation:   master.FarmLocation,
+			District:       district,
+			CropType:       master.CropType,
+			FarmSize:       member.FarmSize,
+			PolicyTerms:    master.PolicyTerms,
+			CreatedDate:    timestamp,
+			CreatedBy:      callerID,
+			LastUpdated:    timestamp,
+			ClaimCount:     0,
+			TotalPayouts:   0,
+			Version:        1,
+			VersionDate:    startDate,
+			TermPremium:    premium,
+			MasterPolicyID: masterPolicyID,
+
+			WaitingPeriodEnd: startDate.AddDate(0, 0, master.WaitingDays),
+			CoolingOffEnd:    startDate.AddDate(0, 0, master.CoolingOffDays),
+		}
+
+		certificateJSON, err := json.Marshal(certificate)
+		if err != nil {
+			return fmt.Errorf("failed to marshal certificate: %v", err)
+		}
+
+		err = ctx.GetStub().PutState(certificate.PolicyID, certificateJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put certificate: %v", err)
+		}
+
+		err = pc.recordHistory(ctx, certificate.PolicyID, "Created", callerID,
+			fmt.Sprintf("Member certificate under master policy %s with coverage %.2f, premium %.2f billed to coop %s",
+				masterPolicyID, coverage, premium, master.CoopID))
+		if err != nil {
+			return err
+		}
+
+		err = pc.recordWindows(ctx, &certificate, callerID)
+		if err != nil {
+			return err
+		}
+
+		err = indexPolicyExpiry(ctx, &certificate)
+		if err != nil {
+			return err
+		}
+
+		master.MemberCount++
+		master.PremiumBilled += premium
+		exposure.addPolicy(&certificate, 1)
+		exposure.bindPolicy(&certificate, 1)
 	}
 
-	return history, nil
+	err = pc.applyExposure(ctx, exposure, timestamp)
+	if err != nil {
+		return err
+	}
+
+	// Certificate premium is billed to the coop, so the pool is not told through a deposit
+	_, err = invokeChaincode(ctx, premiumPoolChaincode, "AdjustActivePolicies", strconv.Itoa(len(members)))
+	if err != nil {
+		return err
+	}
+
+	master.LastUpdated = timestamp
+	return pc.putMasterPolicy(ctx, master)
 }
 
-// ========================================
-// HELPER FUNCTIONS
-// ========================================
+// RemoveMembers cancels member certificates and credits unused premium to the master
+func (pc *PolicyChaincode) RemoveMembers(ctx contractapi.TransactionContextInterface,
+	masterPolicyID string, certificateIDsJSON string, reason string) error {
+
+	master, err := pc.GetMasterPolicy(ctx, masterPolicyID)
+	if err != nil {
+		return err
+	}
+
+	var certificateIDs []string
+	if err := json.Unmarshal([]byte(certificateIDsJSON), &certificateIDs); err != nil {
+		return fmt.Errorf("failed to parse certificate IDs: %v", err)
+	}
+	if len(certificateIDs) == 0 {
+		return fmt.Errorf("no members to remove")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	exposure := exposureDeltas{}
+	for _, certificateID := range certificateIDs {
+		certificate, err := pc.GetPolicy(ctx, certificateID)
+		if err != nil {
+			return err
+		}
+		if certificate.MasterPolicyID != masterPolicyID {
+			return fmt.Errorf("policy %s is not a certificate of master policy %s", certificateID, masterPolicyID)
+		}
+		if certificate.Status != "Active" {
+			return fmt.Errorf("certificate %s is not active", certificateID)
+		}
+
+		// Credit the full premium inside the cooling-off window, otherwise the
+		// unexpired share, unless the member has claimed
+		credit := 0.0
+		termHours := certificate.EndDate.Sub(certificate.StartDate).Hours()
+		if inCoolingOff(certificate, timestamp) {
+			credit = certificate.PremiumAmount
+		} else if certificate.ClaimCount == 0 && termHours > 0 && timestamp.Before(certificate.EndDate) {
+			credit = certificate.PremiumAmount * certificate.EndDate.Sub(timestamp).Hours() / termHours
+		}
+
+		// A cancelled certificate no longer matches weather events in its cells
+		err = unindexPolicyCells(ctx, certificate)
+		if err != nil {
+			return err
+		}
+
+		certificate.Status = "Cancelled"
+		certificate.GridCells = nil
+		certificate.LastUpdated = timestamp
+
+		certificateJSON, err := json.Marshal(certificate)
+		if err != nil {
+			return fmt.Errorf("failed to marshal certificate: %v", err)
+		}
+
+		err = ctx.GetStub().PutState(certificateID, certificateJSON)
+		if err != nil {
+			return fmt.Errorf("failed to cancel certificate: %v", err)
+		}
+
+		err = pc.recordHistory(ctx, certificateID, "Cancelled", callerID,
+			fmt.Sprintf("Removed from master policy %s (premium credit %.2f): %s", masterPolicyID, credit, reason))
+		if err != nil {
+			return err
+		}
+
+		master.MemberCount--
+		master.PremiumBilled -= credit
+		exposure.addPolicy(certificate, -1)
+	}
+
+	err = pc.applyExposure(ctx, exposure, timestamp)
+	if err != nil {
+		return err
+	}
+
+	_, err = invokeChaincode(ctx, premiumPoolChaincode, "AdjustActivePolicies", strconv.Itoa(-len(certificateIDs)))
+	if err != nil {
+		return err
+	}
+
+	master.LastUpdated = timestamp
+	return pc.putMasterPolicy(ctx, master)
+}
+
+// GetMemberCertificates retrieves all certificates issued under a master policy
+func (pc *PolicyChaincode) GetMemberCertificates(ctx contractapi.TransactionContextInterface,
+	masterPolicyID string) ([]*Policy, error) {
+
+	queryString := fmt.Sprintf(`{"selector":{"masterPolicyID":"%s","policyID":{"$exists":true}}}`, masterPolicyID)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query member certificates: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var certificates []*Policy
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var certificate Policy
+		err = json.Unmarshal(queryResponse.Value, &certificate)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, &certificate)
+	}
+
+	return certificates, nil
+}
+
+// GetMasterPolicyReport rolls member coverage, claims and payouts up to the master
+func (pc *PolicyChaincode) GetMasterPolicyReport(ctx contractapi.TransactionContextInterface,
+	masterPolicyID string) (*MasterPolicyReport, error) {
+
+	master, err := pc.GetMasterPolicy(ctx, masterPolicyID)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates, err := pc.GetMemberCertificates(ctx, masterPolicyID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MasterPolicyReport{
+		MasterPolicyID: masterPolicyID,
+		CoopID:         master.CoopID,
+		PremiumBilled:  master.PremiumBilled,
+		Members:        []*MemberSummary{},
+	}
+
+	for _, certificate := range certificates {
+		report.TotalMembers++
+		if certificate.Status == "Active" {
+			report.ActiveMembers++
+			report.TotalCoverage += certificate.CoverageAmount
+		}
+		report.ClaimCount += certificate.ClaimCount
+		report.TotalPayouts += certificate.TotalPayouts
+
+		report.Members = append(report.Members, &MemberSummary{
+			CertificateID:  certificate.PolicyID,
+			FarmerID:       certificate.FarmerID,
+			Status:         certificate.Status,
+			CoverageAmount: certificate.CoverageAmount,
+			PremiumAmount:  certificate.PremiumAmount,
+			ClaimCount:     certificate.ClaimCount,
+			TotalPayouts:   certificate.TotalPayouts,
+		})
+	}
+
+	return report, nil
+}
+
+// ========================================
+// POLICY TRANSFERS & BENEFICIARIES
+// ========================================
+
+// Beneficiary receives payouts on a policy without becoming its holder
+type Beneficiary struct {
+	BeneficiaryID  string    `json:"beneficiaryID"`  // Beneficiary identifier
+	Name           string    `json:"name"`           // Beneficiary name
+	WalletAddress  string    `json:"walletAddress"`  // Wallet that receives payouts
+	Relationship   string    `json:"relationship"`   // Relationship to the farmer
+	DesignatedDate time.Time `json:"designatedDate"` // When the designation was made
+	DesignatedBy   string    `json:"designatedBy"`   // Who made the designation
+
+	ApprovalRequestID string `json:"approvalRequestID"` // Approval authorising the designation
+}
+
+// PolicyTransfer records the reassignment of a policy to another farmer
+type PolicyTransfer struct {
+	TransferID        string    `json:"transferID"`        // Unique transfer identifier
+	PolicyID          string    `json:"policyID"`          // Transferred policy
+	FromFarmerID      string    `json:"fromFarmerID"`      // Previous policy holder
+	ToFarmerID        string    `json:"toFarmerID"`        // New policy holder
+	Reason            string    `json:"reason"`            // Succession, Sale
+	ApprovalRequestID string    `json:"approvalRequestID"` // Approval request signed by coop and insurer
+	ClaimCount        int       `json:"claimCount"`        // Claims carried over to the new holder
+	TotalPayouts      float64   `json:"totalPayouts"`      // Payouts carried over to the new holder
+	TransferDate      time.Time `json:"transferDate"`      // When the transfer took effect
+	TransferredBy     string    `json:"transferredBy"`     // Who executed the transfer
+}
+
+// TransferPolicy reassigns an active policy to another farmer after a death or land sale.
+// The approval request must target policy.TransferPolicy with transferID, policyID and
+// newFarmerID as its leading arguments and be approved by the coop's and insurer's MSPs.
+// Claim count and payout history stay with the policy; any beneficiary is cleared.
+func (pc *PolicyChaincode) TransferPolicy(ctx contractapi.TransactionContextInterface,
+	transferID string, policyID string, newFarmerID string, reason string, approvalRequestID string) error {
+
+	if reason != "Succession" && reason != "Sale" {
+		return fmt.Errorf("invalid transfer reason: %s", reason)
+	}
+
+	transferJSON, err := ctx.GetStub().GetState("TRANSFER_" + transferID)
+	if err != nil {
+		return fmt.Errorf("failed to read transfer: %v", err)
+	}
+	if transferJSON != nil {
+		return fmt.Errorf("transfer %s already exists", transferID)
+	}
+
+	policy, err := pc.GetPolicy(ctx, policyID)
+	if err != nil {
+		return err
+	}
+
+	if policy.Status != "Active" {
+		return fmt.Errorf("can only transfer active policies")
+	}
+	if policy.FarmerID == newFarmerID {
+		return fmt.Errorf("policy %s is already held by farmer %s", policyID, newFarmerID)
+	}
+
+	// New holder must be an active, KYC-verified farmer
+	_, err = invokeChaincode(ctx, farmerChaincode, "VerifyFarmerIdentity", newFarmerID)
+	if err != nil {
+		return err
+	}
+
+	err = verifyApproval(ctx, approvalRequestID, "TransferPolicy",
+		[]string{transferID, policyID, newFarmerID}, policy.CoopID, policy.InsurerID)
+	if err != nil {
+		return err
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	transfer := PolicyTransfer{
+		TransferID:        transferID,
+		PolicyID:          policyID,
+		FromFarmerID:      policy.FarmerID,
+		ToFarmerID:        newFarmerID,
+		Reason:            reason,
+		ApprovalRequestID: approvalRequestID,
+		ClaimCount:        policy.ClaimCount,
+		TotalPayouts:      policy.TotalPayouts,
+		TransferDate:      timestamp,
+		TransferredBy:     callerID,
+	}
+
+	transferJSON, err = json.Marshal(transfer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transfer: %v", err)
+	}
+
+	err = ctx.GetStub().PutState("TRANSFER_"+transferID, transferJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put transfer: %v", err)
+	}
+
+	policy.FarmerID = newFarmerID
+	policy.Beneficiary = nil
+	policy.LastUpdated = timestamp
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(policyID, policyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update policy: %v", err)
+	}
+
+	return pc.recordHistory(ctx, policyID, "Transferred", callerID,
+		fmt.Sprintf("%s: transfer %s from %s to %s approved by %s, carrying %d claims and %.2f paid out",
+			reason, transferID, transfer.FromFarmerID, newFarmerID, approvalRequestID,
+			transfer.ClaimCount, transfer.TotalPayouts))
+}
+
+// GetPolicyTransfer retrieves a transfer record by ID
+func (pc *PolicyChaincode) GetPolicyTransfer(ctx contractapi.TransactionContextInterface,
+	transferID string) (*PolicyTransfer, error) {
+
+	transferJSON, err := ctx.GetStub().GetState("TRANSFER_" + transferID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transfer: %v", err)
+	}
+	if transferJSON == nil {
+		return nil, fmt.Errorf("transfer %s does not exist", transferID)
+	}
+
+	var transfer PolicyTransfer
+	err = json.Unmarshal(transferJSON, &transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transfer: %v", err)
+	}
+
+	return &transfer, nil
+}
+
+// GetPolicyTransfers retrieves every transfer of a policy, oldest first
+func (pc *PolicyChaincode) GetPolicyTransfers(ctx contractapi.TransactionContextInterface,
+	policyID string) ([]*PolicyTransfer, error) {
+
+	queryString := fmt.Sprintf(`{"selector":{"policyID":"%s","transferID":{"$exists":true}}}`, policyID)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transfers: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var transfers []*PolicyTransfer
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var transfer PolicyTransfer
+		err = json.Unmarshal(queryResponse.Value, &transfer)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, &transfer)
+	}
+
+	sort.Slice(transfers, func(i, j int) bool {
+		return transfers[i].TransferDate.Before(transfers[j].TransferDate)
+	})
+
+	return transfers, nil
+}
+
+// DesignateBeneficiary redirects a policy's payouts without transferring the policy.
+// The approval request must target policy.DesignateBeneficiary with policyID, beneficiaryID
+// and walletAddress as its leading arguments and be approved by the coop's and insurer's MSPs.
+func (pc *PolicyChaincode) DesignateBeneficiary(ctx contractapi.TransactionContextInterface,
+	policyID string, beneficiaryID string, name string, walletAddress string, relationship string,
+	approvalRequestID string) error {
+
+	if beneficiaryID == "" || walletAddress == "" {
+		return fmt.Errorf("beneficiary ID and wallet address are required")
+	}
+
+	policy, err := pc.GetPolicy(ctx, policyID)
+	if err != nil {
+		return err
+	}
+
+	if policy.Status != "Active" {
+		return fmt.Errorf("can only designate beneficiaries on active policies")
+	}
+	if beneficiaryID == policy.FarmerID {
+		return fmt.Errorf("farmer %s already receives payouts on policy %s", beneficiaryID, policyID)
+	}
+
+	err = verifyApproval(ctx, approvalRequestID, "DesignateBeneficiary",
+		[]string{policyID, beneficiaryID, walletAddress}, policy.CoopID, policy.InsurerID)
+	if err != nil {
+		return err
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	policy.Beneficiary = &Beneficiary{
+		BeneficiaryID:  beneficiaryID,
+		Name:           name,
+		WalletAddress:  walletAddress,
+		Relationship:   relationship,
+		DesignatedDate: timestamp,
+		DesignatedBy:   callerID,
+
+		ApprovalRequestID: approvalRequestID,
+	}
+	policy.LastUpdated = timestamp
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(policyID, policyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update policy: %v", err)
+	}
+
+	return pc.recordHistory(ctx, policyID, "BeneficiaryDesignated", callerID,
+		fmt.Sprintf("Payouts redirected to %s (%s) approved by %s", beneficiaryID, relationship, approvalRequestID))
+}
+
+// RevokeBeneficiary returns payouts on a policy to the farmer once the coop and insurer approve
+func (pc *PolicyChaincode) RevokeBeneficiary(ctx contractapi.TransactionContextInterface,
+	policyID string, beneficiaryID string, reason string, approvalRequestID string) error {
+
+	policy, err := pc.GetPolicy(ctx, policyID)
+	if err != nil {
+		return err
+	}
+
+	if policy.Beneficiary == nil {
+		return fmt.Errorf("policy %s has no beneficiary", policyID)
+	}
+	if policy.Beneficiary.BeneficiaryID != beneficiaryID {
+		return fmt.Errorf("beneficiary %s is not designated on policy %s", beneficiaryID, policyID)
+	}
+
+	err = verifyApproval(ctx, approvalRequestID, "RevokeBeneficiary",
+		[]string{policyID, beneficiaryID}, policy.CoopID, policy.InsurerID)
+	if err != nil {
+		return err
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	previous := policy.Beneficiary.BeneficiaryID
+	policy.Beneficiary = nil
+	policy.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(policyID, policyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update policy: %v", err)
+	}
+
+	return pc.recordHistory(ctx, policyID, "BeneficiaryRevoked", callerID,
+		fmt.Sprintf("Beneficiary %s revoked: %s (approved by %s)", previous, reason, approvalRequestID))
+}
+
+// GetPolicyPayee returns who receives payouts on a policy: the beneficiary if designated, else the farmer
+func (pc *PolicyChaincode) GetPolicyPayee(ctx contractapi.TransactionContextInterface,
+	policyID string) (string, error) {
+
+	policy, err := pc.GetPolicy(ctx, policyID)
+	if err != nil {
+		return "", err
+	}
+
+	return policyPayee(policy), nil
+}
+
+// ========================================
+// COVERAGE AREAS & GRID MATCHING
+// ========================================
+
+// SetPolicyCoverageArea attaches a point-plus-radius or polygon area to a policy
+// and maps it onto the grid cells used for weather index matching
+func (pc *PolicyChaincode) SetPolicyCoverageArea(ctx contractapi.TransactionContextInterface,
+	policyID string, areaJSON string) error {
+
+	policy, err := pc.GetPolicy(ctx, policyID)
+	if err != nil {
+		return err
+	}
+
+	if policy.Status != "Active" {
+		return fmt.Errorf("can only set coverage area on active policies")
+	}
+
+	var area CoverageArea
+	if err := json.Unmarshal([]byte(areaJSON), &area); err != nil {
+		return fmt.Errorf("failed to parse coverage area: %v", err)
+	}
+	if err := validateCoverageArea(&area); err != nil {
+		return err
+	}
+
+	cells, err := coverageCells(&area)
+	if err != nil {
+		return err
+	}
+
+	// Replace the policy's entries in the cell index
+	err = unindexPolicyCells(ctx, policy)
+	if err != nil {
+		return err
+	}
+	for _, cell := range cells {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(policyCellObjectType, []string{cell, policyID})
+		if err != nil {
+			return fmt.Errorf("failed to create cell key: %v", err)
+		}
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to index policy cell: %v", err)
+		}
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	policy.CoverageArea = &area
+	policy.GridCells = cells
+	policy.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(policyID, policyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set coverage area: %v", err)
+	}
+
+	callerID, _ := ctx.GetClientIdentity().GetID()
+	err = pc.recordHistory(ctx, policyID, "CoverageAreaSet", callerID,
+		fmt.Sprintf("%s coverage area mapped to %d grid cells", area.Shape, len(cells)))
+
+	return err
+}
+
+// GetPoliciesByGridCell retrieves active policies whose coverage area maps to a grid cell
+func (pc *PolicyChaincode) GetPoliciesByGridCell(ctx contractapi.TransactionContextInterface,
+	cellID string) ([]*Policy, error) {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(policyCellObjectType, []string{cellID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query grid cell index: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var policies []*Policy
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split cell key: %v", err)
+		}
+
+		policy, err := pc.GetPolicy(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		if policy.Status != "Active" {
+			continue
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+// GetPoliciesByCoordinates retrieves active policies whose coverage area contains a coordinate
+func (pc *PolicyChaincode) GetPoliciesByCoordinates(ctx contractapi.TransactionContextInterface,
+	latitude float64, longitude float64) ([]*Policy, error) {
+
+	point := GeoPoint{Latitude: latitude, Longitude: longitude}
+	if err := validateGeoPoint(point); err != nil {
+		return nil, err
+	}
+
+	candidates, err := pc.GetPoliciesByGridCell(ctx, geogrid.CellID(latitude, longitude))
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []*Policy
+	for _, policy := range candidates {
+		if policy.CoverageArea != nil && areaContains(policy.CoverageArea, point) {
+			policies = append(policies, policy)
+		}
+	}
+
+	return policies, nil
+}
+
+// PolicyCoversLocation reports whether an index location governs a policy.
+// Location may be a grid cell ID or, for policies without a coverage area, a region name.
+func (pc *PolicyChaincode) PolicyCoversLocation(ctx contractapi.TransactionContextInterface,
+	policyID string, location string) (bool, error) {
+
+	policy, err := pc.GetPolicy(ctx, policyID)
+	if err != nil {
+		return false, err
+	}
+
+	return policyCoversLocation(policy, location), nil
+}
+
+// GetGridCell returns the grid cell ID containing a coordinate
+func (pc *PolicyChaincode) GetGridCell(ctx contractapi.TransactionContextInterface,
+	latitude float64, longitude float64) (string, error) {
+
+	if err := validateGeoPoint(GeoPoint{Latitude: latitude, Longitude: longitude}); err != nil {
+		return "", err
+	}
+	return geogrid.CellID(latitude, longitude), nil
+}
+
+// ========================================
+// POLICY CLAIMS & PAYOUTS
+// ========================================
+
+// RecordClaim updates policy with claim information
+func (pc *PolicyChaincode) RecordClaim(ctx contractapi.TransactionContextInterface,
+	policyID string, payoutAmount float64) error {
+
+	policy, err := pc.GetPolicy(ctx, policyID)
+	if err != nil {
+		return err
+	}
+
+	if policy.Status != "Active" {
+		return fmt.Errorf("cannot claim on non-active policy")
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	// A claimed policy leaves active exposure and its payout counts against its segments
+	exposure := exposureDeltas{}
+	exposure.addPolicy(policy, -1)
+	exposure.addClaim(policy, payoutAmount)
+
+	policy.ClaimCount++
+	policy.TotalPayouts += payoutAmount
+	policy.Status = "Claimed"
+	policy.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(policyID, policyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to record claim: %v", err)
+	}
+
+	err = pc.applyExposure(ctx, exposure, policy.LastUpdated)
+	if err != nil {
+		return err
+	}
+
+	callerID, _ := ctx.GetClientIdentity().GetID()
+	err = pc.recordHistory(ctx, policyID, "Claimed", callerID,
+		fmt.Sprintf("Claim processed with payout %.2f", payoutAmount))
+
+	return err
+}
+
+// ClaimHistorySummary represents claim history data
+type ClaimHistorySummary struct {
+	ClaimCount   int     `json:"claimCount"`
+	TotalPayouts float64 `json:"totalPayouts"`
+}
+
+// GetPolicyClaimHistory retrieves claim count and total payouts
+func (pc *PolicyChaincode) GetPolicyClaimHistory(ctx contractapi.TransactionContextInterface,
+	policyID string) (*ClaimHistorySummary, error) {
+
+	policy, err := pc.GetPolicy(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClaimHistorySummary{
+		ClaimCount:   policy.ClaimCount,
+		TotalPayouts: policy.TotalPayouts,
+	}, nil
+}
+
+// ========================================
+// POLICY QUERIES
+// ========================================
+
+// GetPoliciesByFarmer retrieves all policies for a specific farmer
+func (pc *PolicyChaincode) GetPoliciesByFarmer(ctx contractapi.TransactionContextInterface,
+	farmerID string) ([]*Policy, error) {
+
+	queryString := fmt.Sprintf(`{"selector":{"farmerID":"%s"}}`, farmerID)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policies by farmer: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var policies []*Policy
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var policy Policy
+		err = json.Unmarshal(queryResponse.Value, &policy)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, &policy)
+	}
+
+	return policies, nil
+}
+
+// GetPoliciesByRegion retrieves all policies in a geographic area
+func (pc *PolicyChaincode) GetPoliciesByRegion(ctx contractapi.TransactionContextInterface,
+	region string) ([]*Policy, error) {
+
+	queryString := fmt.Sprintf(`{"selector":{"farmLocation":"%s","policyID":{"$exists":true}}}`, region)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policies by region: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var policies []*Policy
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var policy Policy
+		err = json.Unmarshal(queryResponse.Value, &policy)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, &policy)
+	}
+
+	return policies, nil
+}
+
+// GetActivePolicies retrieves all currently active policies
+func (pc *PolicyChaincode) GetActivePolicies(ctx contractapi.TransactionContextInterface) ([]*Policy, error) {
+	queryString := `{"selector":{"status":"Active","policyID":{"$exists":true}}}`
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active policies: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var policies []*Policy
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var policy Policy
+		err = json.Unmarshal(queryResponse.Value, &policy)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, &policy)
+	}
+
+	return policies, nil
+}
+
+// GetPoliciesByTemplate retrieves all policies bound to a template version
+func (pc *PolicyChaincode) GetPoliciesByTemplate(ctx contractapi.TransactionContextInterface,
+	templateID string) ([]*Policy, error) {
+
+	queryString := fmt.Sprintf(`{"selector":{"templateID":"%s","policyID":{"$exists":true}}}`, templateID)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policies by template: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var policies []*Policy
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var policy Policy
+		err = json.Unmarshal(queryResponse.Value, &policy)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, &policy)
+	}
+
+	return policies, nil
+}
+
+// GetPoliciesByInsurer retrieves all policies from a specific insurer
+func (pc *PolicyChaincode) GetPoliciesByInsurer(ctx contractapi.TransactionContextInterface,
+	insurerID string) ([]*Policy, error) {
+
+	queryString := fmt.Sprintf(`{"selector":{"insurerID":"%s","policyID":{"$exists":true}}}`, insurerID)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policies by insurer: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var policies []*Policy
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var policy Policy
+		err = json.Unmarshal(queryResponse.Value, &policy)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, &policy)
+	}
+
+	return policies, nil
+}
+
+// GetExpiredPolicies retrieves active policies whose end date has passed
+func (pc *PolicyChaincode) GetExpiredPolicies(ctx contractapi.TransactionContextInterface) ([]*Policy, error) {
+	// Get all active policies first
+	activePolicies, err := pc.GetActivePolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	currentTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	var expiredPolicies []*Policy
+
+	// Check which ones have passed end date; ExpirePolicies performs the status change
+	for _, policy := range activePolicies {
+		if currentTime.After(policy.EndDate) {
+			expiredPolicies = append(expiredPolicies, policy)
+		}
+	}
+
+	return expiredPolicies, nil
+}
+
+// ========================================
+// PORTFOLIO EXPOSURE
+// ========================================
+
+// exposureDimensions are the portfolio segments aggregated for underwriters
+var exposureDimensions = []string{"Region", "District", "CropType", "Insurer", "Coop", "Template"}
+
+// ExposureAggregate is the running exposure of one portfolio segment
+type ExposureAggregate struct {
+	Dimension      string    `json:"dimension"`      // Region, District, CropType, Insurer, Coop, Template
+	Value          string    `json:"value"`          // Segment value, e.g. the region name
+	PolicyCount    int       `json:"policyCount"`    // Active policies in the segment
+	BoundPolicies  int       `json:"boundPolicies"`  // Policies written in the segment, whatever their status
+	SumInsured     float64   `json:"sumInsured"`     // Coverage of active policies
+	PremiumWritten float64   `json:"premiumWritten"` // Full-term premium of active policies
+	ClaimCount     int       `json:"claimCount"`     // Claims recorded in the segment
+	ClaimsPaid     float64   `json:"claimsPaid"`     // Payouts recorded in the segment
+	LastUpdated    time.Time `json:"lastUpdated"`    // Last change to the aggregate
+}
+
+// GetExposure retrieves the exposure of one segment, e.g. ("District", "Sidama")
+func (pc *PolicyChaincode) GetExposure(ctx contractapi.TransactionContextInterface,
+	dimension string, value string) (*ExposureAggregate, error) {
+
+	if !validExposureDimension(dimension) {
+		return nil, fmt.Errorf("invalid exposure dimension: %s", dimension)
+	}
+
+	aggregates, err := readExposure(ctx, dimension, value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Segments without policies have no exposure
+	if len(aggregates) == 0 {
+		return &ExposureAggregate{Dimension: dimension, Value: value}, nil
+	}
+
+	return aggregates[0], nil
+}
+
+// GetExposureByDimension retrieves the exposure of every segment in a dimension
+func (pc *PolicyChaincode) GetExposureByDimension(ctx contractapi.TransactionContextInterface,
+	dimension string) ([]*ExposureAggregate, error) {
+
+	if !validExposureDimension(dimension) {
+		return nil, fmt.Errorf("invalid exposure dimension: %s", dimension)
+	}
+
+	return readExposure(ctx, dimension)
+}
+
+// CompactExposure folds a segment's pending deltas into its stored aggregate. Reads stay
+// correct without it; compaction only bounds the number of deltas a read has to sum.
+func (pc *PolicyChaincode) CompactExposure(ctx contractapi.TransactionContextInterface,
+	dimension string, value string) (*ExposureAggregate, error) {
+
+	aggregate, err := pc.GetExposure(ctx, dimension, value)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(exposureDeltaObjectType,
+		[]string{dimension, value})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exposure deltas: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		err = ctx.GetStub().DelState(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete exposure delta: %v", err)
+		}
+	}
+
+	err = putExposure(ctx, aggregate)
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregate, nil
+}
+
+// RebuildExposure recomputes every exposure aggregate from the policies on the ledger,
+// replacing stored aggregates and deltas. It backfills policies written before
+// aggregates were maintained. A policy is bound to every segment it has been written
+// in, including the template versions of its closed renewal terms. The rebuild reads
+// the delta range, so it is invalidated and must be retried if a policy changes in the
+// same block. Returns the number of policies counted.
+func (pc *PolicyChaincode) RebuildExposure(ctx contractapi.TransactionContextInterface) (int, error) {
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	for _, objectType := range []string{exposureObjectType, exposureDeltaObjectType} {
+		err = deleteByPartialKey(ctx, objectType)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	queryString := `{"selector":{"policyID":{"$exists":true},"status":{"$exists":true},"coverageAmount":{"$exists":true}}}`
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query policies: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	exposure := exposureDeltas{}
+	counted := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+
+		var policy Policy
+		err = json.Unmarshal(queryResponse.Value, &policy)
+		if err != nil {
+			return 0, err
+		}
+
+		if policy.Status == "Active" {
+			exposure.addPolicy(&policy, 1)
+		}
+		exposure.bindPolicy(&policy, 1)
+		for _, delta := range exposure.segments(&policy) {
+			delta.ClaimCount += policy.ClaimCount
+			delta.ClaimsPaid += policy.TotalPayouts
+		}
+
+		templates, err := closedTermTemplates(ctx, policy.PolicyID)
+		if err != nil {
+			return 0, err
+		}
+		for _, templateID := range templates {
+			if templateID != policy.TemplateID {
+				exposure.bindTemplate(templateID)
+			}
+		}
+		counted++
+	}
+
+	keys := make([]string, 0, len(exposure))
+	for key := range exposure {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		aggregate := exposure[key]
+		aggregate.LastUpdated = timestamp
+		settleExposure(aggregate)
+
+		err = putExposure(ctx, aggregate)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return counted, nil
+}
+
+// exposureDeltas accumulates changes per segment so each transaction writes one delta
+// per segment; Fabric does not let a transaction read its own writes
+type exposureDeltas map[string]*ExposureAggregate
+
+// addPolicy adds (sign 1) or removes (sign -1) a policy's active exposure
+func (d exposureDeltas) addPolicy(policy *Policy, sign int) {
+	for _, delta := range d.segments(policy) {
+		delta.PolicyCount += sign
+		delta.SumInsured += float64(sign) * policy.CoverageAmount
+		delta.PremiumWritten += float64(sign) * termPremium(policy)
+	}
+}
+
+// bindPolicy adds (sign 1) or removes (sign -1) a policy from the segments it is written in,
+// whether or not it is in force
+func (d exposureDeltas) bindPolicy(policy *Policy, sign int) {
+	for _, delta := range d.segments(policy) {
+		delta.BoundPolicies += sign
+	}
+}
+
+// bindTemplate adds a policy to the segment of a template version it renews onto; the
+// versions it was written on before keep their count
+func (d exposureDeltas) bindTemplate(templateID string) {
+	d.segment("Template", templateID).BoundPolicies++
+}
+
+// addClaim records a claim payout against a policy's segments
+func (d exposureDeltas) addClaim(policy *Policy, payoutAmount float64) {
+	for _, delta := range d.segments(policy) {
+		delta.ClaimCount++
+		delta.ClaimsPaid += payoutAmount
+	}
+}
+
+// segments returns the deltas for every segment a policy belongs to
+func (d exposureDeltas) segments(policy *Policy) []*ExposureAggregate {
+	values := []string{policy.FarmLocation, policy.District, policy.CropType,
+		policy.InsurerID, policy.CoopID, policy.TemplateID}
+
+	var deltas []*ExposureAggregate
+	for i, dimension := range exposureDimensions {
+		if values[i] == "" {
+			continue
+		}
+		deltas = append(deltas, d.segment(dimension, values[i]))
+	}
+	return deltas
+}
+
+// segment returns the delta for one segment, creating it when first touched
+func (d exposureDeltas) segment(dimension string, value string) *ExposureAggregate {
+	key := dimension + "\x00" + value
+	if d[key] == nil {
+		d[key] = &ExposureAggregate{Dimension: dimension, Value: value}
+	}
+	return d[key]
+}
+
+// applyExposure writes accumulated deltas under keys unique to the transaction, so
+// transactions touching the same segment never conflict. Reads sum the stored aggregate
+// and its deltas. Call at most once per transaction.
+func (pc *PolicyChaincode) applyExposure(ctx contractapi.TransactionContextInterface,
+	deltas exposureDeltas, timestamp time.Time) error {
+
+	keys := make([]string, 0, len(deltas))
+	for key := range deltas {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	txID := ctx.GetStub().GetTxID()
+	for _, key := range keys {
+		delta := deltas[key]
+		delta.LastUpdated = timestamp
+
+		deltaKey, err := ctx.GetStub().CreateCompositeKey(exposureDeltaObjectType,
+			[]string{delta.Dimension, delta.Value, txID})
+		if err != nil {
+			return fmt.Errorf("failed to create exposure delta key: %v", err)
+		}
+
+		deltaJSON, err := json.Marshal(delta)
+		if err != nil {
+			return fmt.Errorf("failed to marshal exposure delta: %v", err)
+		}
+
+		err = ctx.GetStub().PutState(deltaKey, deltaJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put exposure delta: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// readExposure sums stored aggregates and their deltas for the segments matching the
+// leading key attributes (a dimension, optionally followed by a value), ordered by value
+func readExposure(ctx contractapi.TransactionContextInterface, attributes ...string) ([]*ExposureAggregate, error) {
+	totals := make(map[string]*ExposureAggregate)
+
+	for _, objectType := range []string{exposureObjectType, exposureDeltaObjectType} {
+		resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, attributes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query exposure: %v", err)
+		}
+
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+
+			var delta ExposureAggregate
+			err = json.Unmarshal(queryResponse.Value, &delta)
+			if err != nil {
+				resultsIterator.Close()
+				return nil, fmt.Errorf("failed to unmarshal exposure: %v", err)
+			}
+
+			total := totals[delta.Value]
+			if total == nil {
+				total = &ExposureAggregate{Dimension: delta.Dimension, Value: delta.Value}
+				totals[delta.Value] = total
+			}
+			total.PolicyCount += delta.PolicyCount
+			total.BoundPolicies += delta.BoundPolicies
+			total.SumInsured += delta.SumInsured
+			total.PremiumWritten += delta.PremiumWritten
+			total.ClaimCount += delta.ClaimCount
+			total.ClaimsPaid += delta.ClaimsPaid
+			if delta.LastUpdated.After(total.LastUpdated) {
+				total.LastUpdated = delta.LastUpdated
+			}
+		}
+		resultsIterator.Close()
+	}
+
+	values := make([]string, 0, len(totals))
+	for value := range totals {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	aggregates := make([]*ExposureAggregate, 0, len(values))
+	for _, value := range values {
+		settleExposure(totals[value])
+		aggregates = append(aggregates, totals[value])
+	}
+
+	return aggregates, nil
+}
+
+// settleExposure clears floating point residue once a segment has no active policies
+func settleExposure(aggregate *ExposureAggregate) {
+	if aggregate.PolicyCount <= 0 {
+		aggregate.PolicyCount = 0
+		aggregate.SumInsured = 0
+		aggregate.PremiumWritten = 0
+	}
+}
+
+// putExposure stores a segment's compacted aggregate
+func putExposure(ctx contractapi.TransactionContextInterface, aggregate *ExposureAggregate) error {
+	exposureKey, err := ctx.GetStub().CreateCompositeKey(exposureObjectType,
+		[]string{aggregate.Dimension, aggregate.Value})
+	if err != nil {
+		return fmt.Errorf("failed to create exposure key: %v", err)
+	}
+
+	exposureJSON, err := json.Marshal(aggregate)
+	if err != nil {
+		return fmt.Errorf("failed to marshal exposure: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(exposureKey, exposureJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put exposure: %v", err)
+	}
+
+	return nil
+}
+
+// deleteByPartialKey removes every key of a composite key object type
+func deleteByPartialKey(ctx contractapi.TransactionContextInterface, objectType string) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to query %s keys: %v", objectType, err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		err = ctx.GetStub().DelState(queryResponse.Key)
+		if err != nil {
+			return fmt.Errorf("failed to delete %s key: %v", objectType, err)
+		}
+	}
+
+	return nil
+}
+
+// closedTermTemplates lists the templates underwriting a policy's closed renewal terms
+func closedTermTemplates(ctx contractapi.TransactionContextInterface, policyID string) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(policyTermObjectType, []string{policyID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policy terms: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	seen := make(map[string]bool)
+	var templates []string
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var term PolicyTerm
+		err = json.Unmarshal(queryResponse.Value, &term)
+		if err != nil {
+			return nil, err
+		}
+		if term.TemplateID != "" && !seen[term.TemplateID] {
+			seen[term.TemplateID] = true
+			templates = append(templates, term.TemplateID)
+		}
+	}
+
+	return templates, nil
+}
+
+func validExposureDimension(dimension string) bool {
+	for _, d := range exposureDimensions {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+// ========================================
+// POLICY EXPIRY & RENEWAL REMINDERS
+// ========================================
+
+// RenewalReminder identifies a policy approaching its end date
+type RenewalReminder struct {
+	PolicyID      string    `json:"policyID"`
+	FarmerID      string    `json:"farmerID"`
+	CoopID        string    `json:"coopID"`
+	EndDate       time.Time `json:"endDate"`
+	DaysRemaining int       `json:"daysRemaining"`
+}
+
+// ExpirySweepResult summarises one page of an expiry sweep
+type ExpirySweepResult struct {
+	Examined     int                `json:"examined"`     // Expiry index entries examined in this page
+	Expired      []string           `json:"expired"`      // Policies moved to Expired
+	Renewed      []string           `json:"renewed"`      // Policies moved onto their pending renewal term
+	RenewalsDue  []*RenewalReminder `json:"renewalsDue"`  // Policies ending within the renewal window
+	Bookmark     string             `json:"bookmark"`     // Last expiry index key examined, pass to continue
+	HasMorePages bool               `json:"hasMorePages"` // Whether further due policies remain
+}
+
+// ExpirePolicies moves overdue active policies to Expired, one page at a time.
+// Policies are visited in end date order through the expiry index, starting after
+// bookmark and stopping at the end of the renewal window, so a page reads only the
+// entries it examines. The transaction timestamp is the only clock used so every
+// endorser agrees.
+func (pc *PolicyChaincode) ExpirePolicies(ctx contractapi.TransactionContextInterface,
+	pageSize int, bookmark string, renewalWindowDays int) (*ExpirySweepResult, error) {
+
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+	if renewalWindowDays < 0 {
+		return nil, fmt.Errorf("renewal window days cannot be negative")
+	}
+	if bookmark != "" && (!strings.HasPrefix(bookmark, expiryKeyPrefix) || !utf8.ValidString(bookmark)) {
+		return nil, fmt.Errorf("invalid bookmark")
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	currentTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+	renewalCutoff := currentTime.AddDate(0, 0, renewalWindowDays)
+
+	// Keys after the timestamp start with "_", which sorts before "~"
+	startKey := expiryKeyPrefix
+	if bookmark != "" {
+		startKey = bookmark + "\x00"
+	}
+	endKey := expiryKeyPrefix + renewalCutoff.UTC().Format(expiryKeyLayout) + "~"
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, endKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expiry index: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	result := &ExpirySweepResult{
+		Expired:     []string{},
+		Renewed:     []string{},
+		RenewalsDue: []*RenewalReminder{},
+		Bookmark:    bookmark,
+	}
+
+	callerID, _ := ctx.GetClientIdentity().GetID()
+	exposure := exposureDeltas{}
+
+	for resultsIterator.HasNext() {
+		if result.Examined == pageSize {
+			result.HasMorePages = true
+			break
+		}
+
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		result.Examined++
+		result.Bookmark = queryResponse.Key
+
+		policy, err := expiryIndexPolicy(ctx, queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		// Entries for policies that left Active or changed end date are dropped
+		if policy == nil || policy.Status != "Active" || expiryKey(policy) != queryResponse.Key {
+			err = ctx.GetStub().DelState(queryResponse.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete expiry index entry: %v", err)
+			}
+			if policy != nil && policy.Status == "Active" {
+				err = indexPolicyExpiry(ctx, policy)
+				if err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		// A term renewed in advance starts instead of the policy expiring
+		if !currentTime.Before(policy.EndDate) && policy.PendingRenewal != nil {
+			err = ctx.GetStub().DelState(queryResponse.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete expiry index entry: %v", err)
+			}
+
+			claims, err := policyClaims(ctx, policy.PolicyID)
+			if err != nil {
+				return nil, err
+			}
+
+			term := policy.PendingRenewal.Term
+			err = pc.startRenewalTerm(ctx, policy, policy.PendingRenewal, claims, exposure, currentTime)
+			if err != nil {
+				return nil, err
+			}
+
+			err = pc.recordHistory(ctx, policy.PolicyID, "Renewed", callerID,
+				fmt.Sprintf("Term %s started at end date %s, running until %s",
+					term.TermID, term.StartDate.Format(time.RFC3339), term.EndDate.Format(time.RFC3339)))
+			if err != nil {
+				return nil, err
+			}
+
+			result.Renewed = append(result.Renewed, policy.PolicyID)
+			continue
+		}
+
+		if currentTime.After(policy.EndDate) {
+			policy.Status = "Expired"
+			policy.LastUpdated = currentTime
+
+			policyJSON, err := json.Marshal(policy)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal policy: %v", err)
+			}
+
+			err = ctx.GetStub().PutState(policy.PolicyID, policyJSON)
+			if err != nil {
+				return nil, fmt.Errorf("failed to expire policy %s: %v", policy.PolicyID, err)
+			}
+
+			err = ctx.GetStub().DelState(queryResponse.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete expiry index entry: %v", err)
+			}
+
+			err = pc.recordHistory(ctx, policy.PolicyID, "Expired", callerID,
+				fmt.Sprintf("Policy expired at end date %s", policy.EndDate.Format(time.RFC3339)))
+			if err != nil {
+				return nil, err
+			}
+
+			result.Expired = append(result.Expired, policy.PolicyID)
+			exposure.addPolicy(policy, -1)
+			continue
+		}
+
+		if renewalWindowDays > 0 && policy.PendingRenewal == nil {
+			result.RenewalsDue = append(result.RenewalsDue, &RenewalReminder{
+				PolicyID:      policy.PolicyID,
+				FarmerID:      policy.FarmerID,
+				CoopID:        policy.CoopID,
+				EndDate:       policy.EndDate,
+				DaysRemaining: int(policy.EndDate.Sub(currentTime).Hours() / 24),
+			})
+		}
+	}
+
+	err = pc.applyExposure(ctx, exposure, currentTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the premium pool's active policy counter in step
+	if len(result.Expired) > 0 {
+		_, err = invokeChaincode(ctx, premiumPoolChaincode, "AdjustActivePolicies",
+			strconv.Itoa(-len(result.Expired)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Fabric keeps a single event per transaction, so reminders are batched
+	if len(result.RenewalsDue) > 0 {
+		eventJSON, err := json.Marshal(result.RenewalsDue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal renewal reminders: %v", err)
+		}
+
+		err = ctx.GetStub().SetEvent("PolicyRenewalDue", eventJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to emit renewal event: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// IndexPolicyExpiry adds the given policies (a JSON array of policy IDs) to the expiry
+// index. Policies created before the index existed must be indexed once to be swept;
+// policies that are not active are skipped and indexing is idempotent.
+func (pc *PolicyChaincode) IndexPolicyExpiry(ctx contractapi.TransactionContextInterface,
+	policyIDsJSON string) (int, error) {
+
+	var policyIDs []string
+	if err := json.Unmarshal([]byte(policyIDsJSON), &policyIDs); err != nil {
+		return 0, fmt.Errorf("failed to parse policy IDs: %v", err)
+	}
+
+	indexed := 0
+	for _, policyID := range policyIDs {
+		policy, err := pc.GetPolicy(ctx, policyID)
+		if err != nil {
+			return 0, err
+		}
+		if policy.Status != "Active" {
+			continue
+		}
+
+		err = indexPolicyExpiry(ctx, policy)
+		if err != nil {
+			return 0, err
+		}
+		indexed++
+	}
+
+	return indexed, nil
+}
+
+// ========================================
+// POLICY HISTORY & AUDIT
+// ========================================
+
+// recordHistory logs a policy lifecycle event
+func (pc *PolicyChaincode) recordHistory(ctx contractapi.TransactionContextInterface,
+	policyID string, action string, performedBy string, details string) error {
+
+	// A single transaction may touch several policies or record several actions
+	historyID := fmt.Sprintf("%s_%s_%s", ctx.GetStub().GetTxID(), policyID, action)
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	history := PolicyHistory{
+		HistoryID:   historyID,
+		PolicyID:    policyID,
+		Action:      action,
+		Timestamp:   time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)),
+		PerformedBy: performedBy,
+		Details:     details,
+	}
+
+	historyJSON, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %v", err)
+	}
+
+	err = ctx.GetStub().PutState("HISTORY_"+historyID, historyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put history: %v", err)
+	}
+
+	return nil
+}
+
+// GetPolicyHistory retrieves all lifecycle events for a policy
+func (pc *PolicyChaincode) GetPolicyHistory(ctx contractapi.TransactionContextInterface,
+	policyID string) ([]*PolicyHistory, error) {
+
+	queryString := fmt.Sprintf(`{"selector":{"policyID":"%s","historyID":{"$exists":true}}}`, policyID)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policy history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var history []*PolicyHistory
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var record PolicyHistory
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, &record)
+	}
+
+	return history, nil
+}
+
+// ========================================
+// HELPER FUNCTIONS
+// ========================================
+
+// TemplateWindows holds the template fields the policy chaincode relies on
+type TemplateWindows struct {
+	WaitingDays    int `json:"waitingDays"`
+	CoolingOffDays int `json:"coolingOffDays"`
+}
+
+// getTemplateWindows reads the waiting period and cooling-off window from the template chaincode
+func getTemplateWindows(ctx contractapi.TransactionContextInterface, templateID string) (*TemplateWindows, error) {
+	templateJSON, err := invokeChaincode(ctx, policyTemplateChaincode, "GetTemplate", templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	var windows TemplateWindows
+	err = json.Unmarshal(templateJSON, &windows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal template: %v", err)
+	}
+
+	return &windows, nil
+}
+
+// recordWindows logs when a new policy's waiting period and cooling-off window end
+func (pc *PolicyChaincode) recordWindows(ctx contractapi.TransactionContextInterface,
+	policy *Policy, callerID string) error {
+
+	err := pc.recordHistory(ctx, policy.PolicyID, "WaitingPeriod", callerID,
+		fmt.Sprintf("Waiting period ends %s", policy.WaitingPeriodEnd.Format(time.RFC3339)))
+	if err != nil {
+		return err
+	}
+
+	return pc.recordHistory(ctx, policy.PolicyID, "CoolingOff", callerID,
+		fmt.Sprintf("Cooling-off window ends %s", policy.CoolingOffEnd.Format(time.RFC3339)))
+}
+
+// inCoolingOff reports whether a claim-free policy can still be cancelled for a full refund
+func inCoolingOff(policy *Policy, at time.Time) bool {
+	return policy.ClaimCount == 0 && at.Before(policy.CoolingOffEnd)
+}
+
+// policyCoversLocation matches grid cells when the policy has a coverage area
+func policyCoversLocation(policy *Policy, location string) bool {
+	if len(policy.GridCells) > 0 {
+		for _, cell := range policy.GridCells {
+			if cell == location {
+				return true
+			}
+		}
+		return false
+	}
+	return policy.FarmLocation == location
+}
+
+// putMasterPolicy stores a master policy
+func (pc *PolicyChaincode) putMasterPolicy(ctx contractapi.TransactionContextInterface, master *MasterPolicy) error {
+	masterJSON, err := json.Marshal(master)
+	if err != nil {
+		return fmt.Errorf("failed to marshal master policy: %v", err)
+	}
+
+	err = ctx.GetStub().PutState("MASTER_"+master.MasterPolicyID, masterJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put master policy: %v", err)
+	}
+
+	return nil
+}
+
+// putPolicyVersion stores a superseded set of policy terms
+func (pc *PolicyChaincode) putPolicyVersion(ctx contractapi.TransactionContextInterface, version *PolicyVersion) error {
+	versionKey, err := ctx.GetStub().CreateCompositeKey(policyVersionObjectType,
+		[]string{version.PolicyID, fmt.Sprintf("%06d", version.Version)})
+	if err != nil {
+		return fmt.Errorf("failed to create version key: %v", err)
+	}
+
+	versionJSON, err := json.Marshal(version)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy version: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(versionKey, versionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put policy version: %v", err)
+	}
+
+	return nil
+}
+
+// currentTerms extracts the policy's terms in force now
+func currentTerms(policy *Policy) CoverageTerms {
+	return CoverageTerms{
+		CoverageAmount: policy.CoverageAmount,
+		FarmSize:       policy.FarmSize,
+		CropType:       policy.CropType,
+		FarmLocation:   policy.FarmLocation,
+		TermPremium:    termPremium(policy),
+	}
+}
+
+// currentVersion treats policies created before versioning as version 1
+func currentVersion(policy *Policy) int {
+	if policy.Version == 0 {
+		return 1
+	}
+	return policy.Version
+}
+
+// versionDate returns when the current terms took effect
+func versionDate(policy *Policy) time.Time {
+	if policy.VersionDate.IsZero() {
+		return policy.StartDate
+	}
+	return policy.VersionDate
+}
+
+// termPremium falls back to the premium paid for policies created before versioning
+func termPremium(policy *Policy) float64 {
+	if policy.TermPremium == 0 {
+		return policy.PremiumAmount
+	}
+	return policy.TermPremium
+}
+
+// policyPayee returns the beneficiary if one is designated, else the farmer
+func policyPayee(policy *Policy) string {
+	if policy.Beneficiary != nil {
+		return policy.Beneficiary.BeneficiaryID
+	}
+	return policy.FarmerID
+}
+
+// verifyApproval checks that an approval-manager request authorises a policy function call.
+// The request must target this chaincode and function with the expected leading arguments,
+// be fully approved, and carry approvals from the MSP of every listed organization. Each
+// request authorises one change: it is recorded as used, so it cannot be replayed.
+func verifyApproval(ctx contractapi.TransactionContextInterface,
+	requestID string, functionName string, args []string, orgIDs ...string) error {
+
+	requestJSON, err := invokeChaincode(ctx, approvalManagerChaincode, "GetApprovalRequest", requestID)
+	if err != nil {
+		return err
+	}
+
+	var request struct {
+		ChaincodeName string          `json:"chaincodeName"`
+		FunctionName  string          `json:"functionName"`
+		Arguments     []string        `json:"arguments"`
+		Approvals     map[string]bool `json:"approvals"`
+		Status        string          `json:"status"`
+	}
+	err = json.Unmarshal(requestJSON, &request)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal approval request: %v", err)
+	}
+
+	if request.Status != "APPROVED" {
+		return fmt.Errorf("approval request %s is not approved (status: %s)", requestID, request.Status)
+	}
+	if request.ChaincodeName != "policy" || request.FunctionName != functionName {
+		return fmt.Errorf("approval request %s does not authorise %s", requestID, functionName)
+	}
+	if len(request.Arguments) < len(args) {
+		return fmt.Errorf("approval request %s does not match the requested arguments", requestID)
+	}
+	for i, arg := range args {
+		if request.Arguments[i] != arg {
+			return fmt.Errorf("approval request %s does not match the requested arguments", requestID)
+		}
+	}
+
+	for _, orgID := range orgIDs {
+		orgJSON, err := invokeChaincode(ctx, accessControlChaincode, "GetOrganization", orgID)
+		if err != nil {
+			return err
+		}
+
+		var org struct {
+			MSP    string `json:"msp"`
+			Status string `json:"status"`
+		}
+		err = json.Unmarshal(orgJSON, &org)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal organization: %v", err)
+		}
+
+		if org.Status != "Active" {
+			return fmt.Errorf("organization %s is not active", orgID)
+		}
+		if !request.Approvals[org.MSP] {
+			return fmt.Errorf("approval request %s lacks approval from %s (%s)", requestID, orgID, org.MSP)
+		}
+	}
+
+	used, err := ctx.GetStub().GetState(usedApprovalPrefix + requestID)
+	if err != nil {
+		return fmt.Errorf("failed to read approval usage: %v", err)
+	}
+	if used != nil {
+		return fmt.Errorf("approval request %s has already been used", requestID)
+	}
+
+	err = ctx.GetStub().PutState(usedApprovalPrefix+requestID, []byte(ctx.GetStub().GetTxID()))
+	if err != nil {
+		return fmt.Errorf("failed to record approval usage: %v", err)
+	}
+
+	return nil
+}
+
+// farmerDistrict looks up the administrative district of a farmer's farm
+func farmerDistrict(ctx contractapi.TransactionContextInterface, farmerID string) (string, error) {
+	farmerJSON, err := invokeChaincode(ctx, farmerChaincode, "GetFarmer", farmerID)
+	if err != nil {
+		return "", err
+	}
+
+	var farmer struct {
+		FarmLocation struct {
+			District string `json:"district"`
+		} `json:"farmLocation"`
+	}
+	err = json.Unmarshal(farmerJSON, &farmer)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal farmer: %v", err)
+	}
+
+	return farmer.FarmLocation.District, nil
+}
+
+// memberDistrict checks that a farmer is an active, verified member of a cooperative
+// and returns the district of their farm
+func memberDistrict(ctx contractapi.TransactionContextInterface, farmerID string, coopID string) (string, error) {
+	farmerJSON, err := invokeChaincode(ctx, farmerChaincode, "GetFarmer", farmerID)
+	if err != nil {
+		return "", err
+	}
+
+	var farmer struct {
+		CoopID       string `json:"coopID"`
+		Status       string `json:"status"`
+		KYCVerified  bool   `json:"kycVerified"`
+		FarmLocation struct {
+			District string `json:"district"`
+		} `json:"farmLocation"`
+	}
+	err = json.Unmarshal(farmerJSON, &farmer)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal farmer: %v", err)
+	}
+
+	if farmer.CoopID != coopID {
+		return "", fmt.Errorf("farmer %s is not a member of coop %s", farmerID, coopID)
+	}
+	if farmer.Status != "Active" {
+		return "", fmt.Errorf("farmer %s is not active", farmerID)
+	}
+	if !farmer.KYCVerified {
+		return "", fmt.Errorf("farmer %s KYC not verified", farmerID)
+	}
+
+	return farmer.FarmLocation.District, nil
+}
+
+// unindexPolicyCells removes a policy's entries from the grid cell index
+func unindexPolicyCells(ctx contractapi.TransactionContextInterface, policy *Policy) error {
+	for _, cell := range policy.GridCells {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(policyCellObjectType, []string{cell, policy.PolicyID})
+		if err != nil {
+			return fmt.Errorf("failed to create cell key: %v", err)
+		}
+		err = ctx.GetStub().DelState(indexKey)
+		if err != nil {
+			return fmt.Errorf("failed to remove cell index: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// premiumQuote is the part of a policy-template premium breakdown a policy records
+type premiumQuote struct {
+	Premium          float64 `json:"premium"`
+	District         string  `json:"district"`
+	RateTableVersion int     `json:"rateTableVersion"`
+	RegionalRate     *struct {
+		RiskLoading float64 `json:"riskLoading"`
+		BaselineID  string  `json:"baselineID"`
+	} `json:"regionalRate"`
+}
+
+// riskLoading returns the regional loading priced into the quote, 0 if none
+func (q *premiumQuote) riskLoading() float64 {
+	if q.RegionalRate == nil {
+		return 0
+	}
+	return q.RegionalRate.RiskLoading
+}
+
+// baselineID returns the baseline referenced by the regional rate, if any
+func (q *premiumQuote) baselineID() string {
+	if q.RegionalRate == nil {
+		return ""
+	}
+	return q.RegionalRate.BaselineID
+}
+
+// quotePremium prices a farmer's cover with the template, applying the farmer's district loading
+func quotePremium(ctx contractapi.TransactionContextInterface, templateID string, farmerID string,
+	coverageAmount float64, farmSize float64, claimFreeYears int) (*premiumQuote, error) {
+
+	breakdownJSON, err := invokeChaincode(ctx, policyTemplateChaincode, "CalculatePremium", templateID, farmerID,
+		strconv.FormatFloat(coverageAmount, 'f', -1, 64),
+		strconv.FormatFloat(farmSize, 'f', -1, 64),
+		strconv.Itoa(claimFreeYears))
+	if err != nil {
+		return nil, err
+	}
+
+	var quote premiumQuote
+	err = json.Unmarshal(breakdownJSON, &quote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal premium breakdown: %v", err)
+	}
+
+	return &quote, nil
+}
+
+// policyClaims fetches a policy's claims from the claim processor
+func policyClaims(ctx contractapi.TransactionContextInterface, policyID string) ([]*termClaim, error) {
+	claimsJSON, err := invokeChaincode(ctx, claimProcessorChaincode, "GetClaimsByPolicy", policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A policy without claims comes back as an empty payload
+	var claims []*termClaim
+	if len(claimsJSON) > 0 {
+		err = json.Unmarshal(claimsJSON, &claims)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal claims: %v", err)
+		}
+	}
+
+	return claims, nil
+}
+
+// putPolicyTerm stores a closed renewal term
+func (pc *PolicyChaincode) putPolicyTerm(ctx contractapi.TransactionContextInterface, term *PolicyTerm) error {
+	termKey, err := ctx.GetStub().CreateCompositeKey(policyTermObjectType,
+		[]string{term.PolicyID, fmt.Sprintf("%04d", term.TermNumber)})
+	if err != nil {
+		return fmt.Errorf("failed to create term key: %v", err)
+	}
+
+	termJSON, err := json.Marshal(term)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy term: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(termKey, termJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put policy term: %v", err)
+	}
+
+	return nil
+}
+
+// initialTermID names a policy's inception term
+func initialTermID(policyID string) string {
+	return policyID + "_T1"
+}
+
+// currentTermID treats policies created before renewal terms as being in their inception term
+func currentTermID(policy *Policy) string {
+	if policy.TermID == "" {
+		return initialTermID(policy.PolicyID)
+	}
+	return policy.TermID
+}
+
+// currentTermNumber treats policies created before renewal terms as term 1
+func currentTermNumber(policy *Policy) int {
+	if policy.TermNumber == 0 {
+		return 1
+	}
+	return policy.TermNumber
+}
+
+// termStart returns when the current term began
+func termStart(policy *Policy) time.Time {
+	if policy.TermStartDate.IsZero() {
+		return policy.StartDate
+	}
+	return policy.TermStartDate
+}
+
+// claimInTerm attributes a non-rejected claim to a term by term ID, or by trigger date for older claims
+func claimInTerm(claim *termClaim, term *PolicyTerm) bool {
+	if claim.Status == "Rejected" {
+		return false
+	}
+	if claim.TermID != "" {
+		return claim.TermID == term.TermID
+	}
+	return !claim.TriggerDate.Before(term.StartDate) && claim.TriggerDate.Before(term.EndDate)
+}
+
+// expiryKey is a policy's entry in the expiry index
+func expiryKey(policy *Policy) string {
+	return expiryKeyPrefix + policy.EndDate.UTC().Format(expiryKeyLayout) + "_" + policy.PolicyID
+}
+
+// indexPolicyExpiry records an active policy in the expiry index
+func indexPolicyExpiry(ctx contractapi.TransactionContextInterface, policy *Policy) error {
+	err := ctx.GetStub().PutState(expiryKey(policy), []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to index policy expiry: %v", err)
+	}
+	return nil
+}
+
+// expiryIndexPolicy loads the policy an expiry index entry refers to, or nil if the
+// entry is malformed or the policy no longer exists
+func expiryIndexPolicy(ctx contractapi.TransactionContextInterface, key string) (*Policy, error) {
+	idStart := len(expiryKeyPrefix) + len(expiryKeyLayout) + 1
+	if len(key) <= idStart {
+		return nil, nil
+	}
+
+	policyJSON, err := ctx.GetStub().GetState(key[idStart:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %v", err)
+	}
+	if policyJSON == nil {
+		return nil, nil
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(policyJSON, &policy); err != nil {
+		return nil, nil
+	}
+
+	return &policy, nil
+}
+
+// invokeChaincode calls a function on another chaincode in the same channel
+func invokeChaincode(ctx contractapi.TransactionContextInterface,
+	chaincodeName string, functionName string, args ...string) ([]byte, error) {
+
+	invokeArgs := make([][]byte, len(args)+1)
+	invokeArgs[0] = []byte(functionName)
+	for i, arg := range args {
+		invokeArgs[i+1] = []byte(arg)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(chaincodeName, invokeArgs, ctx.GetStub().GetChannelID())
+	if response.Status != 200 {
+		return nil, fmt.Errorf("%s.%s failed: %s", chaincodeName, functionName, response.Message)
+	}
+
+	return response.Payload, nil
+}
 
 func (pc *PolicyChaincode) policyExists(ctx contractapi.TransactionContextInterface, policyID string) (bool, error) {
 	policyJSON, err := ctx.GetStub().GetState(policyID)
@@ -3,11 +3,18 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
 )
 
+// policyChaincode is the only chaincode that may adjust the active policy counter
+const policyChaincode = "policy"
+
 // PremiumPoolChaincode manages treasury and financial operations
 type PremiumPoolChaincode struct {
 	contractapi.Contract
@@ -27,7 +34,7 @@ type PremiumPool struct {
 // Transaction represents a financial transaction
 type Transaction struct {
 	TxID          string    `json:"txID"`          // Unique transaction ID
-	Type          string    `json:"type"`          // Premium, Payout, Contribution, Withdrawal
+	Type          string    `json:"type"`          // Premium, Payout, Refund, Contribution, Withdrawal
 	FarmerID      string    `json:"farmerID"`      // Associated farmer (if applicable)
 	PolicyID      string    `json:"policyID"`      // Associated policy (if applicable)
 	Amount        float64   `json:"amount"`        // Transaction amount
@@ -122,6 +129,102 @@ func (pp *PremiumPoolChaincode) DepositPremium(ctx contractapi.TransactionContex
 	return nil
 }
 
+// RefundPremium returns a premium in full, reversing its deposit. It is only callable
+// from transactions submitted to the policy chaincode.
+func (pp *PremiumPoolChaincode) RefundPremium(ctx contractapi.TransactionContextInterface,
+	txID string, farmerID string, policyID string, amount float64) error {
+
+	if err := verifyInvokedThrough(ctx, policyChaincode); err != nil {
+		return err
+	}
+
+	if amount <= 0 {
+		return fmt.Errorf("refund amount must be positive")
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	existing, err := ctx.GetStub().GetState("TX_" + txID)
+	if err != nil {
+		return fmt.Errorf("failed to read transaction: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("transaction %s already exists", txID)
+	}
+
+	// Only premium actually deposited for the policy can be refunded
+	paid, err := pp.policyPremiumPaid(ctx, policyID)
+	if err != nil {
+		return err
+	}
+	if paid < amount {
+		return fmt.Errorf("policy %s has %.2f in deposited premium, cannot refund %.2f", policyID, paid, amount)
+	}
+
+	pool, err := pp.getPool(ctx)
+	if err != nil {
+		return fmt.Errorf("pool not initialized: %v", err)
+	}
+
+	if pool.TotalBalance < amount {
+		return fmt.Errorf("insufficient pool balance: have %.2f, need %.2f", pool.TotalBalance, amount)
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	transaction := Transaction{
+		TxID:          txID,
+		Type:          "Refund",
+		FarmerID:      farmerID,
+		PolicyID:      policyID,
+		Amount:        amount,
+		BalanceBefore: pool.TotalBalance,
+		BalanceAfter:  pool.TotalBalance - amount,
+		Status:        "Completed",
+		Timestamp:     timestamp,
+		InitiatedBy:   callerID,
+		Notes:         fmt.Sprintf("Premium refund for policy %s", policyID),
+	}
+
+	txJSON, err := json.Marshal(transaction)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transaction: %v", err)
+	}
+
+	err = ctx.GetStub().PutState("TX_"+txID, txJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store transaction: %v", err)
+	}
+
+	pool.TotalBalance -= amount
+	pool.TotalPremiums -= amount
+	pool.ActivePolicies--
+	if pool.ActivePolicies < 0 {
+		pool.ActivePolicies = 0
+	}
+	pool.LastUpdated = timestamp
+
+	poolJSON, err := json.Marshal(pool)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pool: %v", err)
+	}
+
+	err = ctx.GetStub().PutState("POOL_MAIN", poolJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update pool: %v", err)
+	}
+
+	return nil
+}
+
 // ========================================
 // PAYOUT EXECUTION
 // ========================================
@@ -330,6 +433,49 @@ func (pp *PremiumPoolChaincode) RecordContribution(ctx contractapi.TransactionCo
 	return nil
 }
 
+// AdjustActivePolicies applies a signed delta to the active policy counter. It is
+// only callable from transactions submitted to the policy chaincode.
+func (pp *PremiumPoolChaincode) AdjustActivePolicies(ctx contractapi.TransactionContextInterface,
+	delta int) error {
+
+	if err := verifyInvokedThrough(ctx, policyChaincode); err != nil {
+		return err
+	}
+
+	if delta == 0 {
+		return nil
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	pool, err := pp.getPool(ctx)
+	if err != nil {
+		return err
+	}
+
+	pool.ActivePolicies += delta
+	if pool.ActivePolicies < 0 {
+		pool.ActivePolicies = 0
+	}
+	pool.LastUpdated = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	poolJSON, err := json.Marshal(pool)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pool: %v", err)
+	}
+
+	err = ctx.GetStub().PutState("POOL_MAIN", poolJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update active policies: %v", err)
+	}
+
+	return nil
+}
+
 // ========================================
 // QUERIES & REPORTING
 // ========================================
@@ -384,6 +530,13 @@ func (pp *PremiumPoolChaincode) GetTransactionHistory(ctx contractapi.Transactio
 	return transactions, nil
 }
 
+// GetPolicyPremiumPaid returns the premium deposited for a policy net of refunds
+func (pp *PremiumPoolChaincode) GetPolicyPremiumPaid(ctx contractapi.TransactionContextInterface,
+	policyID string) (float64, error) {
+
+	return pp.policyPremiumPaid(ctx, policyID)
+}
+
 // GetAllTransactionHistory queries all payment records (for admin/audit view)
 func (pp *PremiumPoolChaincode) GetAllTransactionHistory(ctx contractapi.TransactionContextInterface) ([]*Transaction, error) {
 	// Use GetStateByPartialCompositeKey with "TX_" prefix to get only transaction records
@@ -477,6 +630,8 @@ func (pp *PremiumPoolChaincode) GetFarmerBalance(ctx contractapi.TransactionCont
 
 		if tx.Type == "Premium" {
 			totalPremiums += tx.Amount
+		} else if tx.Type == "Refund" {
+			totalPremiums -= tx.Amount
 		} else if tx.Type == "Payout" {
 			totalPayouts += tx.Amount
 		}
@@ -510,6 +665,90 @@ func (pp *PremiumPoolChaincode) getPool(ctx contractapi.TransactionContextInterf
 	return &pool, nil
 }
 
+// policyPremiumPaid sums a policy's completed premium deposits less its refunds
+func (pp *PremiumPoolChaincode) policyPremiumPaid(ctx contractapi.TransactionContextInterface,
+	policyID string) (float64, error) {
+
+	selector, err := json.Marshal(map[string]interface{}{
+		"selector": map[string]interface{}{
+			"policyID": policyID,
+			"type":     map[string]interface{}{"$in": []string{"Premium", "Refund"}},
+			"status":   "Completed",
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to build premium query: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(selector))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query premium transactions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var transactions []*Transaction
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+
+		var tx Transaction
+		err = json.Unmarshal(queryResponse.Value, &tx)
+		if err != nil {
+			return 0, err
+		}
+		transactions = append(transactions, &tx)
+	}
+
+	// Sum in a fixed order so every endorser computes the same total
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].TxID < transactions[j].TxID })
+
+	paid := 0.0
+	for _, tx := range transactions {
+		if tx.Type == "Refund" {
+			paid -= tx.Amount
+		} else {
+			paid += tx.Amount
+		}
+	}
+
+	return paid, nil
+}
+
+// verifyInvokedThrough checks that the transaction proposal was submitted to the named
+// chaincode, so functions meant for chaincode-to-chaincode calls cannot be invoked
+// directly by clients
+func verifyInvokedThrough(ctx contractapi.TransactionContextInterface, chaincodeName string) error {
+	signedProposal, err := ctx.GetStub().GetSignedProposal()
+	if err != nil || signedProposal == nil {
+		return fmt.Errorf("failed to get signed proposal: %v", err)
+	}
+
+	var proposal peer.Proposal
+	if err := proto.Unmarshal(signedProposal.ProposalBytes, &proposal); err != nil {
+		return fmt.Errorf("failed to unmarshal proposal: %v", err)
+	}
+	var header common.Header
+	if err := proto.Unmarshal(proposal.Header, &header); err != nil {
+		return fmt.Errorf("failed to unmarshal proposal header: %v", err)
+	}
+	var channelHeader common.ChannelHeader
+	if err := proto.Unmarshal(header.ChannelHeader, &channelHeader); err != nil {
+		return fmt.Errorf("failed to unmarshal channel header: %v", err)
+	}
+	var extension peer.ChaincodeHeaderExtension
+	if err := proto.Unmarshal(channelHeader.Extension, &extension); err != nil {
+		return fmt.Errorf("failed to unmarshal chaincode header extension: %v", err)
+	}
+
+	if extension.ChaincodeId == nil || extension.ChaincodeId.Name != chaincodeName {
+		return fmt.Errorf("may only be called through the %s chaincode", chaincodeName)
+	}
+
+	return nil
+}
+
 // ========================================
 // MAIN
 // ========================================
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// WeatherBatchPayload is the canonical batch of station readings an oracle signs. As
+// with WeatherPayload, the signed bytes must be the JSON encoding of this struct exactly.
+type WeatherBatchPayload struct {
+	OracleID  string              `json:"oracleID"`
+	StationID string              `json:"stationID"`
+	Readings  []BatchReadingInput `json:"readings"`
+}
+
+// BatchReadingInput is one timestamped reading in a batch
+type BatchReadingInput struct {
+	ObservationTime string  `json:"observationTime"` // RFC3339
+	Rainfall        float64 `json:"rainfall"`
+	Temperature     float64 `json:"temperature"`
+	Humidity        float64 `json:"humidity"`
+	WindSpeed       float64 `json:"windSpeed"`
+}
+
+// WeatherSeries holds one station's batched readings for one UTC day
+type WeatherSeries struct {
+	StationID   string          `json:"stationID"`   // Station that observed the readings
+	OracleID    string          `json:"oracleID"`    // Oracle that submitted them
+	Day         string          `json:"day"`         // UTC day, YYYY-MM-DD
+	Readings    []SeriesReading `json:"readings"`    // Readings in observation time order
+	Batches     []SeriesBatch   `json:"batches"`     // Batches that contributed readings
+	LastUpdated time.Time       `json:"lastUpdated"` // Last append
+
+	Status          string             `json:"status"`               // Pending, Reconciled, Anomalous; appends reset it to Pending
+	ReconciledRound string             `json:"reconciledRound"`      // Consensus record the day was last checked against
+	Deviations      map[string]float64 `json:"deviations,omitempty"` // Per-metric distance of the day from that consensus
+	ReconciledAt    time.Time          `json:"reconciledAt"`         // When the day was last checked
+}
+
+// SeriesReading is one stored reading of a station series
+type SeriesReading struct {
+	Time        time.Time `json:"time"`        // Observation time, UTC
+	Rainfall    float64   `json:"rainfall"`    // Rainfall in mm over the interval
+	Temperature float64   `json:"temperature"` // Temperature in Celsius
+	Humidity    float64   `json:"humidity"`    // Humidity percentage
+	WindSpeed   float64   `json:"windSpeed"`   // Wind speed in km/h
+}
+
+// SeriesBatch records the signed batch a series append came from
+type SeriesBatch struct {
+	BatchHash      string    `json:"batchHash"`      // SHA-256 of the signed batch payload
+	Signature      string    `json:"signature"`      // Oracle's base64 signature over the payload
+	KeyFingerprint string    `json:"keyFingerprint"` // Fingerprint of the key that verified it
+	SubmittedBy    string    `json:"submittedBy"`    // Oracle submitter identity
+	SubmittedAt    time.Time `json:"submittedAt"`    // Transaction time of the append
+	Readings       int       `json:"readings"`       // Readings the batch added to this day
+}
+
+// SeriesPoint is a reading, or an aggregate of readings, returned by a series query
+type SeriesPoint struct {
+	Time        time.Time `json:"time"`        // Observation time, or start of the interval
+	Rainfall    float64   `json:"rainfall"`    // Total rainfall in mm
+	Temperature float64   `json:"temperature"` // Mean temperature in Celsius
+	Humidity    float64   `json:"humidity"`    // Mean humidity percentage
+	WindSpeed   float64   `json:"windSpeed"`   // Mean wind speed in km/h
+	Count       int       `json:"count"`       // Readings aggregated into the point
+}
+
+// Series storage settings
+const (
+	stationSeriesObjectType = "StationSeries" // station ID, UTC day
+	maxBatchReadings        = 500             // Readings one batch may carry
+	maxSeriesQueryDays      = 366             // Longest range a series query may span
+)
+
+// ========================================
+// BATCH INGESTION
+// ========================================
+
+// SubmitWeatherBatch records a signed batch of timestamped readings from one registered
+// station. payload is the canonical WeatherBatchPayload JSON and signature its base64
+// signature by the oracle's registered key. Each reading is validated as a single
+// submission would be and appended to the station's series for its UTC day; batch
+// readings accumulate like hourly readings and do not join consensus rounds. A day's
+// series stays Pending, and out of interpolation, until ReconcileStationSeries checks it
+// against the consensus for its grid cell.
+func (wo *WeatherOracleChaincode) SubmitWeatherBatch(ctx contractapi.TransactionContextInterface,
+	payload string, signature string) error {
+
+	batch, err := parseWeatherBatch(payload)
+	if err != nil {
+		return err
+	}
+
+	oracle, err := wo.GetOracleProvider(ctx, batch.OracleID)
+	if err != nil {
+		return fmt.Errorf("oracle not found: %v", err)
+	}
+	if oracle.Status != "Active" {
+		return fmt.Errorf("oracle %s is not active", batch.OracleID)
+	}
+	if err := checkSubmitter(ctx, oracle); err != nil {
+		return err
+	}
+	if err := verifyOracleSignature(oracle, []byte(payload), signature); err != nil {
+		return err
+	}
+	payloadHash := sha256.Sum256([]byte(payload))
+
+	station, err := wo.GetStation(ctx, batch.StationID)
+	if err != nil {
+		return err
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	config, err := wo.GetConsensusConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Validate every reading and group them by UTC day
+	byDay := make(map[string][]SeriesReading)
+	seen := make(map[int64]bool)
+	for i, input := range batch.Readings {
+		reading := &WeatherPayload{
+			OracleID:        batch.OracleID,
+			StationID:       batch.StationID,
+			Location:        station.GridCell,
+			Latitude:        station.Latitude,
+			Longitude:       station.Longitude,
+			ObservationTime: input.ObservationTime,
+			Period:          "Hourly",
+			Rainfall:        input.Rainfall,
+			Temperature:     input.Temperature,
+			Humidity:        input.Humidity,
+			WindSpeed:       input.WindSpeed,
+		}
+		if err := validateReading(reading); err != nil {
+			return fmt.Errorf("reading %d: %v", i, err)
+		}
+		observed, err := observationTime(reading, config, timestamp)
+		if err != nil {
+			return fmt.Errorf("reading %d: %v", i, err)
+		}
+		if err := wo.checkStationReading(ctx, reading, observed); err != nil {
+			return fmt.Errorf("reading %d: %v", i, err)
+		}
+		if seen[observed.UnixNano()] {
+			return fmt.Errorf("reading %d: duplicate observation time %s", i, input.ObservationTime)
+		}
+		seen[observed.UnixNano()] = true
+
+		day := observed.Format(dailyBucketLayout)
+		byDay[day] = append(byDay[day], SeriesReading{
+			Time:        observed,
+			Rainfall:    input.Rainfall,
+			Temperature: input.Temperature,
+			Humidity:    input.Humidity,
+			WindSpeed:   input.WindSpeed,
+		})
+	}
+
+	// Append to each day's series once, in day order
+	days := make([]string, 0, len(byDay))
+	for day := range byDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	for _, day := range days {
+		series, err := stationSeries(ctx, batch.StationID, day)
+		if err != nil {
+			return err
+		}
+		if series == nil {
+			series = &WeatherSeries{StationID: batch.StationID, OracleID: batch.OracleID, Day: day}
+		}
+
+		recorded := make(map[int64]bool, len(series.Readings))
+		for _, existing := range series.Readings {
+			recorded[existing.Time.UnixNano()] = true
+		}
+		for _, reading := range byDay[day] {
+			if recorded[reading.Time.UnixNano()] {
+				return fmt.Errorf("station %s already has a reading at %s",
+					batch.StationID, reading.Time.Format(time.RFC3339))
+			}
+		}
+
+		series.Readings = append(series.Readings, byDay[day]...)
+		sort.Slice(series.Readings, func(i, j int) bool {
+			return series.Readings[i].Time.Before(series.Readings[j].Time)
+		})
+		series.Batches = append(series.Batches, SeriesBatch{
+			BatchHash:      hex.EncodeToString(payloadHash[:]),
+			Signature:      signature,
+			KeyFingerprint: oracle.KeyFingerprint,
+			SubmittedBy:    callerID,
+			SubmittedAt:    timestamp,
+			Readings:       len(byDay[day]),
+		})
+		series.LastUpdated = timestamp
+
+		// New readings change the day, so any earlier reconciliation no longer holds
+		series.Status = "Pending"
+		series.ReconciledRound = ""
+		series.Deviations = nil
+
+		if err := putStationSeries(ctx, series); err != nil {
+			return err
+		}
+	}
+
+	// Update oracle's last submission time
+	oracle.LastSubmission = timestamp
+	oracleJSON, err := json.Marshal(oracle)
+	if err != nil {
+		return fmt.Errorf("failed to marshal oracle: %v", err)
+	}
+
+	err = ctx.GetStub().PutState("ORACLE_"+batch.OracleID, oracleJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update oracle: %v", err)
+	}
+
+	return nil
+}
+
+// ========================================
+// TIME-SERIES QUERIES
+// ========================================
+
+// GetStationSeries returns a station's batched readings observed from startTime up to
+// but excluding endTime (RFC3339). With intervalMinutes 0 every reading is returned;
+// otherwise readings are downsampled into intervals aligned to UTC midnight, summing
+// rainfall and averaging the other metrics. intervalMinutes must divide a day evenly.
+func (wo *WeatherOracleChaincode) GetStationSeries(ctx contractapi.TransactionContextInterface,
+	stationID string, startTime string, endTime string, intervalMinutes int) ([]*SeriesPoint, error) {
+
+	start, err := time.Parse(time.RFC3339, startTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time: %v", err)
+	}
+	end, err := time.Parse(time.RFC3339, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end time: %v", err)
+	}
+	start, end = start.UTC(), end.UTC()
+	if !end.After(start) {
+		return nil, fmt.Errorf("end time must be after start time")
+	}
+	if end.Sub(start) > maxSeriesQueryDays*24*time.Hour {
+		return nil, fmt.Errorf("series range cannot exceed %d days", maxSeriesQueryDays)
+	}
+	if intervalMinutes < 0 || intervalMinutes > 1440 || (intervalMinutes > 0 && 1440%intervalMinutes != 0) {
+		return nil, fmt.Errorf("interval must be 0 or a divisor of 1440 minutes")
+	}
+
+	if _, err := wo.GetStation(ctx, stationID); err != nil {
+		return nil, err
+	}
+
+	points := []*SeriesPoint{}
+	var current *SeriesPoint
+	firstDay := start.Truncate(24 * time.Hour)
+	for day := firstDay; day.Before(end); day = day.AddDate(0, 0, 1) {
+		series, err := stationSeries(ctx, stationID, day.Format(dailyBucketLayout))
+		if err != nil {
+			return nil, err
+		}
+		if series == nil {
+			continue
+		}
+
+		for _, reading := range series.Readings {
+			if reading.Time.Before(start) || !reading.Time.Before(end) {
+				continue
+			}
+
+			pointTime := reading.Time
+			if intervalMinutes > 0 {
+				pointTime = day.Add(reading.Time.Sub(day).Truncate(time.Duration(intervalMinutes) * time.Minute))
+			}
+			if current == nil || !current.Time.Equal(pointTime) || intervalMinutes == 0 {
+				current = &SeriesPoint{Time: pointTime}
+				points = append(points, current)
+			}
+			current.Rainfall += reading.Rainfall
+			current.Temperature += reading.Temperature
+			current.Humidity += reading.Humidity
+			current.WindSpeed += reading.WindSpeed
+			current.Count++
+		}
+	}
+
+	for _, point := range points {
+		n := float64(point.Count)
+		point.Temperature /= n
+		point.Humidity /= n
+		point.WindSpeed /= n
+	}
+
+	return points, nil
+}
+
+// GetStationSeriesDay returns a station's stored series for one UTC day (YYYY-MM-DD),
+// including the batches it was built from
+func (wo *WeatherOracleChaincode) GetStationSeriesDay(ctx contractapi.TransactionContextInterface,
+	stationID string, day string) (*WeatherSeries, error) {
+
+	if _, err := time.Parse(dailyBucketLayout, day); err != nil {
+		return nil, fmt.Errorf("invalid day: %v", err)
+	}
+
+	series, err := stationSeries(ctx, stationID, day)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		return nil, fmt.Errorf("station %s has no series for %s", stationID, day)
+	}
+
+	return series, nil
+}
+
+// ========================================
+// SERIES RECONCILIATION
+// ========================================
+
+// ReconcileStationSeries checks a station's batched series for a completed UTC day
+// (YYYY-MM-DD) against the daily consensus round of the station's grid cell. The day is
+// Reconciled when its rainfall total and mean temperature, humidity and wind speed all
+// lie within the consensus tolerances, and Anomalous otherwise. Only Reconciled days are
+// used for interpolation, and so for payouts. The round must have closed with a quorum.
+func (wo *WeatherOracleChaincode) ReconcileStationSeries(ctx contractapi.TransactionContextInterface,
+	stationID string, day string) (*WeatherSeries, error) {
+
+	dayStart, err := time.Parse(dailyBucketLayout, day)
+	if err != nil {
+		return nil, fmt.Errorf("invalid day: %v", err)
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+	if timestamp.Before(dayStart.AddDate(0, 0, 1)) {
+		return nil, fmt.Errorf("day %s has not ended", day)
+	}
+
+	station, err := wo.GetStation(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+	series, err := stationSeries(ctx, stationID, day)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil || len(series.Readings) == 0 {
+		return nil, fmt.Errorf("station %s has no series for %s", stationID, day)
+	}
+
+	record, err := wo.GetConsensusData(ctx, station.GridCell, day)
+	if err != nil {
+		return nil, fmt.Errorf("no closed daily round for %s on %s: %v", station.GridCell, day, err)
+	}
+	if !record.ConsensusReached {
+		return nil, fmt.Errorf("daily round for %s on %s closed without consensus", station.GridCell, day)
+	}
+
+	config, err := wo.GetConsensusConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values := seriesDayValues(series)
+	series.Status = "Reconciled"
+	series.Deviations = make(map[string]float64)
+	for _, metric := range consensusMetrics {
+		consensus := record.Consensus[metric]
+		tolerance := config.Tolerances[metric]
+		allowed := math.Max(tolerance.Absolute, tolerance.Relative*math.Abs(consensus))
+		series.Deviations[metric] = math.Abs(values.metric(metric) - consensus)
+		if series.Deviations[metric] > allowed {
+			series.Status = "Anomalous"
+		}
+	}
+	series.ReconciledRound = record.RecordID
+	series.ReconciledAt = timestamp
+
+	if err := putStationSeries(ctx, series); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// ========================================
+// SERIES HELPER FUNCTIONS
+// ========================================
+
+// parseWeatherBatch decodes a signed batch and insists it is in canonical form
+func parseWeatherBatch(payload string) (*WeatherBatchPayload, error) {
+	var batch WeatherBatchPayload
+	decoder := json.NewDecoder(bytes.NewReader([]byte(payload)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&batch); err != nil {
+		return nil, fmt.Errorf("failed to parse weather batch: %v", err)
+	}
+
+	canonical, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal weather batch: %v", err)
+	}
+	if string(canonical) != payload {
+		return nil, fmt.Errorf("weather batch is not canonical; expected %s", canonical)
+	}
+
+	if batch.OracleID == "" || batch.StationID == "" {
+		return nil, fmt.Errorf("oracleID and stationID are required")
+	}
+	if len(batch.Readings) == 0 {
+		return nil, fmt.Errorf("batch has no readings")
+	}
+	if len(batch.Readings) > maxBatchReadings {
+		return nil, fmt.Errorf("batch cannot exceed %d readings", maxBatchReadings)
+	}
+
+	return &batch, nil
+}
+
+// seriesDayValues combines a day's series like hourly readings: rainfall summed, the
+// other metrics averaged
+func seriesDayValues(series *WeatherSeries) *stationDay {
+	var total stationDay
+	for _, reading := range series.Readings {
+		total.Rainfall += reading.Rainfall
+		total.Temperature += reading.Temperature
+		total.Humidity += reading.Humidity
+		total.WindSpeed += reading.WindSpeed
+	}
+	n := float64(len(series.Readings))
+	return &stationDay{total.Rainfall, total.Temperature / n, total.Humidity / n, total.WindSpeed / n}
+}
+
+// stationSeries loads a station's series for a UTC day, or nil if it has none
+func stationSeries(ctx contractapi.TransactionContextInterface, stationID string, day string) (*WeatherSeries, error) {
+	seriesKey, err := ctx.GetStub().CreateCompositeKey(stationSeriesObjectType, []string{stationID, day})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create series key: %v", err)
+	}
+
+	seriesJSON, err := ctx.GetStub().GetState(seriesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read series: %v", err)
+	}
+	if seriesJSON == nil {
+		return nil, nil
+	}
+
+	var series WeatherSeries
+	err = json.Unmarshal(seriesJSON, &series)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal series: %v", err)
+	}
+
+	return &series, nil
+}
+
+// putStationSeries stores a station's series for its day
+func putStationSeries(ctx contractapi.TransactionContextInterface, series *WeatherSeries) error {
+	seriesKey, err := ctx.GetStub().CreateCompositeKey(stationSeriesObjectType, []string{series.StationID, series.Day})
+	if err != nil {
+		return fmt.Errorf("failed to create series key: %v", err)
+	}
+
+	seriesJSON, err := json.Marshal(series)
+	if err != nil {
+		return fmt.Errorf("failed to marshal series: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(seriesKey, seriesJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put series: %v", err)
+	}
+
+	return nil
+}
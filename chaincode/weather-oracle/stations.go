@@ -0,0 +1,678 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"geogrid"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// WeatherStation is a registered observation point owned by an oracle
+type WeatherStation struct {
+	StationID      string    `json:"stationID"`      // Unique station identifier
+	Name           string    `json:"name"`           // Descriptive name
+	Latitude       float64   `json:"latitude"`       // Latitude coordinate
+	Longitude      float64   `json:"longitude"`      // Longitude coordinate
+	Elevation      float64   `json:"elevation"`      // Metres above sea level
+	GridCell       string    `json:"gridCell"`       // Grid cell containing the station
+	OracleID       string    `json:"oracleID"`       // Oracle that reports for the station
+	ActiveFrom     time.Time `json:"activeFrom"`     // First day the station reports
+	ActiveTo       time.Time `json:"activeTo"`       // Day the station stopped reporting, zero while active
+	RegisteredBy   string    `json:"registeredBy"`   // Identity that registered the station
+	RegisteredDate time.Time `json:"registeredDate"` // Registration timestamp
+}
+
+// StationDistance is a station found near a point
+type StationDistance struct {
+	Station    *WeatherStation `json:"station"`    // Station details
+	DistanceKm float64         `json:"distanceKm"` // Great-circle distance from the point
+}
+
+// InterpolatedDay is one day of weather estimated at a point from nearby stations
+type InterpolatedDay struct {
+	Date         time.Time `json:"date"`         // UTC day
+	HasData      bool      `json:"hasData"`      // Whether any station in range reported
+	Corroborated bool      `json:"corroborated"` // Stations of enough independent oracles agreed
+	Rainfall     float64   `json:"rainfall"`     // Daily rainfall in mm
+	Temperature  float64   `json:"temperature"`  // Mean temperature in Celsius
+	Humidity     float64   `json:"humidity"`     // Mean humidity percentage
+	WindSpeed    float64   `json:"windSpeed"`    // Mean wind speed in km/h
+	Stations     []string  `json:"stations"`     // Stations that contributed, nearest first
+	Oracles      []string  `json:"oracles"`      // Oracles of the agreeing stations, sorted
+	NearestKm    float64   `json:"nearestKm"`    // Distance to the nearest contributing station
+}
+
+// FarmPoint is a farm location checked for station coverage
+type FarmPoint struct {
+	FarmID    string  `json:"farmID"`    // Farm or farmer identifier
+	Latitude  float64 `json:"latitude"`  // Latitude coordinate
+	Longitude float64 `json:"longitude"` // Longitude coordinate
+}
+
+// CoverageGap is a farm with no active station within the requested radius
+type CoverageGap struct {
+	FarmID           string  `json:"farmID"`           // Farm or farmer identifier
+	Latitude         float64 `json:"latitude"`         // Latitude coordinate
+	Longitude        float64 `json:"longitude"`        // Longitude coordinate
+	GridCell         string  `json:"gridCell"`         // Grid cell containing the farm
+	NearestStationID string  `json:"nearestStationID"` // Nearest active station at any distance, empty if none
+	NearestKm        float64 `json:"nearestKm"`        // Distance to it, -1 if none
+}
+
+// Station index object types
+const (
+	stationCellObjectType    = "StationCell"    // grid cell, station ID
+	stationReadingObjectType = "StationReading" // station ID, UTC day, data ID
+)
+
+// Interpolation settings
+const (
+	earthRadiusKm         = 6371.0088 // Mean Earth radius
+	interpolationStations = 4         // Nearest reporting stations used per day
+	interpolationPower    = 2         // Inverse-distance weighting exponent
+	minWeightKm           = 0.01      // Closer stations are weighted as if this far
+	maxSearchRadiusKm     = 500       // Largest radius a lookup may ask for
+)
+
+// ========================================
+// STATION REGISTRY
+// ========================================
+
+// RegisterStation adds a station an oracle reports for. Only the oracle's bound
+// submitter may register its stations. activeFrom is a date (YYYY-MM-DD); activeTo may
+// be empty while the station is in service.
+func (wo *WeatherOracleChaincode) RegisterStation(ctx contractapi.TransactionContextInterface,
+	stationID string, name string, latitude float64, longitude float64, elevation float64,
+	oracleID string, activeFrom string, activeTo string) error {
+
+	existing, err := ctx.GetStub().GetState("STATION_" + stationID)
+	if err != nil {
+		return fmt.Errorf("failed to read station: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("station %s already exists", stationID)
+	}
+
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return fmt.Errorf("invalid coordinates: %.6f, %.6f", latitude, longitude)
+	}
+
+	oracle, err := wo.GetOracleProvider(ctx, oracleID)
+	if err != nil {
+		return err
+	}
+	if err := checkSubmitter(ctx, oracle); err != nil {
+		return err
+	}
+
+	from, err := time.Parse(dailyBucketLayout, activeFrom)
+	if err != nil {
+		return fmt.Errorf("invalid active from date: %v", err)
+	}
+	var to time.Time
+	if activeTo != "" {
+		to, err = time.Parse(dailyBucketLayout, activeTo)
+		if err != nil {
+			return fmt.Errorf("invalid active to date: %v", err)
+		}
+		if to.Before(from) {
+			return fmt.Errorf("active period ends before it starts")
+		}
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	station := WeatherStation{
+		StationID:      stationID,
+		Name:           name,
+		Latitude:       latitude,
+		Longitude:      longitude,
+		Elevation:      elevation,
+		GridCell:       geogrid.CellID(latitude, longitude),
+		OracleID:       oracleID,
+		ActiveFrom:     from,
+		ActiveTo:       to,
+		RegisteredBy:   callerID,
+		RegisteredDate: time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)),
+	}
+
+	if err := putStation(ctx, &station); err != nil {
+		return err
+	}
+
+	cellKey, err := ctx.GetStub().CreateCompositeKey(stationCellObjectType, []string{station.GridCell, stationID})
+	if err != nil {
+		return fmt.Errorf("failed to create station cell key: %v", err)
+	}
+	err = ctx.GetStub().PutState(cellKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to index station: %v", err)
+	}
+
+	return nil
+}
+
+// CloseStation ends a station's active period on the given date (YYYY-MM-DD)
+func (wo *WeatherOracleChaincode) CloseStation(ctx contractapi.TransactionContextInterface,
+	stationID string, activeTo string) error {
+
+	station, err := wo.GetStation(ctx, stationID)
+	if err != nil {
+		return err
+	}
+
+	oracle, err := wo.GetOracleProvider(ctx, station.OracleID)
+	if err != nil {
+		return err
+	}
+	if err := checkSubmitter(ctx, oracle); err != nil {
+		return err
+	}
+
+	to, err := time.Parse(dailyBucketLayout, activeTo)
+	if err != nil {
+		return fmt.Errorf("invalid active to date: %v", err)
+	}
+	if to.Before(station.ActiveFrom) {
+		return fmt.Errorf("active period ends before it starts")
+	}
+
+	station.ActiveTo = to
+	return putStation(ctx, station)
+}
+
+// GetStation retrieves a station
+func (wo *WeatherOracleChaincode) GetStation(ctx contractapi.TransactionContextInterface,
+	stationID string) (*WeatherStation, error) {
+
+	stationJSON, err := ctx.GetStub().GetState("STATION_" + stationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read station: %v", err)
+	}
+	if stationJSON == nil {
+		return nil, fmt.Errorf("station %s does not exist", stationID)
+	}
+
+	var station WeatherStation
+	err = json.Unmarshal(stationJSON, &station)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal station: %v", err)
+	}
+
+	return &station, nil
+}
+
+// GetStationsByCell lists the stations registered in a grid cell
+func (wo *WeatherOracleChaincode) GetStationsByCell(ctx contractapi.TransactionContextInterface,
+	gridCell string) ([]*WeatherStation, error) {
+
+	return wo.indexedStations(ctx, []string{gridCell})
+}
+
+// FindNearestStations returns up to limit stations active on asOf (YYYY-MM-DD) within
+// maxDistanceKm of a point, nearest first; equal distances are ordered by station ID
+func (wo *WeatherOracleChaincode) FindNearestStations(ctx contractapi.TransactionContextInterface,
+	latitude float64, longitude float64, asOf string, maxDistanceKm float64, limit int) ([]*StationDistance, error) {
+
+	day, err := time.Parse(dailyBucketLayout, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid as-of date: %v", err)
+	}
+	if err := validateSearch(latitude, longitude, maxDistanceKm); err != nil {
+		return nil, err
+	}
+
+	nearby, err := wo.stationsWithin(ctx, latitude, longitude, maxDistanceKm)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*StationDistance
+	for _, candidate := range nearby {
+		if stationActive(candidate.Station, day) {
+			results = append(results, candidate)
+		}
+	}
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// InterpolateWeather estimates daily weather at a point for each UTC day from startDate
+// to endDate (YYYY-MM-DD) by inverse-distance weighting the nearest stations within
+// maxDistanceKm that reported validated readings that day. Days no station covers have
+// HasData false. A day is Corroborated when stations of at least MinSubmissions distinct
+// oracles agree within the consensus tolerances; its estimate then uses only those.
+func (wo *WeatherOracleChaincode) InterpolateWeather(ctx contractapi.TransactionContextInterface,
+	latitude float64, longitude float64, startDate string, endDate string, maxDistanceKm float64) ([]*InterpolatedDay, error) {
+
+	start, err := time.Parse(dailyBucketLayout, startDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date: %v", err)
+	}
+	end, err := time.Parse(dailyBucketLayout, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date: %v", err)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end date is before start date")
+	}
+	if end.Sub(start) > 366*24*time.Hour {
+		return nil, fmt.Errorf("interpolation range cannot exceed 366 days")
+	}
+	if err := validateSearch(latitude, longitude, maxDistanceKm); err != nil {
+		return nil, err
+	}
+
+	nearby, err := wo.stationsWithin(ctx, latitude, longitude, maxDistanceKm)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := wo.GetConsensusConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []*InterpolatedDay
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		estimate := &InterpolatedDay{Date: day, Stations: []string{}, Oracles: []string{}}
+
+		// Nearest stations first, so the closest reporting stations are used
+		var used []*StationDistance
+		var values []*stationDay
+		for _, candidate := range nearby {
+			if len(used) == interpolationStations {
+				break
+			}
+			if !stationActive(candidate.Station, day) {
+				continue
+			}
+			value, err := wo.stationDayValues(ctx, candidate.Station.StationID, day)
+			if err != nil {
+				return nil, err
+			}
+			if value == nil {
+				continue
+			}
+			used = append(used, candidate)
+			values = append(values, value)
+		}
+
+		if len(used) > 0 {
+			estimate.HasData = true
+
+			// No station decides alone, however close: only stations agreeing with the
+			// others are used once enough independent oracles agree
+			agreeing, oracles := corroboratingStations(used, values, config)
+			if len(oracles) >= config.MinSubmissions {
+				estimate.Corroborated = true
+				estimate.Oracles = oracles
+				var agreed []*StationDistance
+				var agreedValues []*stationDay
+				for i := range used {
+					if agreeing[i] {
+						agreed = append(agreed, used[i])
+						agreedValues = append(agreedValues, values[i])
+					}
+				}
+				used, values = agreed, agreedValues
+			}
+			estimate.NearestKm = used[0].DistanceKm
+
+			var totalWeight float64
+			for i, candidate := range used {
+				weight := 1 / math.Pow(math.Max(candidate.DistanceKm, minWeightKm), interpolationPower)
+				totalWeight += weight
+				estimate.Rainfall += weight * values[i].Rainfall
+				estimate.Temperature += weight * values[i].Temperature
+				estimate.Humidity += weight * values[i].Humidity
+				estimate.WindSpeed += weight * values[i].WindSpeed
+				estimate.Stations = append(estimate.Stations, candidate.Station.StationID)
+			}
+			estimate.Rainfall /= totalWeight
+			estimate.Temperature /= totalWeight
+			estimate.Humidity /= totalWeight
+			estimate.WindSpeed /= totalWeight
+		}
+
+		days = append(days, estimate)
+	}
+
+	return days, nil
+}
+
+// GetCoverageGaps reports the farms in farmsJSON (an array of FarmPoint) with no station
+// active on asOf (YYYY-MM-DD) within radiusKm
+func (wo *WeatherOracleChaincode) GetCoverageGaps(ctx contractapi.TransactionContextInterface,
+	farmsJSON string, radiusKm float64, asOf string) ([]*CoverageGap, error) {
+
+	var farms []FarmPoint
+	if err := json.Unmarshal([]byte(farmsJSON), &farms); err != nil {
+		return nil, fmt.Errorf("failed to parse farms: %v", err)
+	}
+
+	day, err := time.Parse(dailyBucketLayout, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid as-of date: %v", err)
+	}
+	if radiusKm <= 0 {
+		return nil, fmt.Errorf("radius must be positive")
+	}
+
+	stations, err := wo.indexedStations(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	var active []*WeatherStation
+	for _, station := range stations {
+		if stationActive(station, day) {
+			active = append(active, station)
+		}
+	}
+
+	gaps := []*CoverageGap{}
+	for _, farm := range farms {
+		if farm.Latitude < -90 || farm.Latitude > 90 || farm.Longitude < -180 || farm.Longitude > 180 {
+			return nil, fmt.Errorf("invalid coordinates for farm %s", farm.FarmID)
+		}
+
+		gap := &CoverageGap{
+			FarmID:    farm.FarmID,
+			Latitude:  farm.Latitude,
+			Longitude: farm.Longitude,
+			GridCell:  geogrid.CellID(farm.Latitude, farm.Longitude),
+			NearestKm: -1,
+		}
+		for _, station := range active {
+			distance := haversineKm(farm.Latitude, farm.Longitude, station.Latitude, station.Longitude)
+			if gap.NearestKm < 0 || distance < gap.NearestKm {
+				gap.NearestStationID = station.StationID
+				gap.NearestKm = distance
+			}
+		}
+		if gap.NearestKm < 0 || gap.NearestKm > radiusKm {
+			gaps = append(gaps, gap)
+		}
+	}
+
+	return gaps, nil
+}
+
+// ========================================
+// STATION HELPER FUNCTIONS
+// ========================================
+
+// stationDay is a station's weather for one UTC day
+type stationDay struct {
+	Rainfall    float64
+	Temperature float64
+	Humidity    float64
+	WindSpeed   float64
+}
+
+// metric reads a consensus metric from a station's day
+func (value *stationDay) metric(metric string) float64 {
+	switch metric {
+	case "rainfall":
+		return value.Rainfall
+	case "temperature":
+		return value.Temperature
+	case "humidity":
+		return value.Humidity
+	case "windSpeed":
+		return value.WindSpeed
+	}
+	return 0
+}
+
+// corroboratingStations marks the stations whose day lies within the consensus
+// tolerances of the stations' median on every metric, and returns the distinct oracles
+// reporting for them
+func corroboratingStations(used []*StationDistance, values []*stationDay,
+	config *ConsensusConfig) ([]bool, []string) {
+
+	agreeing := make([]bool, len(used))
+	for i := range agreeing {
+		agreeing[i] = true
+	}
+	for _, metric := range consensusMetrics {
+		metricValues := make([]float64, len(values))
+		for i, value := range values {
+			metricValues[i] = value.metric(metric)
+		}
+		center := median(metricValues)
+		tolerance := config.Tolerances[metric]
+		allowed := math.Max(tolerance.Absolute, tolerance.Relative*math.Abs(center))
+		for i, value := range metricValues {
+			if math.Abs(value-center) > allowed {
+				agreeing[i] = false
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	oracles := []string{}
+	for i, candidate := range used {
+		if agreeing[i] && !seen[candidate.Station.OracleID] {
+			seen[candidate.Station.OracleID] = true
+			oracles = append(oracles, candidate.Station.OracleID)
+		}
+	}
+	sort.Strings(oracles)
+
+	return agreeing, oracles
+}
+
+// stationDayValues combines a station's validated readings for a day.
+// Daily readings are averaged; without one, hourly rainfall is summed and the other
+// metrics averaged, and without either a reconciled batched series is combined the same
+// way. Series not yet reconciled against consensus are never used.
+// Returns nil when the station has no usable reading that day.
+func (wo *WeatherOracleChaincode) stationDayValues(ctx contractapi.TransactionContextInterface,
+	stationID string, day time.Time) (*stationDay, error) {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(stationReadingObjectType,
+		[]string{stationID, day.Format(dailyBucketLayout)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query station readings: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var daily, hourly stationDay
+	dailyCount, hourlyCount := 0, 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split station reading key: %v", err)
+		}
+
+		data, err := wo.GetWeatherData(ctx, attributes[2])
+		if err != nil {
+			return nil, err
+		}
+		// Pending readings have not been judged by a consensus round
+		if data.Status != "Validated" {
+			continue
+		}
+
+		target := &daily
+		if data.ObservationPeriod == "Hourly" {
+			target = &hourly
+			hourlyCount++
+		} else {
+			dailyCount++
+		}
+		target.Rainfall += data.Rainfall
+		target.Temperature += data.Temperature
+		target.Humidity += data.Humidity
+		target.WindSpeed += data.WindSpeed
+	}
+
+	switch {
+	case dailyCount > 0:
+		n := float64(dailyCount)
+		return &stationDay{daily.Rainfall / n, daily.Temperature / n, daily.Humidity / n, daily.WindSpeed / n}, nil
+	case hourlyCount > 0:
+		n := float64(hourlyCount)
+		return &stationDay{hourly.Rainfall, hourly.Temperature / n, hourly.Humidity / n, hourly.WindSpeed / n}, nil
+	}
+
+	// Without individual submissions, use the station's batched series once reconciled
+	series, err := stationSeries(ctx, stationID, day.Format(dailyBucketLayout))
+	if err != nil {
+		return nil, err
+	}
+	if series == nil || len(series.Readings) == 0 || series.Status != "Reconciled" {
+		return nil, nil
+	}
+	return seriesDayValues(series), nil
+}
+
+// stationsWithin returns every registered station within radiusKm of a point, nearest
+// first with ties broken by station ID
+func (wo *WeatherOracleChaincode) stationsWithin(ctx contractapi.TransactionContextInterface,
+	latitude float64, longitude float64, radiusKm float64) ([]*StationDistance, error) {
+
+	stations, err := wo.indexedStations(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var nearby []*StationDistance
+	for _, station := range stations {
+		distance := haversineKm(latitude, longitude, station.Latitude, station.Longitude)
+		if distance <= radiusKm {
+			nearby = append(nearby, &StationDistance{Station: station, DistanceKm: distance})
+		}
+	}
+	sort.Slice(nearby, func(i, j int) bool {
+		if nearby[i].DistanceKm != nearby[j].DistanceKm {
+			return nearby[i].DistanceKm < nearby[j].DistanceKm
+		}
+		return nearby[i].Station.StationID < nearby[j].Station.StationID
+	})
+
+	return nearby, nil
+}
+
+// indexedStations loads the stations under a grid cell index prefix, or all stations
+// for an empty prefix, in key order
+func (wo *WeatherOracleChaincode) indexedStations(ctx contractapi.TransactionContextInterface,
+	prefix []string) ([]*WeatherStation, error) {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(stationCellObjectType, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stations: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	stations := []*WeatherStation{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split station cell key: %v", err)
+		}
+
+		station, err := wo.GetStation(ctx, attributes[1])
+		if err != nil {
+			return nil, err
+		}
+		stations = append(stations, station)
+	}
+
+	return stations, nil
+}
+
+// checkStationReading confirms a reading names a station its oracle owns, that was in
+// service at the observation time, and whose coordinates it reports
+func (wo *WeatherOracleChaincode) checkStationReading(ctx contractapi.TransactionContextInterface,
+	reading *WeatherPayload, observed time.Time) error {
+
+	station, err := wo.GetStation(ctx, reading.StationID)
+	if err != nil {
+		return err
+	}
+	if station.OracleID != reading.OracleID {
+		return fmt.Errorf("station %s is not operated by oracle %s", station.StationID, reading.OracleID)
+	}
+
+	day, _ := time.Parse(dailyBucketLayout, observed.Format(dailyBucketLayout))
+	if !stationActive(station, day) {
+		return fmt.Errorf("station %s was not active on %s", station.StationID, day.Format(dailyBucketLayout))
+	}
+	if math.Abs(station.Latitude-reading.Latitude) > 1e-6 || math.Abs(station.Longitude-reading.Longitude) > 1e-6 {
+		return fmt.Errorf("reading coordinates do not match station %s", station.StationID)
+	}
+
+	return nil
+}
+
+// putStation stores a station
+func putStation(ctx contractapi.TransactionContextInterface, station *WeatherStation) error {
+	stationJSON, err := json.Marshal(station)
+	if err != nil {
+		return fmt.Errorf("failed to marshal station: %v", err)
+	}
+
+	err = ctx.GetStub().PutState("STATION_"+station.StationID, stationJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put station: %v", err)
+	}
+
+	return nil
+}
+
+// stationActive reports whether a station was in service on a UTC day
+func stationActive(station *WeatherStation, day time.Time) bool {
+	if day.Before(station.ActiveFrom) {
+		return false
+	}
+	return station.ActiveTo.IsZero() || !day.After(station.ActiveTo)
+}
+
+// validateSearch checks a lookup point and radius
+func validateSearch(latitude float64, longitude float64, radiusKm float64) error {
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return fmt.Errorf("invalid coordinates: %.6f, %.6f", latitude, longitude)
+	}
+	if radiusKm <= 0 || radiusKm > maxSearchRadiusKm {
+		return fmt.Errorf("search radius must be greater than 0 and at most %d km", maxSearchRadiusKm)
+	}
+	return nil
+}
+
+// haversineKm is the great-circle distance between two coordinates
+func haversineKm(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -1,13 +1,33 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
+	"geogrid"
+
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// Chaincodes invoked from the weather oracle chaincode
+const (
+	accessControlChaincode   = "access-control"
+	approvalManagerChaincode = "approval-manager"
+)
+
 // WeatherOracleChaincode ingests, validates, and stores weather data from multiple sources
 type WeatherOracleChaincode struct {
 	contractapi.Contract
@@ -20,15 +40,44 @@ type WeatherData struct {
 	Location        string    `json:"location"`        // Geographic location/region
 	Latitude        float64   `json:"latitude"`        // Latitude coordinate
 	Longitude       float64   `json:"longitude"`       // Longitude coordinate
-	Timestamp       time.Time `json:"timestamp"`       // Observation timestamp
+	GridCell        string    `json:"gridCell"`        // Grid cell containing the coordinates
+	Timestamp       time.Time `json:"timestamp"`       // Observation time reported by the oracle, UTC
 	Rainfall        float64   `json:"rainfall"`        // Rainfall in mm
 	Temperature     float64   `json:"temperature"`     // Temperature in Celsius
 	Humidity        float64   `json:"humidity"`        // Humidity percentage
 	WindSpeed       float64   `json:"windSpeed"`       // Wind speed in km/h
-	DataHash        string    `json:"dataHash"`        // Hash of raw data for verification
+	DataHash        string    `json:"dataHash"`        // SHA-256 of the signed payload, computed on-chain
 	ValidationScore float64   `json:"validationScore"` // Consensus validation score
 	Status          string    `json:"status"`          // Pending, Validated, Anomalous
 	SubmittedBy     string    `json:"submittedBy"`     // Oracle submitter identity
+
+	Deviations map[string]float64 `json:"deviations,omitempty"` // Per-metric distance from the consensus value
+
+	Signature      string `json:"signature"`      // Oracle's base64 signature over the canonical payload
+	KeyFingerprint string `json:"keyFingerprint"` // Fingerprint of the key that verified the signature
+
+	ObservationPeriod string    `json:"observationPeriod"` // Hourly or Daily; readings before periods were recorded are Daily
+	SubmittedAt       time.Time `json:"submittedAt"`       // Transaction time the reading reached the ledger
+	RoundBucket       string    `json:"roundBucket"`       // Consensus round the reading was indexed to
+
+	StationID string `json:"stationID,omitempty"` // Registered station that observed the reading
+}
+
+// WeatherPayload is the canonical reading an oracle signs. The signed bytes must be the
+// JSON encoding of this struct exactly: fields in this order, no extra whitespace.
+type WeatherPayload struct {
+	DataID          string  `json:"dataID"`
+	OracleID        string  `json:"oracleID"`
+	StationID       string  `json:"stationID,omitempty"` // Registered station, if the oracle reports for one
+	Location        string  `json:"location"`
+	Latitude        float64 `json:"latitude"`
+	Longitude       float64 `json:"longitude"`
+	ObservationTime string  `json:"observationTime"` // RFC3339
+	Period          string  `json:"period"`          // Hourly or Daily
+	Rainfall        float64 `json:"rainfall"`
+	Temperature     float64 `json:"temperature"`
+	Humidity        float64 `json:"humidity"`
+	WindSpeed       float64 `json:"windSpeed"`
 }
 
 // OracleProvider represents an authorized weather data source
@@ -43,26 +92,149 @@ type OracleProvider struct {
 	Status           string    `json:"status"`           // Active, Suspended, Revoked
 	RegisteredDate   time.Time `json:"registeredDate"`   // Registration timestamp
 	LastSubmission   time.Time `json:"lastSubmission"`   // Last data submission time
+
+	PublicKey         string    `json:"publicKey"`         // PEM public key submissions are verified with
+	KeyAlgorithm      string    `json:"keyAlgorithm"`      // ECDSA-P256 or Ed25519
+	KeyFingerprint    string    `json:"keyFingerprint"`    // SHA-256 of the DER public key, hex
+	KeyRegisteredDate time.Time `json:"keyRegisteredDate"` // When the current key was registered
+
+	ProbationRemaining int       `json:"probationRemaining"` // Judged submissions left at the probationary weight
+	StatusReason       string    `json:"statusReason"`       // Why the oracle was last suspended or reinstated
+	StatusChangedBy    string    `json:"statusChangedBy"`    // Identity that last changed the status, empty for automatic
+	StatusChangedDate  time.Time `json:"statusChangedDate"`  // Last status change
+
+	SubmitterMSP       string `json:"submitterMSP"`                // MSP of the identity allowed to submit for the oracle
+	SubmitterID        string `json:"submitterID"`                 // X.509 identity allowed to submit, unless bound by attribute
+	SubmitterAttribute string `json:"submitterAttribute"`          // Certificate attribute "name=value" that identifies submitters instead
+	RegisteredBy       string `json:"registeredBy"`                // Identity that registered or last bound the oracle
+	ApprovalRequestID  string `json:"approvalRequestID,omitempty"` // Approval that authorised registration, if not a role
 }
 
-// ConsensusRecord tracks multi-oracle consensus for a location and time
+// ConsensusRecord is a closed consensus round: every submission for a grid cell and
+// observation bucket, and the consensus reached over them
 type ConsensusRecord struct {
 	RecordID         string             `json:"recordID"`         // Unique consensus record ID
-	Location         string             `json:"location"`         // Geographic location
-	Timestamp        time.Time          `json:"timestamp"`        // Observation timestamp
+	Location         string             `json:"location"`         // Location label of the round's first submission
+	GridCell         string             `json:"gridCell"`         // Grid cell the round covers
+	Timestamp        time.Time          `json:"timestamp"`        // Start of the observation bucket
 	OracleCount      int                `json:"oracleCount"`      // Number of oracles submitted
 	Consensus        map[string]float64 `json:"consensus"`        // Agreed weather values
-	ConsensusReached bool               `json:"consensusReached"` // 2/3+ agreement reached
+	ConsensusReached bool               `json:"consensusReached"` // Quorum of submissions agreed
 	CreatedDate      time.Time          `json:"createdDate"`      // Record creation timestamp
+
+	AgreeingCount int                    `json:"agreeingCount"` // Oracles whose submissions were within tolerance on every metric
+	RequiredCount int                    `json:"requiredCount"` // Agreeing oracles the quorum needed
+	Submissions   []*SubmissionDeviation `json:"submissions"`   // How far each submission sat from consensus
+
+	Bucket       string    `json:"bucket"`       // Observation bucket: "2006-01-02" daily, "2006-01-02T15" hourly
+	Period       string    `json:"period"`       // Daily or Hourly
+	Deadline     time.Time `json:"deadline"`     // When the round closes without a quorum
+	Status       string    `json:"status"`       // Closed; open rounds have no record yet
+	CloseReason  string    `json:"closeReason"`  // Quorum or Deadline
+	Participants []string  `json:"participants"` // Oracles whose submissions were considered, sorted
+	DataIDs      []string  `json:"dataIDs"`      // Every submission indexed to the round, sorted
+}
+
+// SubmissionDeviation records one submission's distance from the consensus on each metric
+type SubmissionDeviation struct {
+	DataID          string             `json:"dataID"`          // Weather data submission
+	OracleID        string             `json:"oracleID"`        // Submitting oracle
+	Deviations      map[string]float64 `json:"deviations"`      // Absolute distance from the consensus by metric
+	Allowed         map[string]float64 `json:"allowed"`         // Tolerance applied by metric
+	Outlier         bool               `json:"outlier"`         // Rejected by the median/MAD test before consensus
+	WithinTolerance bool               `json:"withinTolerance"` // Whether every metric was within tolerance
+}
+
+// MetricTolerance bounds how far a submission may sit from the consensus of a metric.
+// Agreement allows the larger of the absolute and relative bounds, so readings near zero
+// (dry days, temperatures around 0 °C) are judged on the absolute bound.
+type MetricTolerance struct {
+	Absolute      float64 `json:"absolute"`      // Allowed deviation in the metric's unit
+	Relative      float64 `json:"relative"`      // Allowed deviation as a fraction of the consensus value
+	MADMultiplier float64 `json:"madMultiplier"` // Outlier cut in scaled median absolute deviations; 0 disables
+}
+
+// ConsensusConfig governs how oracle submissions are reconciled
+type ConsensusConfig struct {
+	MinSubmissions    int                        `json:"minSubmissions"`    // Distinct oracles needed before consensus is attempted
+	QuorumNumerator   int                        `json:"quorumNumerator"`   // Fraction of those oracles that must agree,
+	QuorumDenominator int                        `json:"quorumDenominator"` // rounded up: 2/3 of 2 oracles is 2
+	Tolerances        map[string]MetricTolerance `json:"tolerances"`        // Tolerance by metric
+
+	Aggregation          string  `json:"aggregation"`          // WeightedMedian or WeightedMean of agreeing oracles
+	ReputationAlpha      float64 `json:"reputationAlpha"`      // Weight of each judgement in the reputation moving average
+	SuspendBelow         float64 `json:"suspendBelow"`         // Reputation below which an oracle is suspended
+	ReinstatementScore   float64 `json:"reinstatementScore"`   // Reputation a reinstated oracle restarts from
+	ProbationSubmissions int     `json:"probationSubmissions"` // Judged submissions a new or reinstated oracle spends on probation
+	ProbationWeight      float64 `json:"probationWeight"`      // Weight multiplier while on probation
+
+	MaxFutureMinutes int `json:"maxFutureMinutes"` // How far past the transaction time an observation may be stamped
+	MaxBackfillDays  int `json:"maxBackfillDays"`  // How old an observation may be when submitted
+
+	RoundDeadlineHours int `json:"roundDeadlineHours"` // Hours after a bucket ends, or its round opens if later, before the round closes without a quorum
+
+	UpdatedBy         string    `json:"updatedBy"`         // Identity that last changed the config
+	UpdatedDate       time.Time `json:"updatedDate"`       // Last change timestamp
+	ApprovalRequestID string    `json:"approvalRequestID"` // Approval that authorised the change, empty for an administrator
+}
+
+// consensusConfigKey stores the consensus configuration
+const consensusConfigKey = "CONSENSUS_CONFIG"
+
+// usedApprovalPrefix marks approval requests already spent on an administrative change
+const usedApprovalPrefix = "APPROVAL_USED_"
+
+// roundSubmissionObjectType indexes submissions by consensus round: grid cell, bucket, data ID
+const roundSubmissionObjectType = "RoundSubmission"
+
+// Bucket labels for daily and hourly consensus rounds, in UTC
+const (
+	dailyBucketLayout  = "2006-01-02"
+	hourlyBucketLayout = "2006-01-02T15"
+)
+
+// consensusMetrics are the metrics compared across submissions, in a fixed order
+var consensusMetrics = []string{"rainfall", "temperature", "humidity", "windSpeed"}
+
+// defaultConsensusConfig applies until SetConsensusConfig is called
+func defaultConsensusConfig() *ConsensusConfig {
+	return &ConsensusConfig{
+		MinSubmissions:    2,
+		QuorumNumerator:   2,
+		QuorumDenominator: 3,
+		Tolerances: map[string]MetricTolerance{
+			"rainfall":    {Absolute: 2, Relative: 0.2, MADMultiplier: 3},
+			"temperature": {Absolute: 2, Relative: 0, MADMultiplier: 3},
+			"humidity":    {Absolute: 10, Relative: 0.1, MADMultiplier: 3},
+			"windSpeed":   {Absolute: 5, Relative: 0.25, MADMultiplier: 3},
+		},
+		Aggregation:          "WeightedMedian",
+		ReputationAlpha:      0.1,
+		SuspendBelow:         70,
+		ReinstatementScore:   80,
+		ProbationSubmissions: 10,
+		ProbationWeight:      0.5,
+		MaxFutureMinutes:     15,
+		MaxBackfillDays:      30,
+		RoundDeadlineHours:   24,
+	}
 }
 
+// observationPeriods are the periods a reading may cover
+var observationPeriods = map[string]bool{"Hourly": true, "Daily": true}
+
 // ========================================
 // ORACLE PROVIDER MANAGEMENT
 // ========================================
 
-// RegisterOracleProvider adds an authorized weather data source
+// RegisterOracleProvider adds an authorized weather data source and binds it to the
+// calling identity: only callers from the same MSP with the same X.509 identity, or
+// carrying submitterAttribute ("name=value") if given, may submit for the oracle.
+// The caller needs an active Oracle role in access-control, or an approval-manager
+// request for RegisterOracleProvider with this oracle ID when approvalRequestID is set.
 func (wo *WeatherOracleChaincode) RegisterOracleProvider(ctx contractapi.TransactionContextInterface,
-	oracleID string, providerName string, providerType string, dataSources []string) error {
+	oracleID string, providerName string, providerType string, dataSources []string,
+	submitterAttribute string, approvalRequestID string) error {
 
 	// Check if oracle already exists
 	exists, err := wo.oracleExists(ctx, oracleID)
@@ -79,6 +251,20 @@ func (wo *WeatherOracleChaincode) RegisterOracleProvider(ctx contractapi.Transac
 		return fmt.Errorf("invalid provider type: %s", providerType)
 	}
 
+	if approvalRequestID != "" {
+		err = verifyFunctionApproval(ctx, approvalRequestID, "RegisterOracleProvider", oracleID)
+	} else {
+		err = verifyCallerRole(ctx, "Oracle")
+	}
+	if err != nil {
+		return err
+	}
+
+	submitter, err := bindSubmitter(ctx, submitterAttribute)
+	if err != nil {
+		return err
+	}
+
 	// Get deterministic transaction timestamp
 	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
@@ -86,6 +272,11 @@ func (wo *WeatherOracleChaincode) RegisterOracleProvider(ctx contractapi.Transac
 	}
 	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
+	config, err := wo.GetConsensusConfig(ctx)
+	if err != nil {
+		return err
+	}
+
 	oracle := OracleProvider{
 		OracleID:         oracleID,
 		ProviderName:     providerName,
@@ -97,6 +288,15 @@ func (wo *WeatherOracleChaincode) RegisterOracleProvider(ctx contractapi.Transac
 		Status:           "Active",
 		RegisteredDate:   timestamp,
 		LastSubmission:   time.Time{},
+
+		ProbationRemaining: config.ProbationSubmissions,
+		StatusChangedDate:  timestamp,
+
+		SubmitterMSP:       submitter.SubmitterMSP,
+		SubmitterID:        submitter.SubmitterID,
+		SubmitterAttribute: submitter.SubmitterAttribute,
+		RegisteredBy:       submitter.RegisteredBy,
+		ApprovalRequestID:  approvalRequestID,
 	}
 
 	oracleJSON, err := json.Marshal(oracle)
@@ -133,6 +333,78 @@ func (wo *WeatherOracleChaincode) GetOracleProvider(ctx contractapi.TransactionC
 	return &oracle, nil
 }
 
+// BindOracleSubmitter moves an oracle's submitter binding to the calling identity, for
+// certificate changes and oracles registered before submissions were bound. Without an
+// approval the caller must be the currently bound submitter; otherwise it needs a
+// one-time approval-manager request for BindOracleSubmitter naming this oracle.
+func (wo *WeatherOracleChaincode) BindOracleSubmitter(ctx contractapi.TransactionContextInterface,
+	oracleID string, submitterAttribute string, approvalRequestID string) error {
+
+	oracle, err := wo.GetOracleProvider(ctx, oracleID)
+	if err != nil {
+		return err
+	}
+
+	if approvalRequestID != "" {
+		err = verifyFunctionApproval(ctx, approvalRequestID, "BindOracleSubmitter", oracleID)
+		if err == nil {
+			err = spendApproval(ctx, approvalRequestID)
+		}
+	} else {
+		err = checkSubmitter(ctx, oracle)
+	}
+	if err != nil {
+		return err
+	}
+
+	submitter, err := bindSubmitter(ctx, submitterAttribute)
+	if err != nil {
+		return err
+	}
+
+	oracle.SubmitterMSP = submitter.SubmitterMSP
+	oracle.SubmitterID = submitter.SubmitterID
+	oracle.SubmitterAttribute = submitter.SubmitterAttribute
+	oracle.RegisteredBy = submitter.RegisteredBy
+	oracle.ApprovalRequestID = approvalRequestID
+
+	return wo.putOracle(ctx, oracle)
+}
+
+// RegisterOracleKey sets the public key an oracle's submissions must be signed with,
+// replacing any previous key. The key is a PEM "PUBLIC KEY" block (PKIX) holding an
+// ECDSA P-256 or Ed25519 key. Only the oracle's bound submitter may set it.
+func (wo *WeatherOracleChaincode) RegisterOracleKey(ctx contractapi.TransactionContextInterface,
+	oracleID string, publicKeyPEM string) error {
+
+	oracle, err := wo.GetOracleProvider(ctx, oracleID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkSubmitter(ctx, oracle); err != nil {
+		return err
+	}
+
+	_, algorithm, fingerprint, err := parseOracleKey(publicKeyPEM)
+	if err != nil {
+		return err
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	oracle.PublicKey = publicKeyPEM
+	oracle.KeyAlgorithm = algorithm
+	oracle.KeyFingerprint = fingerprint
+	oracle.KeyRegisteredDate = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	return wo.putOracle(ctx, oracle)
+}
+
 // GetOracleReputation retrieves the trust score for an oracle
 func (wo *WeatherOracleChaincode) GetOracleReputation(ctx contractapi.TransactionContextInterface,
 	oracleID string) (float64, error) {
@@ -145,8 +417,21 @@ func (wo *WeatherOracleChaincode) GetOracleReputation(ctx contractapi.Transactio
 	return oracle.ReputationScore, nil
 }
 
-// UpdateOracleReputation adjusts oracle trust score
+// UpdateOracleReputation records one judgement of an oracle's data in its reputation.
+// The caller must be a platform administrator, or present an approval request naming
+// UpdateOracleReputation with the oracle ID as its first argument.
 func (wo *WeatherOracleChaincode) UpdateOracleReputation(ctx contractapi.TransactionContextInterface,
+	oracleID string, anomalousData bool, approvalRequestID string) error {
+
+	if err := authorizeAdmin(ctx, "UpdateOracleReputation", oracleID, approvalRequestID); err != nil {
+		return err
+	}
+
+	return wo.recordJudgement(ctx, oracleID, anomalousData)
+}
+
+// recordJudgement moves an oracle's reputation by one judgement of its data
+func (wo *WeatherOracleChaincode) recordJudgement(ctx contractapi.TransactionContextInterface,
 	oracleID string, anomalousData bool) error {
 
 	oracle, err := wo.GetOracleProvider(ctx, oracleID)
@@ -154,61 +439,117 @@ func (wo *WeatherOracleChaincode) UpdateOracleReputation(ctx contractapi.Transac
 		return err
 	}
 
-	oracle.TotalSubmissions++
-	if anomalousData {
-		oracle.AnomalousCount++
+	config, err := wo.GetConsensusConfig(ctx)
+	if err != nil {
+		return err
 	}
 
-	// Calculate reputation: (total - anomalous) / total * 100
-	if oracle.TotalSubmissions > 0 {
-		successRate := float64(oracle.TotalSubmissions-oracle.AnomalousCount) / float64(oracle.TotalSubmissions)
-		oracle.ReputationScore = successRate * 100
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
 	}
 
-	// Suspend oracle if reputation drops below 70%
-	if oracle.ReputationScore < 70.0 {
-		oracle.Status = "Suspended"
+	judgeOracle(oracle, anomalousData, config, time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)))
+
+	return wo.putOracle(ctx, oracle)
+}
+
+// ReinstateOracle returns a suspended oracle to Active at the reinstatement score; it
+// serves a fresh probation before its data carries full weight. The caller must be a
+// platform administrator, or present an approval request naming ReinstateOracle with
+// the oracle ID as its first argument.
+func (wo *WeatherOracleChaincode) ReinstateOracle(ctx contractapi.TransactionContextInterface,
+	oracleID string, reason string, approvalRequestID string) error {
+
+	if err := authorizeAdmin(ctx, "ReinstateOracle", oracleID, approvalRequestID); err != nil {
+		return err
 	}
 
-	oracleJSON, err := json.Marshal(oracle)
+	oracle, err := wo.GetOracleProvider(ctx, oracleID)
 	if err != nil {
-		return fmt.Errorf("failed to marshal oracle: %v", err)
+		return err
+	}
+	if oracle.Status != "Suspended" {
+		return fmt.Errorf("oracle %s is %s, not Suspended", oracleID, oracle.Status)
+	}
+	if reason == "" {
+		return fmt.Errorf("reinstatement reason is required")
 	}
 
-	err = ctx.GetStub().PutState("ORACLE_"+oracleID, oracleJSON)
+	config, err := wo.GetConsensusConfig(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to update oracle reputation: %v", err)
+		return err
 	}
 
-	return nil
+	// Get caller identity
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	oracle.Status = "Active"
+	oracle.ReputationScore = config.ReinstatementScore
+	oracle.ProbationRemaining = config.ProbationSubmissions
+	oracle.StatusReason = reason
+	oracle.StatusChangedBy = callerID
+	oracle.StatusChangedDate = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	return wo.putOracle(ctx, oracle)
 }
 
 // ========================================
 // WEATHER DATA SUBMISSION & VALIDATION
 // ========================================
 
-// SubmitWeatherData records weather readings from an oracle
+// SubmitWeatherData records a signed reading from an oracle. payload is the canonical
+// WeatherPayload JSON and signature its base64 signature by the oracle's registered key:
+// ECDSA P-256 (ASN.1 DER over SHA-256) or Ed25519. The reading is stamped with its
+// observation time, which must fall within the configured bounds of the transaction time,
+// and joins the consensus round for its grid cell and bucket. The round closes in the
+// same transaction once the reading completes a quorum.
 func (wo *WeatherOracleChaincode) SubmitWeatherData(ctx contractapi.TransactionContextInterface,
-	dataID string, oracleID string, location string, latitude float64, longitude float64,
-	rainfall float64, temperature float64, humidity float64, windSpeed float64, dataHash string) error {
+	payload string, signature string) error {
+
+	reading, err := parseWeatherPayload(payload)
+	if err != nil {
+		return err
+	}
 
 	// Check if data already exists
-	exists, err := wo.weatherDataExists(ctx, dataID)
+	exists, err := wo.weatherDataExists(ctx, reading.DataID)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return fmt.Errorf("weather data %s already exists", dataID)
+		return fmt.Errorf("weather data %s already exists", reading.DataID)
 	}
 
 	// Verify oracle is active
-	oracle, err := wo.GetOracleProvider(ctx, oracleID)
+	oracle, err := wo.GetOracleProvider(ctx, reading.OracleID)
 	if err != nil {
 		return fmt.Errorf("oracle not found: %v", err)
 	}
 	if oracle.Status != "Active" {
-		return fmt.Errorf("oracle %s is not active", oracleID)
+		return fmt.Errorf("oracle %s is not active", reading.OracleID)
+	}
+
+	// Only the oracle's bound identity may submit for it
+	if err := checkSubmitter(ctx, oracle); err != nil {
+		return err
+	}
+
+	// The reading must be signed by the oracle's registered key
+	if err := verifyOracleSignature(oracle, []byte(payload), signature); err != nil {
+		return err
 	}
+	payloadHash := sha256.Sum256([]byte(payload))
 
 	// Get caller identity
 	callerID, err := ctx.GetClientIdentity().GetID()
@@ -217,14 +558,8 @@ func (wo *WeatherOracleChaincode) SubmitWeatherData(ctx contractapi.TransactionC
 	}
 
 	// Validate data ranges
-	if rainfall < 0 || rainfall > 1000 {
-		return fmt.Errorf("invalid rainfall value: %.2f", rainfall)
-	}
-	if temperature < -50 || temperature > 60 {
-		return fmt.Errorf("invalid temperature value: %.2f", temperature)
-	}
-	if humidity < 0 || humidity > 100 {
-		return fmt.Errorf("invalid humidity value: %.2f", humidity)
+	if err := validateReading(reading); err != nil {
+		return err
 	}
 
 	// Get deterministic transaction timestamp
@@ -234,21 +569,78 @@ func (wo *WeatherOracleChaincode) SubmitWeatherData(ctx contractapi.TransactionC
 	}
 	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
+	config, err := wo.GetConsensusConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	observed, err := observationTime(reading, config, timestamp)
+	if err != nil {
+		return err
+	}
+
+	// The reading joins the round for its grid cell, which must still be open; the
+	// free-text location would let one area split into several rounds. Each oracle
+	// submits once per round, so none can make up a quorum alone.
+	gridCell := geogrid.CellID(reading.Latitude, reading.Longitude)
+	bucket := roundBucket(reading.Period, observed)
+	_, _, bucketEnd, err := parseRoundBucket(bucket)
+	if err != nil {
+		return err
+	}
+	recordJSON, err := ctx.GetStub().GetState(consensusRecordID(gridCell, bucket))
+	if err != nil {
+		return fmt.Errorf("failed to read consensus record: %v", err)
+	}
+	existing, err := wo.GetRoundSubmissions(ctx, gridCell, bucket)
+	if err != nil {
+		return err
+	}
+
+	// The deadline runs from when the round opened, so a backfilled reading within
+	// MaxBackfillDays opens a new round for its bucket however old the bucket is
+	if recordJSON != nil ||
+		(len(existing) > 0 && !timestamp.Before(roundDeadline(bucketEnd, roundOpened(existing), config))) {
+		return fmt.Errorf("consensus round %s %s is closed", gridCell, bucket)
+	}
+	for _, data := range existing {
+		if data.OracleID == reading.OracleID {
+			return fmt.Errorf("oracle %s already submitted %s to consensus round %s %s",
+				reading.OracleID, data.DataID, gridCell, bucket)
+		}
+	}
+
+	// A station reading must come from the station's oracle while it is in service
+	if reading.StationID != "" {
+		if err := wo.checkStationReading(ctx, reading, observed); err != nil {
+			return err
+		}
+	}
+
 	weatherData := WeatherData{
-		DataID:          dataID,
-		OracleID:        oracleID,
-		Location:        location,
-		Latitude:        latitude,
-		Longitude:       longitude,
-		Timestamp:       timestamp,
-		Rainfall:        rainfall,
-		Temperature:     temperature,
-		Humidity:        humidity,
-		WindSpeed:       windSpeed,
-		DataHash:        dataHash,
+		DataID:          reading.DataID,
+		OracleID:        reading.OracleID,
+		Location:        reading.Location,
+		Latitude:        reading.Latitude,
+		Longitude:       reading.Longitude,
+		GridCell:        gridCell,
+		Timestamp:       observed,
+		Rainfall:        reading.Rainfall,
+		Temperature:     reading.Temperature,
+		Humidity:        reading.Humidity,
+		WindSpeed:       reading.WindSpeed,
+		DataHash:        hex.EncodeToString(payloadHash[:]),
 		ValidationScore: 0.0,
 		Status:          "Pending",
 		SubmittedBy:     callerID,
+
+		Signature:      signature,
+		KeyFingerprint: oracle.KeyFingerprint,
+
+		ObservationPeriod: reading.Period,
+		SubmittedAt:       timestamp,
+		RoundBucket:       bucket,
+		StationID:         reading.StationID,
 	}
 
 	dataJSON, err := json.Marshal(weatherData)
@@ -256,19 +648,48 @@ func (wo *WeatherOracleChaincode) SubmitWeatherData(ctx contractapi.TransactionC
 		return fmt.Errorf("failed to marshal weather data: %v", err)
 	}
 
-	err = ctx.GetStub().PutState(dataID, dataJSON)
+	err = ctx.GetStub().PutState(reading.DataID, dataJSON)
 	if err != nil {
 		return fmt.Errorf("failed to put weather data: %v", err)
 	}
 
+	roundKey, err := ctx.GetStub().CreateCompositeKey(roundSubmissionObjectType,
+		[]string{gridCell, bucket, reading.DataID})
+	if err != nil {
+		return fmt.Errorf("failed to create round key: %v", err)
+	}
+	err = ctx.GetStub().PutState(roundKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to index weather data: %v", err)
+	}
+
+	if reading.StationID != "" {
+		stationKey, err := ctx.GetStub().CreateCompositeKey(stationReadingObjectType,
+			[]string{reading.StationID, observed.Format(dailyBucketLayout), reading.DataID})
+		if err != nil {
+			return fmt.Errorf("failed to create station reading key: %v", err)
+		}
+		err = ctx.GetStub().PutState(stationKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to index station reading: %v", err)
+		}
+	}
+
 	// Update oracle's last submission time (reuse timestamp from earlier)
 	oracle.LastSubmission = timestamp
+
+	// The reading may complete its round's quorum. It is passed in, as this
+	// transaction's writes are not visible to its own reads.
+	if _, err := wo.closeRound(ctx, gridCell, bucket, &weatherData, oracle); err != nil {
+		return err
+	}
+
 	oracleJSON, err := json.Marshal(oracle)
 	if err != nil {
 		return fmt.Errorf("failed to marshal oracle: %v", err)
 	}
 
-	err = ctx.GetStub().PutState("ORACLE_"+oracleID, oracleJSON)
+	err = ctx.GetStub().PutState("ORACLE_"+reading.OracleID, oracleJSON)
 	if err != nil {
 		return fmt.Errorf("failed to update oracle: %v", err)
 	}
@@ -342,115 +763,356 @@ func (wo *WeatherOracleChaincode) GetWeatherByRegion(ctx contractapi.Transaction
 // CONSENSUS & VALIDATION
 // ========================================
 
-// ValidateDataConsensus implements 2/3 consensus across multiple oracles
-func (wo *WeatherOracleChaincode) ValidateDataConsensus(ctx contractapi.TransactionContextInterface,
-	location string, timestampStr string, dataIDs []string) (bool, error) {
+// SetConsensusConfig replaces the quorum rule, per-metric tolerances and reputation settings.
+// The caller must be a platform administrator, or present an approval request naming
+// SetConsensusConfig with the exact config JSON as its first argument.
+func (wo *WeatherOracleChaincode) SetConsensusConfig(ctx contractapi.TransactionContextInterface,
+	configJSON string, approvalRequestID string) error {
 
-	if len(dataIDs) < 2 {
-		return false, fmt.Errorf("need at least 2 oracle submissions for consensus")
+	if err := authorizeAdmin(ctx, "SetConsensusConfig", configJSON, approvalRequestID); err != nil {
+		return err
 	}
 
-	timestamp, err := time.Parse(time.RFC3339, timestampStr)
-	if err != nil {
-		return false, fmt.Errorf("invalid timestamp format: %v", err)
+	var config ConsensusConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return fmt.Errorf("failed to parse consensus config: %v", err)
 	}
 
-	// Collect all weather data submissions
-	var submissions []*WeatherData
-	for _, dataID := range dataIDs {
-		data, err := wo.GetWeatherData(ctx, dataID)
-		if err != nil {
-			continue
+	if config.MinSubmissions < 2 {
+		return fmt.Errorf("minimum submissions must be at least 2")
+	}
+	if config.QuorumDenominator <= 0 || config.QuorumNumerator <= 0 || config.QuorumNumerator > config.QuorumDenominator {
+		return fmt.Errorf("quorum must be a fraction between 0 and 1")
+	}
+	for _, metric := range consensusMetrics {
+		tolerance, ok := config.Tolerances[metric]
+		if !ok {
+			return fmt.Errorf("tolerance for %s is required", metric)
+		}
+		if tolerance.Absolute < 0 || tolerance.Relative < 0 || tolerance.MADMultiplier < 0 {
+			return fmt.Errorf("tolerance for %s cannot be negative", metric)
+		}
+		if tolerance.Absolute == 0 && tolerance.Relative == 0 {
+			return fmt.Errorf("tolerance for %s needs an absolute or relative bound", metric)
 		}
-		submissions = append(submissions, data)
 	}
-
-	if len(submissions) < 2 {
-		return false, fmt.Errorf("insufficient valid submissions")
+	for metric := range config.Tolerances {
+		if !isConsensusMetric(metric) {
+			return fmt.Errorf("unknown consensus metric: %s", metric)
+		}
 	}
 
-	// Calculate average values
-	var totalRainfall, totalTemp, totalHumidity float64
-	for _, data := range submissions {
-		totalRainfall += data.Rainfall
-		totalTemp += data.Temperature
-		totalHumidity += data.Humidity
+	if config.Aggregation != "WeightedMedian" && config.Aggregation != "WeightedMean" {
+		return fmt.Errorf("invalid aggregation: %s", config.Aggregation)
+	}
+	if config.ReputationAlpha <= 0 || config.ReputationAlpha > 1 {
+		return fmt.Errorf("reputation alpha must be greater than 0 and at most 1")
+	}
+	if config.SuspendBelow < 0 || config.SuspendBelow >= 100 {
+		return fmt.Errorf("suspension threshold must be between 0 and 100")
+	}
+	if config.ReinstatementScore <= config.SuspendBelow || config.ReinstatementScore > 100 {
+		return fmt.Errorf("reinstatement score must be above the suspension threshold and at most 100")
+	}
+	if config.ProbationSubmissions < 0 {
+		return fmt.Errorf("probation submissions cannot be negative")
+	}
+	if config.ProbationWeight <= 0 || config.ProbationWeight > 1 {
+		return fmt.Errorf("probation weight must be greater than 0 and at most 1")
+	}
+	if config.MaxFutureMinutes < 0 {
+		return fmt.Errorf("max future minutes cannot be negative")
+	}
+	if config.MaxBackfillDays < 1 {
+		return fmt.Errorf("max backfill days must be at least 1")
+	}
+	if config.RoundDeadlineHours < 1 {
+		return fmt.Errorf("round deadline hours must be at least 1")
 	}
 
-	count := float64(len(submissions))
-	avgRainfall := totalRainfall / count
-	avgTemp := totalTemp / count
-	avgHumidity := totalHumidity / count
-
-	// Check if values are within acceptable variance (20%)
-	consensusThreshold := 0.20
-	consensusCount := 0
+	// Get caller identity
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
 
-	for _, data := range submissions {
-		rainfallDiff := abs(data.Rainfall-avgRainfall) / avgRainfall
-		tempDiff := abs(data.Temperature-avgTemp) / abs(avgTemp)
-		humidityDiff := abs(data.Humidity-avgHumidity) / avgHumidity
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	config.UpdatedBy = callerID
+	config.UpdatedDate = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+	config.ApprovalRequestID = approvalRequestID
 
-		// Count as consensus if all metrics within threshold
-		if rainfallDiff <= consensusThreshold &&
-			tempDiff <= consensusThreshold &&
-			humidityDiff <= consensusThreshold {
-			consensusCount++
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal consensus config: %v", err)
+	}
 
-			// Update data status to validated
-			data.Status = "Validated"
-			data.ValidationScore = 100.0
+	err = ctx.GetStub().PutState(consensusConfigKey, configBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put consensus config: %v", err)
+	}
 
-			dataJSON, _ := json.Marshal(data)
-			ctx.GetStub().PutState(data.DataID, dataJSON)
-		} else {
-			// Mark as anomalous
-			data.Status = "Anomalous"
-			data.ValidationScore = 0.0
+	return nil
+}
 
-			dataJSON, _ := json.Marshal(data)
-			ctx.GetStub().PutState(data.DataID, dataJSON)
+// GetConsensusConfig returns the consensus configuration in force
+func (wo *WeatherOracleChaincode) GetConsensusConfig(ctx contractapi.TransactionContextInterface) (*ConsensusConfig, error) {
+	configJSON, err := ctx.GetStub().GetState(consensusConfigKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consensus config: %v", err)
+	}
+	if configJSON == nil {
+		return defaultConsensusConfig(), nil
+	}
 
-			// Update oracle reputation
-			wo.UpdateOracleReputation(ctx, data.OracleID, true)
-		}
+	var config ConsensusConfig
+	err = json.Unmarshal(configJSON, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal consensus config: %v", err)
 	}
 
-	// Require 2/3 consensus
-	required := int(float64(len(submissions)) * 2.0 / 3.0)
-	consensusReached := consensusCount >= required
+	// Configs stored before reputation weighting use the default reputation settings
+	if config.ReputationAlpha == 0 {
+		defaults := defaultConsensusConfig()
+		config.Aggregation = defaults.Aggregation
+		config.ReputationAlpha = defaults.ReputationAlpha
+		config.SuspendBelow = defaults.SuspendBelow
+		config.ReinstatementScore = defaults.ReinstatementScore
+		config.ProbationSubmissions = defaults.ProbationSubmissions
+		config.ProbationWeight = defaults.ProbationWeight
+	}
 
-	// Get deterministic transaction timestamp
-	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
-	if err != nil {
-		return false, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	// Configs stored before observation times were checked use the default bounds
+	if config.MaxBackfillDays == 0 {
+		defaults := defaultConsensusConfig()
+		config.MaxFutureMinutes = defaults.MaxFutureMinutes
+		config.MaxBackfillDays = defaults.MaxBackfillDays
 	}
-	currentTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
-	// Store consensus record
-	recordID := fmt.Sprintf("CONSENSUS_%s_%d", location, timestamp.Unix())
-	consensusRec := ConsensusRecord{
-		RecordID:    recordID,
-		Location:    location,
-		Timestamp:   timestamp,
-		OracleCount: len(submissions),
-		Consensus: map[string]float64{
-			"rainfall":    avgRainfall,
-			"temperature": avgTemp,
-			"humidity":    avgHumidity,
-		},
-		ConsensusReached: consensusReached,
-		CreatedDate:      currentTime,
+	// Configs stored before consensus rounds use the default round deadline
+	if config.RoundDeadlineHours == 0 {
+		config.RoundDeadlineHours = defaultConsensusConfig().RoundDeadlineHours
 	}
 
-	recordJSON, err := json.Marshal(consensusRec)
-	if err != nil {
-		return false, fmt.Errorf("failed to marshal consensus record: %v", err)
+	return &config, nil
+}
+
+// ValidateDataConsensus evaluates the consensus round for a grid cell and observation
+// bucket over every submission indexed to it; callers cannot choose the submissions.
+// Submissions from suspended oracles are ignored and outliers are rejected by a
+// median/MAD test. Each metric's consensus is the reputation-weighted median (or mean)
+// of the rest, and a submission agrees when every metric lies within tolerance of the
+// consensus. The round closes once a quorum agrees, marking the others Anomalous, or once
+// its deadline passes without one; while it stays open nothing is written.
+// SubmitWeatherData closes rounds on quorum itself, so this is mainly for deadlines.
+func (wo *WeatherOracleChaincode) ValidateDataConsensus(ctx contractapi.TransactionContextInterface,
+	gridCell string, bucket string) (bool, error) {
+
+	if _, _, _, err := parseRoundBucket(bucket); err != nil {
+		return false, err
 	}
 
-	err = ctx.GetStub().PutState(recordID, recordJSON)
+	recordJSON, err := ctx.GetStub().GetState(consensusRecordID(gridCell, bucket))
 	if err != nil {
-		return false, fmt.Errorf("failed to store consensus record: %v", err)
+		return false, fmt.Errorf("failed to read consensus record: %v", err)
+	}
+	if recordJSON != nil {
+		return false, fmt.Errorf("consensus round %s %s is already closed", gridCell, bucket)
+	}
+
+	return wo.closeRound(ctx, gridCell, bucket, nil, nil)
+}
+
+// closeRound evaluates an open consensus round and closes it on quorum or deadline.
+// A submission written in the same transaction is passed as fresh, with its oracle,
+// since the round index and state reads do not see it yet.
+func (wo *WeatherOracleChaincode) closeRound(ctx contractapi.TransactionContextInterface,
+	gridCell string, bucket string, fresh *WeatherData, freshOracle *OracleProvider) (bool, error) {
+
+	config, err := wo.GetConsensusConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	period, bucketStart, bucketEnd, err := parseRoundBucket(bucket)
+	if err != nil {
+		return false, err
+	}
+	recordID := consensusRecordID(gridCell, bucket)
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return false, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	currentTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	dataIDs, err := wo.roundDataIDs(ctx, gridCell, bucket)
+	if err != nil {
+		return false, err
+	}
+	oracles := make(map[string]*OracleProvider)
+	if fresh != nil {
+		dataIDs = append(dataIDs, fresh.DataID)
+		sort.Strings(dataIDs)
+		oracles[freshOracle.OracleID] = freshOracle
+	}
+
+	// Collect every submission in the round
+	var loaded, submissions []*WeatherData
+	for _, dataID := range dataIDs {
+		data := fresh
+		if fresh == nil || dataID != fresh.DataID {
+			data, err = wo.GetWeatherData(ctx, dataID)
+			if err != nil {
+				return false, err
+			}
+		}
+		loaded = append(loaded, data)
+
+		// Suspended and revoked oracles no longer count towards consensus
+		if _, loaded := oracles[data.OracleID]; !loaded {
+			oracle, err := wo.GetOracleProvider(ctx, data.OracleID)
+			if err != nil {
+				return false, err
+			}
+			oracles[data.OracleID] = oracle
+		}
+		if oracles[data.OracleID].Status != "Active" {
+			continue
+		}
+		submissions = append(submissions, data)
+	}
+
+	// A round nobody submitted to has not opened; closing it would shut out backfill
+	if len(loaded) == 0 {
+		return false, nil
+	}
+
+	deadline := roundDeadline(bucketEnd, roundOpened(loaded), config)
+	pastDeadline := !currentTime.Before(deadline)
+
+	// Quorum counts distinct oracles, so no oracle can make up a quorum on its own
+	distinct := make(map[string]bool)
+	for _, data := range submissions {
+		distinct[data.OracleID] = true
+	}
+
+	consensus := make(map[string]float64)
+	var deviations []*SubmissionDeviation
+	agreeing, required := 0, config.MinSubmissions
+	if len(distinct) >= config.MinSubmissions {
+		consensus, deviations, _ = reconcileSubmissions(submissions, oracles, config)
+
+		// An oracle agrees only if every one of its submissions is within tolerance
+		dissenting := make(map[string]bool)
+		for i, data := range submissions {
+			if !deviations[i].WithinTolerance {
+				dissenting[data.OracleID] = true
+			}
+		}
+		agreeing = len(distinct) - len(dissenting)
+
+		// Quorum rounds up, so one of two oracles is never enough at 2/3
+		required = (len(distinct)*config.QuorumNumerator + config.QuorumDenominator - 1) / config.QuorumDenominator
+	}
+	consensusReached := len(distinct) >= config.MinSubmissions && agreeing >= required
+
+	// Later submissions may still bring a quorum until the deadline
+	if !consensusReached && !pastDeadline {
+		return false, nil
+	}
+
+	// Submissions are only judged once a quorum agrees; without one there is no telling
+	// which oracle is wrong, so they stay Pending with their deviations recorded
+	for i := range deviations {
+		data := submissions[i]
+		data.Deviations = deviations[i].Deviations
+		if consensusReached && deviations[i].WithinTolerance {
+			data.Status = "Validated"
+			data.ValidationScore = 100.0
+		} else if consensusReached {
+			data.Status = "Anomalous"
+			data.ValidationScore = 0.0
+		}
+
+		dataJSON, err := json.Marshal(data)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal weather data: %v", err)
+		}
+		err = ctx.GetStub().PutState(data.DataID, dataJSON)
+		if err != nil {
+			return false, fmt.Errorf("failed to update weather data: %v", err)
+		}
+	}
+
+	// Every judged submission moves its oracle's reputation. Oracles are updated in
+	// memory and written once, as a second read of the same key would not see the first write.
+	joined := make(map[string]bool)
+	for i, data := range submissions {
+		if consensusReached {
+			judgeOracle(oracles[data.OracleID], !deviations[i].WithinTolerance, config, currentTime)
+		}
+		joined[data.OracleID] = true
+	}
+	participants := make([]string, 0, len(joined))
+	for oracleID := range joined {
+		participants = append(participants, oracleID)
+	}
+	sort.Strings(participants)
+	if consensusReached {
+		for _, oracleID := range participants {
+			if err := wo.putOracle(ctx, oracles[oracleID]); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// The round's location label is only descriptive; the grid cell identifies it
+	location := ""
+	if len(submissions) > 0 {
+		location = submissions[0].Location
+	}
+
+	closeReason := "Quorum"
+	if !consensusReached {
+		closeReason = "Deadline"
+	}
+
+	// Store consensus record; its presence closes the round
+	consensusRec := ConsensusRecord{
+		RecordID:         recordID,
+		Location:         location,
+		GridCell:         gridCell,
+		Timestamp:        bucketStart,
+		OracleCount:      len(submissions),
+		Consensus:        consensus,
+		ConsensusReached: consensusReached,
+		CreatedDate:      currentTime,
+		AgreeingCount:    agreeing,
+		RequiredCount:    required,
+		Submissions:      deviations,
+
+		Bucket:       bucket,
+		Period:       period,
+		Deadline:     deadline,
+		Status:       "Closed",
+		CloseReason:  closeReason,
+		Participants: participants,
+		DataIDs:      dataIDs,
+	}
+
+	recordJSON, err := json.Marshal(consensusRec)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal consensus record: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(recordID, recordJSON)
+	if err != nil {
+		return false, fmt.Errorf("failed to store consensus record: %v", err)
 	}
 
 	// If consensus reached, trigger automatic policy threshold checking
@@ -461,8 +1123,9 @@ func (wo *WeatherOracleChaincode) ValidateDataConsensus(ctx contractapi.Transact
 
 		// Emit an event for external monitoring systems
 		err = ctx.GetStub().SetEvent("ConsensusReached", []byte(fmt.Sprintf(
-			`{"location":"%s","timestamp":"%s","rainfall":%.2f,"temperature":%.2f,"humidity":%.2f}`,
-			location, timestamp.Format(time.RFC3339), avgRainfall, avgTemp, avgHumidity,
+			`{"location":"%s","bucket":"%s","gridCell":"%s","timestamp":"%s","rainfall":%.2f,"temperature":%.2f,"humidity":%.2f,"windSpeed":%.2f}`,
+			location, bucket, gridCell, bucketStart.Format(time.RFC3339),
+			consensus["rainfall"], consensus["temperature"], consensus["humidity"], consensus["windSpeed"],
 		)))
 		if err != nil {
 			// Log but don't fail - event emission is not critical
@@ -473,9 +1136,15 @@ func (wo *WeatherOracleChaincode) ValidateDataConsensus(ctx contractapi.Transact
 	return consensusReached, nil
 }
 
-// FlagAnomalousData marks suspicious or outlier data
+// FlagAnomalousData marks suspicious or outlier data and counts it against the oracle.
+// Like UpdateOracleReputation, it needs a platform administrator or an approval request
+// naming FlagAnomalousData with the data ID as its first argument.
 func (wo *WeatherOracleChaincode) FlagAnomalousData(ctx contractapi.TransactionContextInterface,
-	dataID string, reason string) error {
+	dataID string, reason string, approvalRequestID string) error {
+
+	if err := authorizeAdmin(ctx, "FlagAnomalousData", dataID, approvalRequestID); err != nil {
+		return err
+	}
 
 	data, err := wo.GetWeatherData(ctx, dataID)
 	if err != nil {
@@ -496,7 +1165,7 @@ func (wo *WeatherOracleChaincode) FlagAnomalousData(ctx contractapi.TransactionC
 	}
 
 	// Update oracle reputation
-	err = wo.UpdateOracleReputation(ctx, data.OracleID, true)
+	err = wo.recordJudgement(ctx, data.OracleID, true)
 	if err != nil {
 		return err
 	}
@@ -504,17 +1173,15 @@ func (wo *WeatherOracleChaincode) FlagAnomalousData(ctx contractapi.TransactionC
 	return nil
 }
 
-// GetConsensusData retrieves validated consensus weather data
+// GetConsensusData retrieves the closed consensus round for a grid cell and bucket
 func (wo *WeatherOracleChaincode) GetConsensusData(ctx contractapi.TransactionContextInterface,
-	location string, timestampStr string) (*ConsensusRecord, error) {
+	gridCell string, bucket string) (*ConsensusRecord, error) {
 
-	timestamp, err := time.Parse(time.RFC3339, timestampStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid timestamp format: %v", err)
+	if _, _, _, err := parseRoundBucket(bucket); err != nil {
+		return nil, err
 	}
 
-	recordID := fmt.Sprintf("CONSENSUS_%s_%d", location, timestamp.Unix())
-	recordJSON, err := ctx.GetStub().GetState(recordID)
+	recordJSON, err := ctx.GetStub().GetState(consensusRecordID(gridCell, bucket))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read consensus record: %v", err)
 	}
@@ -531,10 +1198,417 @@ func (wo *WeatherOracleChaincode) GetConsensusData(ctx contractapi.TransactionCo
 	return &record, nil
 }
 
+// GetRoundSubmissions lists every submission indexed to a consensus round
+func (wo *WeatherOracleChaincode) GetRoundSubmissions(ctx contractapi.TransactionContextInterface,
+	gridCell string, bucket string) ([]*WeatherData, error) {
+
+	dataIDs, err := wo.roundDataIDs(ctx, gridCell, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	submissions := make([]*WeatherData, 0, len(dataIDs))
+	for _, dataID := range dataIDs {
+		data, err := wo.GetWeatherData(ctx, dataID)
+		if err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, data)
+	}
+
+	return submissions, nil
+}
+
 // ========================================
 // HELPER FUNCTIONS
 // ========================================
 
+// parseWeatherPayload decodes a signed reading and insists it is in canonical form, so
+// the signed bytes and the stored fields cannot disagree
+func parseWeatherPayload(payload string) (*WeatherPayload, error) {
+	var reading WeatherPayload
+	decoder := json.NewDecoder(bytes.NewReader([]byte(payload)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&reading); err != nil {
+		return nil, fmt.Errorf("failed to parse weather payload: %v", err)
+	}
+
+	canonical, err := json.Marshal(reading)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal weather payload: %v", err)
+	}
+	if string(canonical) != payload {
+		return nil, fmt.Errorf("weather payload is not canonical; expected %s", canonical)
+	}
+
+	if reading.DataID == "" || reading.OracleID == "" || reading.Location == "" {
+		return nil, fmt.Errorf("dataID, oracleID and location are required")
+	}
+
+	return &reading, nil
+}
+
+// validateReading checks that a reading's values are physically plausible
+func validateReading(reading *WeatherPayload) error {
+	if reading.Rainfall < 0 || reading.Rainfall > 1000 {
+		return fmt.Errorf("invalid rainfall value: %.2f", reading.Rainfall)
+	}
+	if reading.Temperature < -50 || reading.Temperature > 60 {
+		return fmt.Errorf("invalid temperature value: %.2f", reading.Temperature)
+	}
+	if reading.Humidity < 0 || reading.Humidity > 100 {
+		return fmt.Errorf("invalid humidity value: %.2f", reading.Humidity)
+	}
+	if reading.WindSpeed < 0 || reading.WindSpeed > 400 {
+		return fmt.Errorf("invalid wind speed value: %.2f", reading.WindSpeed)
+	}
+	if reading.Latitude < -90 || reading.Latitude > 90 || reading.Longitude < -180 || reading.Longitude > 180 {
+		return fmt.Errorf("invalid coordinates: %.6f, %.6f", reading.Latitude, reading.Longitude)
+	}
+	return nil
+}
+
+// observationTime parses a reading's observation time and checks it against the
+// submission bounds: no later than MaxFutureMinutes after the transaction time, to allow
+// for clock skew, and no older than MaxBackfillDays
+func observationTime(reading *WeatherPayload, config *ConsensusConfig, submitted time.Time) (time.Time, error) {
+	if !observationPeriods[reading.Period] {
+		return time.Time{}, fmt.Errorf("invalid observation period: %s", reading.Period)
+	}
+
+	observed, err := time.Parse(time.RFC3339, reading.ObservationTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid observation time: %v", err)
+	}
+	observed = observed.UTC()
+
+	latest := submitted.Add(time.Duration(config.MaxFutureMinutes) * time.Minute)
+	if observed.After(latest) {
+		return time.Time{}, fmt.Errorf("observation time %s is more than %d minutes in the future",
+			reading.ObservationTime, config.MaxFutureMinutes)
+	}
+	earliest := submitted.AddDate(0, 0, -config.MaxBackfillDays)
+	if observed.Before(earliest) {
+		return time.Time{}, fmt.Errorf("observation time %s is more than %d days old",
+			reading.ObservationTime, config.MaxBackfillDays)
+	}
+
+	return observed, nil
+}
+
+// parseOracleKey reads a PEM public key and returns it with its algorithm and fingerprint
+func parseOracleKey(publicKeyPEM string) (interface{}, string, string, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, "", "", fmt.Errorf("public key must be a PEM PUBLIC KEY block")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to parse public key: %v", err)
+	}
+
+	var algorithm string
+	switch typed := key.(type) {
+	case *ecdsa.PublicKey:
+		if typed.Curve != elliptic.P256() {
+			return nil, "", "", fmt.Errorf("ECDSA keys must use the P-256 curve")
+		}
+		algorithm = "ECDSA-P256"
+	case ed25519.PublicKey:
+		algorithm = "Ed25519"
+	default:
+		return nil, "", "", fmt.Errorf("unsupported public key type %T", key)
+	}
+
+	fingerprint := sha256.Sum256(block.Bytes)
+	return key, algorithm, hex.EncodeToString(fingerprint[:]), nil
+}
+
+// verifyOracleSignature checks a base64 signature over the payload with the oracle's key
+func verifyOracleSignature(oracle *OracleProvider, payload []byte, signature string) error {
+	if oracle.PublicKey == "" {
+		return fmt.Errorf("oracle %s has no registered public key", oracle.OracleID)
+	}
+
+	key, _, _, err := parseOracleKey(oracle.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("signature must be base64: %v", err)
+	}
+
+	valid := false
+	switch typed := key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(payload)
+		valid = ecdsa.VerifyASN1(typed, digest[:], sig)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(typed, payload, sig)
+	}
+	if !valid {
+		return fmt.Errorf("signature does not match the payload for oracle %s", oracle.OracleID)
+	}
+
+	return nil
+}
+
+// bindSubmitter returns the submitter binding for the caller's identity; callers
+// authorise the registration or rebinding first
+func bindSubmitter(ctx contractapi.TransactionContextInterface,
+	submitterAttribute string) (*OracleProvider, error) {
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller MSP: %v", err)
+	}
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	submitter := &OracleProvider{
+		SubmitterMSP: mspID,
+		SubmitterID:  callerID,
+		RegisteredBy: callerID,
+	}
+
+	// An attribute binding admits any certificate from the MSP that carries it,
+	// starting with the registrant's own
+	if submitterAttribute != "" {
+		name, value, found := strings.Cut(submitterAttribute, "=")
+		if !found || name == "" || value == "" {
+			return nil, fmt.Errorf("submitter attribute must be name=value")
+		}
+		if err := ctx.GetClientIdentity().AssertAttributeValue(name, value); err != nil {
+			return nil, fmt.Errorf("caller does not carry submitter attribute %s: %v", submitterAttribute, err)
+		}
+		submitter.SubmitterID = ""
+		submitter.SubmitterAttribute = submitterAttribute
+	}
+
+	return submitter, nil
+}
+
+// checkSubmitter verifies that the caller is the identity bound to an oracle
+func checkSubmitter(ctx contractapi.TransactionContextInterface, oracle *OracleProvider) error {
+	if oracle.SubmitterMSP == "" {
+		return fmt.Errorf("oracle %s has no bound submitter; call BindOracleSubmitter", oracle.OracleID)
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller MSP: %v", err)
+	}
+	if mspID != oracle.SubmitterMSP {
+		return fmt.Errorf("caller from %s may not submit for oracle %s", mspID, oracle.OracleID)
+	}
+
+	if oracle.SubmitterAttribute != "" {
+		name, value, _ := strings.Cut(oracle.SubmitterAttribute, "=")
+		if err := ctx.GetClientIdentity().AssertAttributeValue(name, value); err != nil {
+			return fmt.Errorf("caller may not submit for oracle %s: %v", oracle.OracleID, err)
+		}
+		return nil
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+	if callerID != oracle.SubmitterID {
+		return fmt.Errorf("caller is not the bound submitter for oracle %s", oracle.OracleID)
+	}
+
+	return nil
+}
+
+// verifyCallerRole checks that the calling identity holds an active, unexpired role
+func verifyCallerRole(ctx contractapi.TransactionContextInterface, roleName string) error {
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+	return verifyRole(ctx, callerID, roleName)
+}
+
+// verifyRole checks that an identity holds an active, unexpired role in access-control
+func verifyRole(ctx contractapi.TransactionContextInterface, entityID string, roleName string) error {
+	rolesJSON, err := invokeChaincode(ctx, accessControlChaincode, "GetRolesByEntity", entityID)
+	if err != nil {
+		return err
+	}
+
+	var roles []struct {
+		RoleName   string    `json:"roleName"`
+		Status     string    `json:"status"`
+		ExpiryDate time.Time `json:"expiryDate"`
+	}
+	if len(rolesJSON) > 0 {
+		err = json.Unmarshal(rolesJSON, &roles)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal roles: %v", err)
+		}
+	}
+
+	// Get deterministic transaction timestamp
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	for _, role := range roles {
+		if role.RoleName != roleName || role.Status != "Active" {
+			continue
+		}
+		if !role.ExpiryDate.IsZero() && now.After(role.ExpiryDate) {
+			continue
+		}
+		return nil
+	}
+
+	return fmt.Errorf("caller does not hold an active %s role", roleName)
+}
+
+// authorizeAdmin admits a platform administrator, or a caller presenting an approval
+// request for the function whose first argument is subject. Each approval request
+// authorises one change, so it cannot be replayed to undo a later one.
+func authorizeAdmin(ctx contractapi.TransactionContextInterface,
+	functionName string, subject string, approvalRequestID string) error {
+
+	if approvalRequestID == "" {
+		return verifyCallerRole(ctx, "PlatformAdmin")
+	}
+
+	if err := verifyFunctionApproval(ctx, approvalRequestID, functionName, subject); err != nil {
+		return err
+	}
+
+	return spendApproval(ctx, approvalRequestID)
+}
+
+// spendApproval records an approval request as used, failing if it already was
+func spendApproval(ctx contractapi.TransactionContextInterface, approvalRequestID string) error {
+	used, err := ctx.GetStub().GetState(usedApprovalPrefix + approvalRequestID)
+	if err != nil {
+		return fmt.Errorf("failed to read approval usage: %v", err)
+	}
+	if used != nil {
+		return fmt.Errorf("approval request %s has already been used", approvalRequestID)
+	}
+
+	err = ctx.GetStub().PutState(usedApprovalPrefix+approvalRequestID, []byte(ctx.GetStub().GetTxID()))
+	if err != nil {
+		return fmt.Errorf("failed to record approval usage: %v", err)
+	}
+
+	return nil
+}
+
+// verifyFunctionApproval checks that an approval-manager request authorises a
+// weather-oracle function: it must be approved or executed, and name the function with
+// the subject (an oracle ID, data ID or config) as its first argument
+func verifyFunctionApproval(ctx contractapi.TransactionContextInterface,
+	requestID string, functionName string, subject string) error {
+
+	requestJSON, err := invokeChaincode(ctx, approvalManagerChaincode, "GetApprovalRequest", requestID)
+	if err != nil {
+		return err
+	}
+
+	var request struct {
+		ChaincodeName string   `json:"chaincodeName"`
+		FunctionName  string   `json:"functionName"`
+		Arguments     []string `json:"arguments"`
+		Status        string   `json:"status"`
+	}
+	err = json.Unmarshal(requestJSON, &request)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal approval request: %v", err)
+	}
+
+	if request.Status != "APPROVED" && request.Status != "EXECUTED" {
+		return fmt.Errorf("approval request %s is not approved (status: %s)", requestID, request.Status)
+	}
+	if request.ChaincodeName != "weather-oracle" || request.FunctionName != functionName ||
+		len(request.Arguments) == 0 || request.Arguments[0] != subject {
+		return fmt.Errorf("approval request %s does not authorise %s for %s", requestID, functionName, subject)
+	}
+
+	return nil
+}
+
+// invokeChaincode calls another chaincode on the same channel and returns its payload
+func invokeChaincode(ctx contractapi.TransactionContextInterface,
+	chaincodeName string, functionName string, args ...string) ([]byte, error) {
+
+	invokeArgs := make([][]byte, len(args)+1)
+	invokeArgs[0] = []byte(functionName)
+	for i, arg := range args {
+		invokeArgs[i+1] = []byte(arg)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(chaincodeName, invokeArgs, ctx.GetStub().GetChannelID())
+	if response.Status != 200 {
+		return nil, fmt.Errorf("%s.%s failed: %s", chaincodeName, functionName, response.Message)
+	}
+
+	return response.Payload, nil
+}
+
+// putOracle stores an oracle provider
+func (wo *WeatherOracleChaincode) putOracle(ctx contractapi.TransactionContextInterface, oracle *OracleProvider) error {
+	oracleJSON, err := json.Marshal(oracle)
+	if err != nil {
+		return fmt.Errorf("failed to marshal oracle: %v", err)
+	}
+
+	err = ctx.GetStub().PutState("ORACLE_"+oracle.OracleID, oracleJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update oracle: %v", err)
+	}
+
+	return nil
+}
+
+// judgeOracle folds one judgement into an oracle's reputation as an exponential moving
+// average, so recent behaviour outweighs a long clean history, and suspends the oracle
+// once its reputation falls below the configured floor
+func judgeOracle(oracle *OracleProvider, anomalous bool, config *ConsensusConfig, timestamp time.Time) {
+	oracle.TotalSubmissions++
+	outcome := 100.0
+	if anomalous {
+		oracle.AnomalousCount++
+		outcome = 0
+	}
+	oracle.ReputationScore = (1-config.ReputationAlpha)*oracle.ReputationScore + config.ReputationAlpha*outcome
+
+	if !anomalous && oracle.ProbationRemaining > 0 {
+		oracle.ProbationRemaining--
+	}
+
+	if oracle.Status == "Active" && oracle.ReputationScore < config.SuspendBelow {
+		oracle.Status = "Suspended"
+		oracle.StatusReason = fmt.Sprintf("reputation %.2f fell below %.2f", oracle.ReputationScore, config.SuspendBelow)
+		oracle.StatusChangedBy = ""
+		oracle.StatusChangedDate = timestamp
+	}
+}
+
+// oracleWeight is an oracle's say in the consensus value: its reputation as a fraction,
+// reduced while it is on probation
+func oracleWeight(oracle *OracleProvider, config *ConsensusConfig) float64 {
+	weight := oracle.ReputationScore / 100
+	if oracle.ProbationRemaining > 0 {
+		weight *= config.ProbationWeight
+	}
+	return weight
+}
+
 func (wo *WeatherOracleChaincode) oracleExists(ctx contractapi.TransactionContextInterface, oracleID string) (bool, error) {
 	oracleJSON, err := ctx.GetStub().GetState("ORACLE_" + oracleID)
 	if err != nil {
@@ -551,11 +1625,262 @@ func (wo *WeatherOracleChaincode) weatherDataExists(ctx contractapi.TransactionC
 	return dataJSON != nil, nil
 }
 
-func abs(x float64) float64 {
-	if x < 0 {
-		return -x
+// roundDataIDs lists the submissions indexed to a consensus round, in key order
+func (wo *WeatherOracleChaincode) roundDataIDs(ctx contractapi.TransactionContextInterface,
+	gridCell string, bucket string) ([]string, error) {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(roundSubmissionObjectType,
+		[]string{gridCell, bucket})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query round submissions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var dataIDs []string
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split round key: %v", err)
+		}
+		dataIDs = append(dataIDs, attributes[2])
+	}
+
+	return dataIDs, nil
+}
+
+// roundBucket labels the consensus round an observation falls in: its UTC day for
+// daily readings, its UTC hour for hourly ones, so the two never share a round
+func roundBucket(period string, observed time.Time) string {
+	if period == "Hourly" {
+		return observed.UTC().Format(hourlyBucketLayout)
+	}
+	return observed.UTC().Format(dailyBucketLayout)
+}
+
+// parseRoundBucket returns a bucket's period and the observation window it covers
+func parseRoundBucket(bucket string) (string, time.Time, time.Time, error) {
+	if start, err := time.Parse(dailyBucketLayout, bucket); err == nil {
+		return "Daily", start, start.AddDate(0, 0, 1), nil
+	}
+	if start, err := time.Parse(hourlyBucketLayout, bucket); err == nil {
+		return "Hourly", start, start.Add(time.Hour), nil
+	}
+	return "", time.Time{}, time.Time{}, fmt.Errorf("invalid round bucket %s; expected YYYY-MM-DD or YYYY-MM-DDTHH", bucket)
+}
+
+// roundDeadline is when a round closes without a quorum: RoundDeadlineHours after its
+// bucket ends or, for a round opened later by backfilled readings, after it opened
+func roundDeadline(bucketEnd time.Time, opened time.Time, config *ConsensusConfig) time.Time {
+	start := bucketEnd
+	if opened.After(start) {
+		start = opened
+	}
+	return start.Add(time.Duration(config.RoundDeadlineHours) * time.Hour)
+}
+
+// roundOpened is when a round's first submission reached the ledger, zero if it has none
+func roundOpened(submissions []*WeatherData) time.Time {
+	var opened time.Time
+	for _, data := range submissions {
+		if opened.IsZero() || data.SubmittedAt.Before(opened) {
+			opened = data.SubmittedAt
+		}
+	}
+	return opened
+}
+
+// consensusRecordID is the key of a round's consensus record
+func consensusRecordID(gridCell string, bucket string) string {
+	return fmt.Sprintf("CONSENSUS_%s_%s", gridCell, bucket)
+}
+
+// reconcileSubmissions computes the consensus of a round's submissions and each
+// submission's deviation from it, returning how many agree on every metric
+func reconcileSubmissions(submissions []*WeatherData, oracles map[string]*OracleProvider,
+	config *ConsensusConfig) (map[string]float64, []*SubmissionDeviation, int) {
+
+	// Reject outliers against the median and MAD of every submission; with fewer
+	// than three submissions there is no majority to judge an outlier by
+	outlier := make([]bool, len(submissions))
+	if len(submissions) >= 3 {
+		for _, metric := range consensusMetrics {
+			tolerance := config.Tolerances[metric]
+			if tolerance.MADMultiplier == 0 {
+				continue
+			}
+			values := make([]float64, len(submissions))
+			for i, data := range submissions {
+				values[i] = metricValue(data, metric)
+			}
+			center := median(values)
+			cut := tolerance.MADMultiplier * scaledMAD(values, center, tolerance.Absolute)
+			for i, value := range values {
+				if math.Abs(value-center) > cut {
+					outlier[i] = true
+				}
+			}
+		}
+	}
+
+	// Consensus is the reputation-weighted median or mean of the remaining submissions
+	var inliers []*WeatherData
+	for i, data := range submissions {
+		if !outlier[i] {
+			inliers = append(inliers, data)
+		}
+	}
+	if len(inliers) == 0 {
+		inliers = submissions
+	}
+
+	weights := make([]float64, len(inliers))
+	for i, data := range inliers {
+		weights[i] = oracleWeight(oracles[data.OracleID], config)
+	}
+
+	consensus := make(map[string]float64)
+	allowed := make(map[string]float64)
+	for _, metric := range consensusMetrics {
+		values := make([]float64, len(inliers))
+		for i, data := range inliers {
+			values[i] = metricValue(data, metric)
+		}
+		if config.Aggregation == "WeightedMean" {
+			consensus[metric] = weightedMean(values, weights)
+		} else {
+			consensus[metric] = weightedMedian(values, weights)
+		}
+
+		tolerance := config.Tolerances[metric]
+		allowed[metric] = math.Max(tolerance.Absolute, tolerance.Relative*math.Abs(consensus[metric]))
+	}
+
+	// A submission agrees when every metric is within tolerance of the consensus
+	agreeing := 0
+	deviations := make([]*SubmissionDeviation, len(submissions))
+	for i, data := range submissions {
+		deviation := &SubmissionDeviation{
+			DataID:          data.DataID,
+			OracleID:        data.OracleID,
+			Deviations:      make(map[string]float64),
+			Allowed:         allowed,
+			Outlier:         outlier[i],
+			WithinTolerance: !outlier[i],
+		}
+		for _, metric := range consensusMetrics {
+			deviation.Deviations[metric] = math.Abs(metricValue(data, metric) - consensus[metric])
+			if deviation.Deviations[metric] > allowed[metric] {
+				deviation.WithinTolerance = false
+			}
+		}
+		deviations[i] = deviation
+		if deviation.WithinTolerance {
+			agreeing++
+		}
+	}
+
+	return consensus, deviations, agreeing
+}
+
+// metricValue reads a consensus metric from a submission
+func metricValue(data *WeatherData, metric string) float64 {
+	switch metric {
+	case "rainfall":
+		return data.Rainfall
+	case "temperature":
+		return data.Temperature
+	case "humidity":
+		return data.Humidity
+	case "windSpeed":
+		return data.WindSpeed
+	}
+	return 0
+}
+
+func isConsensusMetric(metric string) bool {
+	for _, name := range consensusMetrics {
+		if name == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// median returns the middle value, averaging the two middle values of an even count
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// weightedMedian returns the value at which half the total weight lies on either side,
+// averaging the two neighbours when the split falls exactly between them. Without any
+// weight it falls back to the plain median.
+func weightedMedian(values []float64, weights []float64) float64 {
+	type weighted struct {
+		value  float64
+		weight float64
+	}
+	points := make([]weighted, len(values))
+	total := 0.0
+	for i := range values {
+		points[i] = weighted{values[i], weights[i]}
+		total += weights[i]
+	}
+	if total <= 0 {
+		return median(values)
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].value < points[j].value })
+
+	cumulative := 0.0
+	for i, point := range points {
+		cumulative += point.weight
+		if cumulative > total/2 {
+			return point.value
+		}
+		if cumulative == total/2 && i+1 < len(points) {
+			return (point.value + points[i+1].value) / 2
+		}
+	}
+	return points[len(points)-1].value
+}
+
+// weightedMean averages values by weight, falling back to the plain mean without any weight
+func weightedMean(values []float64, weights []float64) float64 {
+	sum, total := 0.0, 0.0
+	for i, value := range values {
+		sum += value * weights[i]
+		total += weights[i]
+	}
+	if total <= 0 {
+		for _, value := range values {
+			sum += value
+		}
+		return sum / float64(len(values))
+	}
+	return sum / total
+}
+
+// scaledMAD estimates the spread of readings as 1.4826 times their median absolute
+// deviation, floored so identical readings do not make every other reading an outlier
+func scaledMAD(values []float64, center float64, floor float64) float64 {
+	distances := make([]float64, len(values))
+	for i, value := range values {
+		distances[i] = math.Abs(value - center)
 	}
-	return x
+	return math.Max(1.4826*median(distances), floor)
 }
 
 // ========================================
@@ -256,6 +256,67 @@ deploy_ui() {
     echo ""
 }
 
+# Approve and activate a Draft policy template through the approval manager
+activate_template() {
+    local template_id="$1"
+    local oversight_msp="${TEMPLATE_OVERSIGHT_MSP:-PlatformMSP}"
+    local api="http://localhost:3001/api"
+    
+    local content_hash
+    content_hash=$(curl -s "${api}/policy-templates/${template_id}/content-hash" | jq -r '.data.contentHash // empty')
+    if [ -z "$content_hash" ]; then
+        print_warning "Could not read content hash for ${template_id}"
+        return 1
+    fi
+    
+    local request_id="TEMPLATE_ACTIVATION_${template_id}_$(date +%s)"
+    local response
+    response=$(curl -s -X POST "${api}/approval" \
+        -H "Content-Type: application/json" \
+        -H "X-User-Org: Insurer1" \
+        -d "{
+            \"requestId\": \"${request_id}\",
+            \"requestType\": \"TEMPLATE_ACTIVATION\",
+            \"chaincodeName\": \"policy-template\",
+            \"functionName\": \"ValidateTemplateParameters\",
+            \"arguments\": [\"${template_id}\", \"${content_hash}\"],
+            \"requiredOrgs\": [\"Insurer1MSP\", \"${oversight_msp}\"],
+            \"metadata\": {\"templateID\": \"${template_id}\"}
+        }")
+    if ! echo "$response" | grep -q '"success":true'; then
+        print_warning "Could not create activation approval request: $response"
+        return 1
+    fi
+    
+    for msp in "Insurer1MSP" "$oversight_msp"; do
+        response=$(curl -s -X POST "${api}/approval/${request_id}/approve" \
+            -H "Content-Type: application/json" \
+            -d "{\"approverOrg\": \"${msp}\", \"reason\": \"Template reviewed\"}")
+        if ! echo "$response" | grep -q '"success":true'; then
+            print_warning "Activation approval by ${msp} failed: $response"
+            return 1
+        fi
+    done
+    
+    response=$(curl -s -X POST "${api}/approval/${request_id}/execute" \
+        -H "X-User-Org: Insurer1")
+    if ! echo "$response" | grep -q '"success":true'; then
+        print_warning "Could not execute activation approval request: $response"
+        return 1
+    fi
+    
+    response=$(curl -s -X POST "${api}/policy-templates/${template_id}/activate" \
+        -H "Content-Type: application/json" \
+        -H "X-User-Org: Insurer1" \
+        -d "{\"approvalRequestId\": \"${request_id}\"}")
+    if ! echo "$response" | grep -q '"success":true'; then
+        print_warning "Could not activate ${template_id}: $response"
+        return 1
+    fi
+    
+    print_success "Policy template activated (approval ${request_id})"
+}
+
 # Step 6: Seed demo data
 seed_demo_data() {
     print_step "Step 6: Seeding Demo Data"
@@ -286,7 +347,7 @@ seed_demo_data() {
         
         # Add drought threshold to template
         print_info "Adding drought threshold to template..."
-        curl -s -X POST "http://localhost:3001/api/policy-templates/TEMPLATE_RICE_DROUGHT_001/thresholds" \
+        THRESHOLD_RESPONSE=$(curl -s -X POST "http://localhost:3001/api/policy-templates/TEMPLATE_RICE_DROUGHT_001/thresholds" \
             -H "Content-Type: application/json" \
             -H "X-User-Org: Insurer1" \
             -d '{
@@ -297,16 +358,20 @@ seed_demo_data() {
                 "measurementDays": 30,
                 "payoutPercent": 75,
                 "severity": "Severe"
-            }' > /dev/null 2>&1
+            }')
         
-        print_success "Drought threshold added"
+        if echo "$THRESHOLD_RESPONSE" | grep -q '"success":true'; then
+            print_success "Drought threshold added"
+        else
+            print_warning "Could not add drought threshold: $THRESHOLD_RESPONSE"
+        fi
         
-        # Activate the template
+        # Activation needs an executed approval request for the template's current content,
+        # approved by an insurer and by an org registered as Regulator or Auditor
         print_info "Activating policy template..."
-        curl -s -X POST "http://localhost:3001/api/policy-templates/TEMPLATE_RICE_DROUGHT_001/activate" \
-            -H "X-User-Org: Insurer1" > /dev/null 2>&1
-        
-        print_success "Policy template activated"
+        if ! activate_template "TEMPLATE_RICE_DROUGHT_001"; then
+            print_warning "Policy template left in Draft"
+        fi
     else
         print_warning "Could not create policy template"
     fi
@@ -39,6 +39,7 @@ const typeLabels: Record<ApprovalRequestType, string> = {
   POLICY_CREATION: 'Policy Creation',
   CLAIM_APPROVAL: 'Claim Approval',
   POOL_WITHDRAWAL: 'Pool Withdrawal',
+  TEMPLATE_ACTIVATION: 'Template Activation',
 };
 
 export const ApprovalCard: React.FC<ApprovalCardProps> = ({
@@ -9,6 +9,7 @@ import {
   Box,
   Alert,
   CircularProgress,
+  MenuItem,
 } from '@mui/material';
 import { useForm, Controller } from 'react-hook-form';
 import { weatherOracleService } from '../../services';
@@ -37,14 +38,16 @@ export const WeatherDataForm: React.FC<WeatherDataFormProps> = ({
     defaultValues: {
       dataID: '',
       oracleID: '',
-      farmerID: '',
+      stationID: '',
+      location: '',
       latitude: 0,
       longitude: 0,
       temperature: 0,
       rainfall: 0,
       humidity: 0,
       windSpeed: 0,
-      timestamp: new Date().toISOString(),
+      observationTime: new Date().toISOString().slice(0, 16),
+      period: 'Hourly',
     },
   });
 
@@ -53,7 +56,11 @@ export const WeatherDataForm: React.FC<WeatherDataFormProps> = ({
       setLoading(true);
       setError('');
 
-      const response = await weatherOracleService.submitWeatherData(data);
+      const response = await weatherOracleService.submitWeatherData({
+        ...data,
+        stationID: data.stationID || undefined,
+        observationTime: new Date(data.observationTime).toISOString(),
+      });
 
       if (response.success) {
         reset();
@@ -127,37 +134,64 @@ export const WeatherDataForm: React.FC<WeatherDataFormProps> = ({
             />
 
             <Controller
-              name="farmerID"
+              name="location"
               control={control}
-              rules={{ required: 'Farmer ID is required' }}
+              rules={{ required: 'Location is required' }}
               render={({ field }) => (
                 <TextField
                   {...field}
-                  label="Farmer ID"
-                  error={!!errors.farmerID}
-                  helperText={errors.farmerID?.message}
+                  label="Location"
+                  error={!!errors.location}
+                  helperText={errors.location?.message}
                   fullWidth
                 />
               )}
             />
 
             <Controller
-              name="timestamp"
+              name="stationID"
               control={control}
-              rules={{ required: 'Timestamp is required' }}
+              render={({ field }) => (
+                <TextField {...field} label="Station ID (optional)" fullWidth />
+              )}
+            />
+
+            <Controller
+              name="observationTime"
+              control={control}
+              rules={{ required: 'Observation time is required' }}
               render={({ field }) => (
                 <TextField
                   {...field}
-                  label="Timestamp"
+                  label="Observation Time"
                   type="datetime-local"
-                  error={!!errors.timestamp}
-                  helperText={errors.timestamp?.message}
+                  error={!!errors.observationTime}
+                  helperText={errors.observationTime?.message}
                   fullWidth
                   InputLabelProps={{ shrink: true }}
                 />
               )}
             />
 
+            <Controller
+              name="period"
+              control={control}
+              rules={{ required: 'Period is required' }}
+              render={({ field }) => (
+                <TextField
+                  {...field}
+                  select
+                  label="Period"
+                  error={!!errors.period}
+                  helperText={errors.period?.message}
+                  fullWidth
+                >
+                  <MenuItem value="Hourly">Hourly</MenuItem>
+                  <MenuItem value="Daily">Daily</MenuItem>
+                </TextField>
+              )}
+            />
+
             <Controller
               name="latitude"
               control={control}
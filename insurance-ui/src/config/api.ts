@@ -29,7 +29,8 @@ export const ENDPOINTS = {
     GET: '/policy-template',
     SET_THRESHOLD: '/policy-template/set-threshold',
     LIST: '/policy-template/list',
-    ACTIVATE: '/policy-template/activate',
+    ACTIVATE: '/policy-templates',
+    CONTENT_HASH: '/policy-templates',
   },
 
   // Policies
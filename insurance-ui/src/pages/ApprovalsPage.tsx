@@ -74,6 +74,7 @@ export const ApprovalsPage: React.FC = () => {
     POLICY_CREATION: 'Policy Creation',
     CLAIM_APPROVAL: 'Claim Approval',
     POOL_WITHDRAWAL: 'Pool Withdrawal',
+    TEMPLATE_ACTIVATION: 'Template Activation',
   };
 
   const columns: Column<ApprovalRequest>[] = [
@@ -552,6 +553,7 @@ export const ApprovalsPage: React.FC = () => {
             <MenuItem value="POLICY_CREATION">Policy Creation</MenuItem>
             <MenuItem value="CLAIM_APPROVAL">Claim Approval</MenuItem>
             <MenuItem value="POOL_WITHDRAWAL">Pool Withdrawal</MenuItem>
+            <MenuItem value="TEMPLATE_ACTIVATION">Template Activation</MenuItem>
           </TextField>
         </Box>
       </Paper>
@@ -2,6 +2,7 @@ import apiService from './api.service';
 import { ENDPOINTS } from '../config/api';
 import type { Policy, PolicyTemplate, ApiResponse } from '../types/blockchain';
 import { mockPolicyTemplates, mockPolicies } from '../data/mockData';
+import { approvalService } from './approval.service';
 
 export interface CreatePolicyTemplateDto {
   templateID: string;
@@ -14,6 +15,13 @@ export interface CreatePolicyTemplateDto {
   temperatureThreshold?: number;
 }
 
+export interface TemplateDigest {
+  templateID: string;
+  version: number;
+  status: string;
+  contentHash: string;
+}
+
 export interface CreatePolicyDto {
   policyID: string;
   farmerID: string;
@@ -70,10 +78,56 @@ export const policyTemplateService = {
   },
 
   /**
-   * Activate a policy template
+   * Get the content hash an activation approval request must carry
    */
-  async activateTemplate(templateID: string): Promise<ApiResponse<void>> {
-    return apiService.post<void>(ENDPOINTS.POLICY_TEMPLATE.ACTIVATE, { templateID }, undefined, undefined);
+  async getContentHash(templateID: string): Promise<ApiResponse<TemplateDigest>> {
+    const mockDigest: TemplateDigest = { templateID, version: 1, status: 'Draft', contentHash: 'mock-content-hash' };
+    return apiService.get<TemplateDigest>(`${ENDPOINTS.POLICY_TEMPLATE.CONTENT_HASH}/${templateID}/content-hash`, undefined, mockDigest);
+  },
+
+  /**
+   * Activate a policy template. Activation needs an executed approval request for the
+   * template's current content, approved by an insurer and a regulator or auditor org,
+   * so the request is created, approved by each approver and executed first.
+   */
+  async activateTemplate(templateID: string, approverOrgs: string[]): Promise<ApiResponse<void>> {
+    const digest = await this.getContentHash(templateID);
+    if (!digest.success || !digest.data) {
+      return { success: false, error: digest.error || 'Failed to read template content hash' };
+    }
+
+    const requestId = `TEMPLATE_ACTIVATION_${templateID}_${Date.now()}`;
+    const created = await approvalService.createApprovalRequest({
+      requestId,
+      requestType: 'TEMPLATE_ACTIVATION',
+      chaincodeName: 'policy-template',
+      functionName: 'ValidateTemplateParameters',
+      arguments: [templateID, digest.data.contentHash],
+      requiredOrgs: approverOrgs,
+      metadata: { templateID },
+    });
+    if (!created.success) {
+      return { success: false, error: created.error };
+    }
+
+    for (const approverOrg of approverOrgs) {
+      const approved = await approvalService.approveRequest(requestId, { approverOrg, reason: 'Template reviewed' });
+      if (!approved.success) {
+        return { success: false, error: approved.error };
+      }
+    }
+
+    const executed = await approvalService.executeRequest(requestId);
+    if (!executed.success) {
+      return { success: false, error: executed.error };
+    }
+
+    return apiService.post<void>(
+      `${ENDPOINTS.POLICY_TEMPLATE.ACTIVATE}/${templateID}/activate`,
+      { approvalRequestId: requestId },
+      undefined,
+      undefined
+    );
   },
 };
 
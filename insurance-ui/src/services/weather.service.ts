@@ -14,14 +14,16 @@ export interface RegisterOracleDto {
 export interface SubmitWeatherDataDto {
   dataID: string;
   oracleID: string;
-  farmerID: string;
+  stationID?: string;
+  location: string;
   latitude: number;
   longitude: number;
+  observationTime: string; // RFC3339
+  period: 'Hourly' | 'Daily';
   temperature: number;
   rainfall: number;
   humidity: number;
   windSpeed: number;
-  timestamp: string;
 }
 
 export const weatherOracleService = {
@@ -48,8 +50,8 @@ export const weatherOracleService = {
     const mockWeatherData: WeatherData = {
       dataID: data.dataID,
       oracleID: data.oracleID,
-      location: `${data.latitude},${data.longitude}`,
-      timestamp: data.timestamp,
+      location: data.location,
+      timestamp: data.observationTime,
       temperature: data.temperature,
       rainfall: data.rainfall,
       humidity: data.humidity,
@@ -127,7 +127,8 @@ export interface WeatherIndex {
 export interface Claim {
   claimID: string;
   policyID: string;
-  farmerID: string;
+  farmerID: string; // Policyholder the claim is made under
+  payeeID?: string; // Farmer or designated beneficiary receiving the payout
   indexID: string; // Weather data ID that triggered the claim
   triggerDate: string;
   payoutAmount: number;
@@ -182,7 +183,7 @@ export interface DashboardStats {
 
 // Approval Management Types
 export type ApprovalStatus = 'PENDING' | 'APPROVED' | 'REJECTED' | 'EXECUTED';
-export type ApprovalRequestType = 'FARMER_REGISTRATION' | 'POLICY_CREATION' | 'CLAIM_APPROVAL' | 'POOL_WITHDRAWAL';
+export type ApprovalRequestType = 'FARMER_REGISTRATION' | 'POLICY_CREATION' | 'CLAIM_APPROVAL' | 'POOL_WITHDRAWAL' | 'TEMPLATE_ACTIVATION';
 
 export interface ApprovalRequest {
   requestId: string;
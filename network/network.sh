@@ -225,6 +225,9 @@ function deployChaincode() {
     
     # Package chaincode
     echo "Step 1: Packaging chaincode..."
+    # Chaincodes using the shared geogrid or payoutcurve modules need them vendored into the package
+    docker exec -w ${CC_PATH} cli \
+        sh -c 'if grep -qsE "^replace (geogrid|payoutcurve) " go.mod; then go mod vendor; fi'
     docker exec cli peer lifecycle chaincode package ${CC_NAME}.tar.gz \
         --path ${CC_PATH} \
         --lang ${CHAINCODE_LANGUAGE} \
@@ -183,6 +183,10 @@ deploy_chaincode() {
             ;;
     esac
     
+    # Chaincodes using the shared geogrid or payoutcurve modules need them vendored into the package
+    docker exec -w /opt/gopath/src/github.com/chaincode/${CC_NAME} cli \
+        sh -c 'if grep -qsE "^replace (geogrid|payoutcurve) " go.mod; then go mod vendor; fi'
+    
     # Package - show output so we can see progress
     docker exec cli peer lifecycle chaincode package ${CC_NAME}-v${CC_VERSION}.tar.gz \
         --path /opt/gopath/src/github.com/chaincode/${CC_NAME}/ \
@@ -13,6 +13,8 @@ CHANNEL_NAME="insurance-channel"
 POLICY_EXPR="OR('Insurer1MSP.peer','Insurer2MSP.peer','CoopMSP.peer','PlatformMSP.peer')"
 
 echo "Step 1: Packaging chaincode..."
+# The shared geogrid and payoutcurve modules live outside the chaincode directory, so vendor them into the package
+$DOCKER exec -w /opt/gopath/src/github.com/chaincode/${CC_NAME} cli go mod vendor
 $DOCKER exec cli peer lifecycle chaincode package ${CC_NAME}-v${CC_VERSION}.tar.gz \
     --path /opt/gopath/src/github.com/chaincode/${CC_NAME}/ \
     --lang golang \
@@ -204,6 +204,10 @@ else
         
         echo "  Deploying: ${CC_NAME} v${CC_VERSION}..."
         
+        # Chaincodes using the shared geogrid or payoutcurve modules need them vendored into the package
+        docker exec -w /opt/gopath/src/github.com/chaincode/${CC_NAME} cli \
+            sh -c 'if grep -qsE "^replace (geogrid|payoutcurve) " go.mod; then go mod vendor; fi'
+        
         # Package
         echo "    - Packaging..."
         if ! docker exec cli peer lifecycle chaincode package ${CC_NAME}.tar.gz \